@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/alarm"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var (
+	alarmFile string
+	alarmAt   string
+	alarmRamp time.Duration
+	alarmOff  time.Duration
+	alarmDays string
+	alarmSave bool
+	alarmIdle bool
+)
+
+func init() {
+	rootCmd.AddCommand(alarmCmd)
+	alarmCmd.PersistentFlags().StringVar(&alarmFile, "file", alarm.DefaultFile(), "alarm file")
+	alarmCmd.Flags().StringVar(&alarmAt, "at", "", "time to start the sunrise, 06:30 or 2006-01-02 06:30")
+	alarmCmd.Flags().DurationVar(&alarmRamp, "ramp", 30*time.Minute, "how long the sunrise takes, 0 turns the lights straight on at daylight")
+	alarmCmd.Flags().DurationVar(&alarmOff, "off", 0, "turn the lights off this long after the sunrise (0 leaves them on)")
+	alarmCmd.Flags().StringVar(&alarmDays, "days", "", "repeat on these days of the week, like mon-fri or sat,sun")
+	alarmCmd.Flags().BoolVar(&alarmSave, "save", false, "only save the alarm, for an already running alarm run")
+
+	alarmCmd.AddCommand(alarmRunCmd)
+	alarmRunCmd.Flags().BoolVar(&alarmIdle, "idle", false, "keep running when no alarms are left")
+	alarmCmd.AddCommand(alarmListCmd)
+	alarmCmd.AddCommand(alarmRemoveCmd)
+}
+
+var alarmCmd = &cobra.Command{
+	Use:   "alarm SELECTOR --at TIME",
+	Short: "Wakes you with a sunrise",
+	Long: `Alarm turns the LIFX lights identified by SELECTOR on at --at, barely lit in a deep red, and brightens
+them through orange to daylight over --ramp. The alarm is saved to the alarm file and the command waits
+for it, along with any other saved alarms. If it is stopped, "alarm run" carries on with the saved alarms.
+
+` + selectorHelp,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		a := &alarm.Alarm{Ramp: alarmRamp, Off: alarmOff, Lights: args[0]}
+		if err := parseAlarmAt(a); err != nil {
+			return err
+		}
+		alarms, err := alarm.ReadFile(alarmFile)
+		if err != nil {
+			return err
+		}
+		if alarms, err = alarms.Add(a); err != nil {
+			return err
+		}
+		if err := alarm.WriteFile(alarmFile, alarms); err != nil {
+			return err
+		}
+		if err := printResults(a); err != nil {
+			return err
+		}
+		if alarmSave {
+			return nil
+		}
+		return runAlarms(false)
+	},
+}
+
+var alarmRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Waits for the saved alarms",
+	Long:  `Wakes the saved alarms as they come due, including any cut short by a restart, until none are left.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAlarms(alarmIdle)
+	},
+}
+
+var alarmListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the saved alarms",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		alarms, err := alarm.ReadFile(alarmFile)
+		if err != nil {
+			return err
+		}
+		return printResults(alarms)
+	},
+}
+
+var alarmRemoveCmd = &cobra.Command{
+	Use:   "remove ID",
+	Short: "Removes a saved alarm",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		alarms, err := alarm.ReadFile(alarmFile)
+		if err != nil {
+			return err
+		}
+		if alarms.Find(args[0]) == nil {
+			return fmt.Errorf("no alarm %v", args[0])
+		}
+		return alarm.WriteFile(alarmFile, alarms.Remove(args[0]))
+	},
+}
+
+//parseAlarmAt sets the alarm's time from --at and --days.
+func parseAlarmAt(a *alarm.Alarm) error {
+	if alarmAt == "" {
+		return fmt.Errorf("--at is required")
+	}
+	now := time.Now()
+	if at, err := time.ParseInLocation("2006-01-02 15:04", alarmAt, time.Local); err == nil {
+		if alarmDays != "" {
+			return fmt.Errorf("--days needs a time of day for --at not a date")
+		}
+		a.At = at
+		return nil
+	}
+	clock, err := time.Parse("15:04", alarmAt)
+	if err != nil {
+		return fmt.Errorf("--at %q is not 06:30 or 2006-01-02 06:30", alarmAt)
+	}
+	days := alarmDays
+	if days == "" {
+		days = "*"
+	}
+	// the next time the clock shows it on one of the days, a one off alarm doesn't keep the repeat
+	a.Repeat = fmt.Sprintf("%v %v * * %v", clock.Minute(), clock.Hour(), days)
+	if a.At, err = a.Next(now); err != nil {
+		return fmt.Errorf("--days %q: %v", alarmDays, err)
+	}
+	if alarmDays == "" {
+		a.Repeat = ""
+	}
+	return nil
+}
+
+func runAlarms(idle bool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			status("Stopping, alarm run carries on with the saved alarms\n")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	c, err := client.StartUp(ctx)
+	if err != nil {
+		return err
+	}
+	r := &alarm.Runner{Client: c, File: alarmFile, Idle: idle}
+	return r.Run(ctx)
+}
@@ -10,9 +10,11 @@ import (
 	"github.com/nathanhack/lifx/core/broadcast"
 	"github.com/nathanhack/lifx/core/header"
 	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/fields"
 	"github.com/nathanhack/lifx/core/server"
 	"github.com/spf13/cobra"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -43,7 +45,7 @@ var broadcastCmd = &cobra.Command{
 				return err
 			}
 			timeout = time.Duration(tmp) * time.Millisecond
-			fmt.Printf("Timeout found, using %v\n", timeout)
+			status("Timeout found, using %v\n", timeout)
 		}
 
 		ctx := context.Background()
@@ -53,15 +55,34 @@ var broadcastCmd = &cobra.Command{
 		}
 
 		bctx, _ := context.WithTimeout(ctx, timeout)
-		_, err = sendBroadcast(bctx, out, in, []string{}, broadcastShowLabel)
+		targetBroadcasts, err := sendBroadcast(bctx, out, in, []string{}, broadcastShowLabel)
 		if err != nil {
 			return err
 		}
 
-		return nil
+		found := make([]foundDevice, 0, len(targetBroadcasts))
+		for _, targetBroadcast := range targetBroadcasts {
+			found = append(found, foundDevice{targetBroadcast})
+		}
+		sort.Slice(found, func(i, j int) bool {
+			return bytes.Compare(found[i].Target, found[j].Target) < 0
+		})
+		return printResults(found)
 	},
 }
 
+//foundDevice keeps the text output of broadcast the way it has always looked
+type foundDevice struct {
+	*broadcast.BroadcastResult
+}
+
+func (f foundDevice) String() string {
+	if f.Label != "" {
+		return fmt.Sprintf("Found LIFX %x (%v) at %v:%v", f.Target, f.Label, f.IP, f.Port)
+	}
+	return fmt.Sprintf("Found LIFX %x at %v:%v", f.Target, f.IP, f.Port)
+}
+
 func sendBroadcast(ctx context.Context, out chan *server.OutBoundPayload, in chan *server.InboundPayload, filterByHexString []string, requestLabels bool) (targetBroadcasts map[string]*broadcast.BroadcastResult, err error) {
 	//we'll make a map to hold the filterByHexString's byte versions
 	hexStringsFilter := make(map[string][]byte)
@@ -90,7 +111,7 @@ func sendBroadcast(ctx context.Context, out chan *server.OutBoundPayload, in cha
 		return nil, err
 	}
 
-	fmt.Println("sending broadcast to determine address for device(s)")
+	status("sending broadcast to determine address for device(s)\n")
 	out <- &server.OutBoundPayload{
 		Data:    buffer.Bytes(),
 		Address: broadcastAddress,
@@ -124,13 +145,12 @@ func sendBroadcast(ctx context.Context, out chan *server.OutBoundPayload, in cha
 				if len(hexStringsFilter) > 0 {
 					if _, has := hexStringsFilter[hexStr]; has {
 						targetBroadcasts[hexStr] = targetBroadcast
-						fmt.Printf("Found target %x at %v:%v\n", targetBroadcast.Target, payload.Conn.IP, payload.Conn.Port)
+						status("Found target %x at %v:%v\n", targetBroadcast.Target, payload.Conn.IP, payload.Conn.Port)
 					}
 				} else {
 					if !requestLabels {
 						if _, has := targetBroadcasts[hexStr]; !has {
 							targetBroadcasts[hexStr] = targetBroadcast
-							fmt.Printf("Found LIFX %x at %v:%v\n", targetBroadcast.Target, payload.Conn.IP, payload.Conn.Port)
 						}
 					} else {
 						sendDeviceGetLabel(out, targetBroadcast.Target, payload.Conn.IP, payload.Conn.Port)
@@ -148,8 +168,8 @@ func sendBroadcast(ctx context.Context, out chan *server.OutBoundPayload, in cha
 					continue
 				}
 				if _, has := targetBroadcasts[hexStr]; !has {
+					targetBroadcast.Label = fields.Label(s.Label)
 					targetBroadcasts[hexStr] = targetBroadcast
-					fmt.Printf("Found LIFX %x (%v) at %v:%v\n", targetBroadcast.Target, s.GetLabel(), payload.Conn.IP, payload.Conn.Port)
 				}
 			}
 		}
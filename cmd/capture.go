@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/capture"
+	"github.com/nathanhack/lifx/core/server"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+)
+
+var captureOut string
+var captureDiscover bool
+
+func init() {
+	rootCmd.AddCommand(captureCmd)
+	captureCmd.Flags().StringVar(&captureOut, "out", "", "pcap file to write the capture to")
+	captureCmd.Flags().BoolVar(&captureDiscover, "discover", false, "send a broadcast so devices announce themselves")
+	captureCmd.MarkFlagRequired("out")
+
+	captureCmd.AddCommand(captureShowCmd)
+}
+
+var captureCmd = &cobra.Command{
+	Use:   "capture --out FILE [DURATION_MILLISECONDS]",
+	Short: "Records LIFX traffic to a pcap file",
+	Long: `Records every datagram sent or received on the LIFX port to a pcap file that can be opened in Wireshark or read back with "capture show".
+
+Capturing stops after DURATION_MILLISECONDS or when interrupted (Ctrl-C).`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var duration time.Duration
+		if len(args) > 0 {
+			tmp, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			duration = time.Duration(tmp) * time.Millisecond
+		}
+
+		file, err := os.Create(captureOut)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		writer, err := capture.NewWriter(file)
+		if err != nil {
+			return err
+		}
+		defer writer.Flush()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if duration > 0 {
+			ctx, cancel = context.WithTimeout(ctx, duration)
+			defer cancel()
+		}
+
+		out, in, err := server.StartUpWithRecorder(ctx, writer)
+		if err != nil {
+			return err
+		}
+
+		if captureDiscover {
+			go sendBroadcast(ctx, out, in, []string{}, false)
+		} else {
+			//nobody else reads inbound so keep it drained
+			go func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-in:
+					}
+				}
+			}()
+		}
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+
+		status("Capturing to %v\n", captureOut)
+		select {
+		case <-ctx.Done():
+		case <-interrupt:
+		}
+		return writer.Flush()
+	},
+}
+
+var captureShowCmd = &cobra.Command{
+	Use:   "show FILE",
+	Short: "Prints the LIFX packets found in a pcap or pcapng file",
+	Long:  `Prints the LIFX packets found in a pcap or pcapng file, one per line.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		packets, err := capture.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		return printResults(packets)
+	},
+}
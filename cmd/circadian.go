@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/circadian"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/spf13/cobra"
+	"math"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var (
+	circadianCurveFile  string
+	circadianInterval   uint32
+	circadianTransition uint32
+	circadianResume     uint32
+)
+
+func init() {
+	rootCmd.AddCommand(circadianCmd)
+	circadianCmd.PersistentFlags().StringVar(&circadianCurveFile, "curve", "", "curve file (default warm at night and cool at noon)")
+
+	circadianCmd.AddCommand(circadianRunCmd)
+	circadianRunCmd.Flags().Uint32Var(&circadianInterval, "interval", uint32(circadian.DefaultInterval/time.Millisecond), "time in milliseconds between updates")
+	circadianRunCmd.Flags().Uint32Var(&circadianTransition, "transition", uint32(circadian.DefaultTransition/time.Millisecond), "time in milliseconds each update fades for")
+	circadianRunCmd.Flags().Uint32Var(&circadianResume, "resume", 0, "time in milliseconds before taking back a light changed by hand (0 waits until it is turned off)")
+
+	circadianCmd.AddCommand(circadianCurveCmd)
+}
+
+const circadianHelp = `The curve file is YAML, the lights fade in a straight line from one point to the next:
+  latitude: 51.48          only needed for sun events
+  longitude: -0.01
+  points:
+    - at: "00:00"
+      kelvin: 2200
+      brightness: 10
+    - at: sunrise
+      kelvin: 2700
+      brightness: 40
+    - at: "12:00"
+      kelvin: 6000
+      brightness: 100
+    - at: sunset+1h
+      kelvin: 2700
+      brightness: 50
+at is a clock time or a sun event, sunrise, sunset, dawn, dusk or noon, with an optional offset.`
+
+var circadianCmd = &cobra.Command{
+	Use:   "circadian",
+	Short: "Follows the time of day with the white of lights",
+	Long: `Circadian changes the kelvin and brightness of lights through the day along a curve.
+
+` + circadianHelp,
+}
+
+var circadianRunCmd = &cobra.Command{
+	Use:   "run SELECTOR [TIMEOUT_MILLISECONDS]",
+	Short: "Keeps lights on the curve until interrupted",
+	Long: `Keeps the LIFX lights identified by SELECTOR on the curve's white until interrupted. Lights are only changed
+while they are on, and a light whose color is changed by hand is left alone until it is turned off or --resume
+has passed.
+
+` + circadianHelp + `
+
+` + selectorHelp,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		curve, err := readCurve()
+		if err != nil {
+			return err
+		}
+		timeout, err := parseTimeout(args, 1)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		lights, err := selectLights(ctx, args[0], timeout)
+		if err != nil {
+			return err
+		}
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		go func() {
+			select {
+			case <-interrupt:
+				status("Stopping\n")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		status("Following the curve on %v lights\n", len(lights))
+		a := &circadian.Adaptive{
+			Curve:       curve,
+			Interval:    time.Duration(circadianInterval) * time.Millisecond,
+			Transition:  time.Duration(circadianTransition) * time.Millisecond,
+			ResumeAfter: time.Duration(circadianResume) * time.Millisecond,
+		}
+		return a.Run(ctx, lights)
+	},
+}
+
+var circadianCurveCmd = &cobra.Command{
+	Use:   "curve",
+	Short: "Lists the curve's white for each hour of today",
+	Long: `Lists the kelvin and brightness of the curve for each hour of today.
+
+` + circadianHelp,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		curve, err := readCurve()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		hours := make([]curveHour, 0, 24)
+		for hour := 0; hour < 24; hour++ {
+			at := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+			kelvin, brightness := curve.At(at)
+			hours = append(hours, curveHour{Time: at.Format("15:04"), Kelvin: kelvin, Brightness: math.Round(hsbk.Fraction(brightness)*1000) / 10})
+		}
+		return printResults(hours)
+	},
+}
+
+func readCurve() (*circadian.Curve, error) {
+	if circadianCurveFile == "" {
+		return circadian.DefaultCurve(), nil
+	}
+	return circadian.ReadCurve(circadianCurveFile)
+}
+
+type curveHour struct {
+	Time       string  `json:"time" yaml:"time"`
+	Kelvin     uint16  `json:"kelvin" yaml:"kelvin"`
+	Brightness float64 `json:"brightness" yaml:"brightness"`
+}
+
+func (c curveHour) String() string {
+	return fmt.Sprintf("%v %vK %.0f%%", c.Time, c.Kelvin, c.Brightness)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/effect"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+var (
+	effectRunDuration uint32
+	effectRunPeriod   uint32
+	effectRunColor    string
+	effectRunFPS      int
+	effectRunSeed     int64
+	effectRunKeep     bool
+)
+
+func init() {
+	rootCmd.AddCommand(effectCmd)
+	effectCmd.AddCommand(effectRunCmd)
+	effectCmd.AddCommand(effectListCmd)
+
+	effectRunCmd.Flags().Uint32VarP(&effectRunDuration, "duration", "d", 0, "time in milliseconds to run for (0 runs until interrupted)")
+	effectRunCmd.Flags().Uint32Var(&effectRunPeriod, "period", 0, "time in milliseconds of one cycle (0 uses the effect's default)")
+	effectRunCmd.Flags().StringVar(&effectRunColor, "color", "", "color for the effects that use one, see light setcolor")
+	effectRunCmd.Flags().IntVar(&effectRunFPS, "fps", effect.DefaultFPS, "colors sent to each light a second")
+	effectRunCmd.Flags().Int64Var(&effectRunSeed, "seed", 0, "seed for the random effects (0 picks one)")
+	effectRunCmd.Flags().BoolVar(&effectRunKeep, "keep", false, "leave the lights as the effect left them instead of restoring them")
+}
+
+var effectCmd = &cobra.Command{
+	Use:   "effect",
+	Short: "Runs software effects on lights",
+	Long:  `Effect runs animations driven from this computer, such as a candle flicker or a rainbow across a group.`,
+}
+
+var effectRunCmd = &cobra.Command{
+	Use:   "run NAME SELECTOR [TIMEOUT_MILLISECONDS]",
+	Short: "Runs an effect on lights",
+	Long: `Runs the effect NAME on the LIFX lights identified by SELECTOR until --duration passes or it is interrupted, then
+restores the lights. NAME is one of ` + strings.Join(effect.Names(), ", ") + `.
+
+` + selectorHelp,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params := effect.Params{Period: time.Duration(effectRunPeriod) * time.Millisecond}
+		if effectRunColor != "" {
+			spec, err := hsbk.Parse(effectRunColor)
+			if err != nil {
+				return err
+			}
+			if !spec.Complete() {
+				// there is no light to take the rest from so fill in a full bright neutral white
+				spec.HSBK = spec.Apply(hsbk.HSBK{Brightness: 0xffff, Kelvin: hsbk.NeutralKelvin})
+			}
+			params.Color = &spec.HSBK
+		}
+		e, err := effect.Named(args[0], params)
+		if err != nil {
+			return err
+		}
+
+		timeout, err := parseTimeout(args, 2)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		lights, err := selectLights(ctx, args[1], timeout)
+		if err != nil {
+			return err
+		}
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+
+		status("Running %v on %v lights\n", args[0], len(lights))
+		running := effect.Start(ctx, e, lights, effect.Options{
+			FPS:      effectRunFPS,
+			Duration: time.Duration(effectRunDuration) * time.Millisecond,
+			Seed:     effectRunSeed,
+			Restore:  !effectRunKeep,
+		})
+		select {
+		case <-running.Done():
+		case <-interrupt:
+			status("Stopping %v\n", args[0])
+		}
+		return running.Stop()
+	},
+}
+
+var effectListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the effects",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names := make([]effectName, 0)
+		for _, name := range effect.Names() {
+			names = append(names, effectName{Name: name})
+		}
+		return printResults(names)
+	},
+}
+
+type effectName struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+func (e effectName) String() string {
+	return fmt.Sprint(e.Name)
+}
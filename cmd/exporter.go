@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/exporter"
+	"github.com/nathanhack/lifx/core/selector"
+	"github.com/spf13/cobra"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var (
+	exporterListen  string
+	exporterWait    uint32
+	exporterTimeout uint32
+)
+
+func init() {
+	rootCmd.AddCommand(exporterCmd)
+
+	exporterCmd.Flags().StringVar(&exporterListen, "listen", ":9750", "address the metrics are served on")
+	exporterCmd.Flags().Uint32Var(&exporterWait, "wait", uint32(selector.DefaultWait/time.Millisecond), "time in milliseconds discovery listens for lights")
+	exporterCmd.Flags().Uint32Var(&exporterTimeout, "timeout", uint32(exporter.DefaultTimeout/time.Millisecond), "time in milliseconds a scrape waits for the lights")
+}
+
+var exporterCmd = &cobra.Command{
+	Use:   "exporter",
+	Short: "Serves Prometheus metrics for the lights",
+	Long: `Serves Prometheus metrics on /metrics until interrupted. Every scrape asks each light for:
+  lifx_device_up                          1 when the light answered, 0 when a light found before didn't
+  lifx_device_info                        the product and firmware version as labels
+  lifx_device_request_latency_seconds     time the state request took
+  lifx_device_power, lifx_device_brightness, lifx_device_kelvin
+  lifx_device_wifi_signal                 as the light reports it, the RSSI in dBm when negative
+  lifx_device_wifi_signal_quality         0 (no signal) to 4 (good), with the class as a label
+  lifx_device_wifi_tx_bytes_total, lifx_device_wifi_rx_bytes_total
+  lifx_device_uptime_seconds, lifx_device_downtime_seconds
+Device metrics are labeled with the light's id and label. lifx_client_packets_sent_total,
+lifx_client_packets_received_total and lifx_client_packets_dropped_total count the exporter's own packets.
+
+e.g. scrape_configs: [{job_name: lifx, static_configs: [{targets: ["localhost:9750"]}]}]`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		c, err := client.StartUp(ctx)
+		if err != nil {
+			return err
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", &exporter.Exporter{
+			Client:  c,
+			Lights:  &selector.Cache{Client: c, Wait: time.Duration(exporterWait) * time.Millisecond},
+			Timeout: time.Duration(exporterTimeout) * time.Millisecond,
+		})
+		server := &http.Server{Addr: exporterListen, Handler: mux}
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		go func() {
+			select {
+			case <-interrupt:
+				status("Stopping\n")
+				shutdownCtx, done := context.WithTimeout(ctx, 5*time.Second)
+				defer done()
+				server.Shutdown(shutdownCtx)
+			case <-ctx.Done():
+			}
+		}()
+
+		status("Serving metrics on %v/metrics\n", exporterListen)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	},
+}
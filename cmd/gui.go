@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"context"
-	"fmt"
 	"github.com/nathanhack/lifx/cmd/gui"
 	"github.com/nathanhack/lifx/core/server"
 	"github.com/spf13/cobra"
@@ -20,7 +19,7 @@ var guiCmd = &cobra.Command{
 Ideally the lights should be apart of one groups.'`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
-		fmt.Println("gui called", strings.Join(args, ","))
+		status("gui called %v\n", strings.Join(args, ","))
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 		out, in, err := server.StartUp(ctx)
@@ -0,0 +1,168 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"reflect"
+	"strings"
+)
+
+type Format string
+
+const (
+	Text Format = "text"
+	JSON Format = "json"
+	YAML Format = "yaml"
+	CSV  Format = "csv"
+)
+
+var Formats = []Format{Text, JSON, YAML, CSV}
+
+//ParseFormat validates a --output flag value.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(s) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q (expected one of %v)", s, Formats)
+}
+
+//Write renders records, either a single value or a slice of values, to w. Text prints each value on
+//its own line using its String method, the other formats rely on the values' JSON/YAML marshaling.
+//CSV flattens nested objects into dotted column names (color.hue).
+func Write(w io.Writer, format Format, records interface{}) error {
+	switch format {
+	case Text:
+		for _, r := range asSlice(records) {
+			if _, err := fmt.Fprintln(w, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case JSON:
+		b, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case YAML:
+		b, err := yaml.Marshal(records)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case CSV:
+		return writeCSV(w, asSlice(records))
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+func asSlice(records interface{}) []interface{} {
+	v := reflect.ValueOf(records)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []interface{}{records}
+	}
+	result := make([]interface{}, v.Len())
+	for i := range result {
+		result[i] = v.Index(i).Interface()
+	}
+	return result
+}
+
+func writeCSV(w io.Writer, records []interface{}) error {
+	columns := make([]string, 0)
+	seen := make(map[string]bool)
+	rows := make([]map[string]string, 0, len(records))
+	for _, r := range records {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		row := make(map[string]string)
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.UseNumber()
+		keys, err := flatten(decoder, "", row)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		line := make([]string, len(columns))
+		for i, c := range columns {
+			line[i] = row[c]
+		}
+		if err := writer.Write(line); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+//flatten walks one JSON value keeping the order fields were written in and returns the column names found.
+func flatten(decoder *json.Decoder, prefix string, row map[string]string) ([]string, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		keys := make([]string, 0)
+		for i := 0; decoder.More(); i++ {
+			var name string
+			if t == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				name = fmt.Sprint(key)
+			} else {
+				name = fmt.Sprint(i)
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			inner, err := flatten(decoder, name, row)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, inner...)
+		}
+		// consume the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return keys, nil
+	case nil:
+		row[prefix] = ""
+	default:
+		row[prefix] = fmt.Sprint(t)
+	}
+	if prefix == "" {
+		prefix = "value"
+		row[prefix] = row[""]
+		delete(row, "")
+	}
+	return []string{prefix}, nil
+}
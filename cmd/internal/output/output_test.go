@@ -0,0 +1,38 @@
+package output
+
+import (
+	"bytes"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"testing"
+)
+
+func TestWrite_CSV(t *testing.T) {
+	states := []light.State{
+		{Label: fields.ToLabel("Kitchen"), Power: 0xffff, Color: hsbk.HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}},
+		{Label: fields.ToLabel("Hall, upstairs"), Color: hsbk.HSBK{Kelvin: 2700}},
+	}
+	buffer := bytes.NewBuffer([]byte{})
+	if err := Write(buffer, CSV, states); err != nil {
+		t.Fatal(err)
+	}
+	expected := `label,power,color.hue,color.saturation,color.brightness,color.kelvin
+Kitchen,on,120,100,100,3500
+"Hall, upstairs",off,0,0,0,2700
+`
+	if buffer.String() != expected {
+		t.Errorf("expect %q but got %q", expected, buffer.String())
+	}
+}
+
+func TestWrite_Text(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+	if err := Write(buffer, Text, []light.StatePower{{Level: 0xffff}, {Level: 0}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "{Level:ON}\n{Level:OFF}\n"
+	if buffer.String() != expected {
+		t.Errorf("expect %q but got %q", expected, buffer.String())
+	}
+}
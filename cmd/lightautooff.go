@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/autooff"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var lightAutoOffAfter time.Duration
+var lightAutoOffInterval time.Duration
+var lightAutoOffFade time.Duration
+
+func init() {
+	lightCmd.AddCommand(lightAutoOffCmd)
+
+	lightAutoOffCmd.Flags().DurationVar(&lightAutoOffAfter, "after", 2*time.Hour, "how long a light may stay on")
+	lightAutoOffCmd.Flags().DurationVar(&lightAutoOffInterval, "interval", autooff.DefaultInterval, "how often the lights are checked")
+	lightAutoOffCmd.Flags().DurationVar(&lightAutoOffFade, "fade", autooff.DefaultFade, "how long the lights take to dim to off")
+}
+
+var lightAutoOffCmd = &cobra.Command{
+	Use:   "autooff SELECTOR [TIMEOUT_MILLISECONDS]",
+	Short: "Turns LIFX lights off once they have been on too long",
+	Long: `Checks the LIFX lights identified by SELECTOR every --interval until interrupted and turns off the ones that
+have been on for longer than --after. A light counts as on from the first check that finds it on.
+
+` + selectorHelp,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, err := parseTimeout(args, 1)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		lights, err := selectLights(ctx, args[0], timeout)
+		if err != nil {
+			return err
+		}
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		go func() {
+			select {
+			case <-interrupt:
+				status("Stopping\n")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		status("Turning off %v lights after %v on\n", len(lights), lightAutoOffAfter)
+		p := &autooff.Policy{After: lightAutoOffAfter, Interval: lightAutoOffInterval, Fade: lightAutoOffFade}
+		return p.Run(ctx, lights)
+	},
+}
@@ -1,18 +1,13 @@
 package cmd
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
-	"encoding/hex"
 	"fmt"
-	"github.com/nathanhack/lifx/cmd/internal"
-	"github.com/nathanhack/lifx/core/broadcast"
-	"github.com/nathanhack/lifx/core/header"
-	"github.com/nathanhack/lifx/core/messages/light"
-	"github.com/nathanhack/lifx/core/server"
-	"net"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/transition"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -24,6 +19,7 @@ var (
 	lightSetColorBright   int
 	lightSetColorKelvin   int
 	lightSetColorDuration uint32
+	lightSetColorEasing   string
 )
 
 func init() {
@@ -34,6 +30,7 @@ func init() {
 	lightSetColorCmd.Flags().IntVarP(&lightSetColorBright, "brightness", "b", -1, "set brightness value [0,100]")
 	lightSetColorCmd.Flags().IntVarP(&lightSetColorKelvin, "kelvin", "k", -1, "set kelvin value [2500,9000]")
 	lightSetColorCmd.Flags().Uint32VarP(&lightSetColorDuration, "duration", "d", 0, "time in milliseconds for transition")
+	lightSetColorCmd.Flags().StringVar(&lightSetColorEasing, "easing", "", "fade with this easing instead of the light's linear fade: "+strings.Join(transition.EasingNames(), ", "))
 }
 
 func intmin(a, b int) int {
@@ -55,123 +52,96 @@ func minMaxInt(x, max, min int) int {
 }
 
 var lightSetColorCmd = &cobra.Command{
-	Use:   "setcolor TARGET_HEXSTR  [TIMEOUT_MILLISECONDS]",
-	Short: "Sets the color for a particular LIFX light",
-	Long: `Sets the color for the LIFX light identified by TARGET_HEXSTR.
-
-Note if the IP and port are known include those tags then the broadcast step can be skipped.`,
-	Args: cobra.RangeArgs(1, 2),
+	Use:   "setcolor SELECTOR [COLOR] [TIMEOUT_MILLISECONDS]",
+	Short: "Sets the color for LIFX lights",
+	Long: `Sets the color for the LIFX lights identified by SELECTOR. Values not given are kept from each light's current color.
+
+COLOR is made of space separated parts, later parts and the flags override earlier ones:
+  red, tomato, ...     CSS/X11 color names
+  white                no saturation
+  warm white, ...      warm white, soft white, neutral white, cool white or daylight
+  #ff0000, #f00        hex RGB
+  rgb(255,0,0)         RGB
+  hsb(120,100%,50%)    hue in degrees, saturation and brightness
+  hue:120 saturation:50% brightness:30% kelvin:2700
+For example: setcolor label:Kitchen "warm white brightness:30%"
+
+` + selectorHelp,
+	Args: cobra.RangeArgs(1, 3),
 	RunE: func(cmd *cobra.Command, args []string) error {
-
-		timeout := defaultTimeout
-		if len(args) > 2 {
-			tmp, err := strconv.Atoi(args[2])
-			if err != nil {
-				return err
+		// COLOR is optional so a number in its place is the timeout
+		var spec hsbk.Spec
+		if len(args) > 1 {
+			if _, err := strconv.Atoi(args[1]); err != nil {
+				spec, err = hsbk.Parse(args[1])
+				if err != nil {
+					return err
+				}
+				args = append(args[:1], args[2:]...)
 			}
-			timeout = time.Duration(tmp) * time.Millisecond
-			fmt.Printf("Timeout found, using %v\n", timeout)
 		}
-		ctx := context.Background()
-		out, in, err := server.StartUp(ctx)
-		if err != nil {
-			return err
+		if len(args) > 2 {
+			return fmt.Errorf("unexpected argument %q", args[2])
 		}
-		var targetBroadcast *broadcast.BroadcastResult
-		if ip == "" || port <= 0 {
-			bctx, _ := context.WithTimeout(ctx, timeout)
-			targetBroadcasts, err := sendBroadcast(bctx, out, in, []string{args[0]}, false)
-			if err != nil {
-				return err
-			}
-			if len(targetBroadcasts) == 0 {
-				fmt.Println("could not find target device")
-				return nil
-			}
-			targetBroadcast = targetBroadcasts[args[0]]
-		} else {
-			address, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%v:%v", ip, port))
-			if err != nil {
-				return err
-			}
-			hexBytes, err := hex.DecodeString(args[0])
-			if err != nil {
-				return err
-			}
-
-			targetBroadcast = &broadcast.BroadcastResult{
-				Target: hexBytes,
-				IP:     address.IP,
-				Port:   address.Port,
-			}
+		if lightSetColorHue >= 0 {
+			spec.Hue = hsbk.FromDegrees(float64(minMaxInt(lightSetColorHue, 360, 0)))
+			spec.Set |= hsbk.HueField
+		}
+		if lightSetColorSat >= 0 {
+			spec.Saturation = hsbk.FromFraction(float64(lightSetColorSat) / 100)
+			spec.Set |= hsbk.SaturationField
+		}
+		if lightSetColorBright >= 0 {
+			spec.Brightness = hsbk.FromFraction(float64(lightSetColorBright) / 100)
+			spec.Set |= hsbk.BrightnessField
+		}
+		if lightSetColorKelvin >= 0 {
+			spec.Kelvin = uint16(minMaxInt(lightSetColorKelvin, 9000, 2400))
+			spec.Set |= hsbk.KelvinField
 		}
 
-		message := light.SetColor{}
-		message.Color.Hue = uint16(minMaxInt(int(float32(lightSetColorHue)/360*0xffff), 0xffff, 0))
-		message.Color.Saturation = uint16(minMaxInt(int(float32(lightSetColorSat)/100*0xffff), 0xffff, 0))
-		message.Color.Brightness = uint16(minMaxInt(int(float32(lightSetColorBright)/100*0xffff), 0xffff, 0))
-		message.Color.Kelvin = uint16(minMaxInt(lightSetColorKelvin, 9000, 2400))
-		message.Duration = lightSetColorDuration
-
-		// now we need to replace any negative values with the current state from the light
-		if lightSetColorHue < 0 || lightSetColorSat < 0 || lightSetColorBright < 0 || lightSetColorKelvin < 0 {
-			bctx, _ := context.WithTimeout(ctx, timeout)
-			oldstate, err := sendLightGet(bctx, out, in, targetBroadcast)
+		var easing transition.Easing
+		if lightSetColorEasing != "" {
+			var err error
+			easing, err = transition.ParseEasing(lightSetColorEasing)
 			if err != nil {
 				return err
 			}
-			if lightSetColorHue < 0 {
-				message.Color.Hue = oldstate.Color.Hue
-			}
-			if lightSetColorSat < 0 {
-				message.Color.Saturation = oldstate.Color.Saturation
-			}
-			if lightSetColorBright < 0 {
-				message.Color.Brightness = oldstate.Color.Brightness
-			}
-			if lightSetColorKelvin < 0 {
-				message.Color.Kelvin = oldstate.Color.Kelvin
-			}
 		}
 
-		//now lets get the powerlevel
-		pctx, _ := context.WithTimeout(ctx, timeout)
-		err = sendLightSetColor(pctx, out, targetBroadcast, &message)
+		timeout, err := parseTimeout(args, 1)
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		lights, err := selectLights(ctx, args[0], timeout)
 		if err != nil {
 			return err
 		}
 
-		return nil
-	},
-}
-
-func sendLightSetColor(ctx context.Context, out chan *server.OutBoundPayload, targetBroadcast *broadcast.BroadcastResult, message *light.SetColor) error {
-	head := header.New(internal.GetNextSequence())
-	head.SetTarget(targetBroadcast.Target)
-	message.RequiredHeader(head, true)
-	buffer := bytes.NewBuffer([]byte{})
-	err := binary.Write(buffer, binary.LittleEndian, head)
-	if err != nil {
-		return err
-	}
-	err = binary.Write(buffer, binary.LittleEndian, message)
-	if err != nil {
-		return err
-	}
+		duration := time.Duration(lightSetColorDuration) * time.Millisecond
+		if easing != nil {
+			// the client drives the fade so it has to stay around for it
+			timeout += duration
+		}
+		_, err = eachLight(ctx, lights, timeout, func(ctx context.Context, l *client.Light) (interface{}, error) {
+			// now we need to fill in what wasn't given with the current state from the light
+			var current hsbk.HSBK
+			if !spec.Complete() {
+				oldstate, err := l.State(ctx)
+				if err != nil {
+					return nil, err
+				}
+				current = oldstate.Color
+			}
+			color := spec.Apply(current)
 
-	address, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%v:%v", targetBroadcast.IP, targetBroadcast.Port))
-	if err != nil {
+			status("Setting color of %x at %v to %v\n", l.Target, l.Address, color)
+			if easing != nil {
+				return nil, transition.Transition{To: color, Duration: duration, Easing: easing}.Run(ctx, l)
+			}
+			return nil, l.SetColor(ctx, color, duration)
+		})
 		return err
-	}
-
-	fmt.Printf("Setting color to %02x at %v:%v to %v\n", targetBroadcast.Target, targetBroadcast.IP, targetBroadcast.Port, message.Color)
-	localctx, done := context.WithCancel(ctx)
-	out <- &server.OutBoundPayload{
-		Data:    buffer.Bytes(),
-		Address: address,
-		Done:    done,
-	}
-	// now we wait for receipt that the message has been sent
-	<-localctx.Done()
-	return nil
+	},
 }
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var lightSleepAfter time.Duration
+var lightSleepFade time.Duration
+
+func init() {
+	lightCmd.AddCommand(lightSleepCmd)
+
+	lightSleepCmd.Flags().DurationVar(&lightSleepAfter, "after", 20*time.Minute, "how long to wait before dimming")
+	lightSleepCmd.Flags().DurationVar(&lightSleepFade, "fade", 2*time.Minute, "how long the lights take to dim to off")
+}
+
+var lightSleepCmd = &cobra.Command{
+	Use:   "sleep SELECTOR [TIMEOUT_MILLISECONDS]",
+	Short: "Dims LIFX lights to off after a while",
+	Long: `Waits --after and then dims the LIFX lights identified by SELECTOR to off over --fade. The lights keep
+their color and brightness for when they are next turned on. Interrupt it to cancel.
+
+` + selectorHelp,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, err := parseTimeout(args, 1)
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		lights, err := selectLights(ctx, args[0], timeout)
+		if err != nil {
+			return err
+		}
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		status("Dimming %v lights to off at %v\n", len(lights), time.Now().Add(lightSleepAfter).Format("15:04:05"))
+		select {
+		case <-interrupt:
+			status("Cancelled\n")
+			return nil
+		case <-time.After(lightSleepAfter):
+		}
+
+		_, err = eachLight(ctx, lights, timeout, func(ctx context.Context, l *client.Light) (interface{}, error) {
+			status("Dimming %x at %v to off over %v\n", l.Target, l.Address, lightSleepFade)
+			return nil, l.SetPower(ctx, false, lightSleepFade)
+		})
+		return err
+	},
+}
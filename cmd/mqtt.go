@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/events"
+	"github.com/nathanhack/lifx/core/mqtt"
+	"github.com/nathanhack/lifx/core/mqttbridge"
+	"github.com/nathanhack/lifx/core/selector"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var (
+	mqttBroker          string
+	mqttClientID        string
+	mqttUsername        string
+	mqttPassword        string
+	mqttPrefix          string
+	mqttDiscoveryPrefix string
+	mqttNoDiscovery     bool
+	mqttPoll            uint32
+	mqttWait            uint32
+)
+
+func init() {
+	rootCmd.AddCommand(mqttCmd)
+
+	mqttCmd.Flags().StringVar(&mqttBroker, "broker", "tcp://localhost:1883", "MQTT broker, tcp://HOST:PORT or ssl://HOST:PORT")
+	mqttCmd.Flags().StringVar(&mqttClientID, "client-id", "lifx", "MQTT client id")
+	mqttCmd.Flags().StringVar(&mqttUsername, "username", "", "MQTT user name")
+	mqttCmd.Flags().StringVar(&mqttPassword, "password", "", "MQTT password")
+	mqttCmd.Flags().StringVar(&mqttPrefix, "prefix", mqttbridge.DefaultPrefix, "prefix of the bridge's topics")
+	mqttCmd.Flags().StringVar(&mqttDiscoveryPrefix, "discovery-prefix", mqttbridge.DefaultDiscoveryPrefix, "Home Assistant's discovery prefix")
+	mqttCmd.Flags().BoolVar(&mqttNoDiscovery, "no-discovery", false, "don't publish Home Assistant discovery configs")
+	mqttCmd.Flags().Uint32Var(&mqttPoll, "poll", uint32(events.DefaultInterval/time.Millisecond), "time in milliseconds between polls of the lights")
+	mqttCmd.Flags().Uint32Var(&mqttWait, "wait", uint32(selector.DefaultWait/time.Millisecond), "time in milliseconds discovery listens for lights")
+}
+
+var mqttCmd = &cobra.Command{
+	Use:   "mqtt",
+	Short: "Bridges the lights to an MQTT broker",
+	Long: `Publishes the state of every light to the MQTT --broker and changes the lights on commands, until
+interrupted. For a light with TARGET_HEXSTR id the topics are:
+  lifx/bridge/status          online or offline
+  lifx/id/state               JSON state, retained
+  lifx/id/availability        online or offline, retained
+  lifx/id/set                 JSON command, e.g. {"state":"ON","brightness":128,"color":{"h":240,"s":100}}
+  lifx/id/power/set           on or off
+  lifx/id/color/set           a color as the light commands take it, e.g. "warm white brightness:50%"
+  lifx/id/brightness/set      percent
+  lifx/id/effect/set          an effect of "effect list", or none
+The JSON follows Home Assistant's JSON schema for MQTT lights and a discovery config with what each product
+can do is published under --discovery-prefix, so the lights show up in Home Assistant by themselves.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		go func() {
+			select {
+			case <-interrupt:
+				status("Stopping\n")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		c, err := client.StartUp(ctx)
+		if err != nil {
+			return err
+		}
+		b := &mqttbridge.Bridge{
+			Watcher: &events.Watcher{
+				Client:   c,
+				Interval: time.Duration(mqttPoll) * time.Millisecond,
+				Wait:     time.Duration(mqttWait) * time.Millisecond,
+			},
+			Prefix:          mqttPrefix,
+			DiscoveryPrefix: mqttDiscoveryPrefix,
+			NoDiscovery:     mqttNoDiscovery,
+		}
+		b.MQTT, err = mqtt.Dial(ctx, mqttBroker, mqtt.Options{
+			ClientID: mqttClientID,
+			Username: mqttUsername,
+			Password: mqttPassword,
+			Will:     &mqtt.Message{Topic: b.StatusTopic(), Payload: []byte("offline"), Retain: true},
+		})
+		if err != nil {
+			return err
+		}
+		defer b.MQTT.Close()
+
+		status("Bridging to %v\n", mqttBroker)
+		return b.Run(ctx)
+	},
+}
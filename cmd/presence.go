@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/presence"
+	"github.com/nathanhack/lifx/core/schedule"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var (
+	presenceWindows   []string
+	presenceJitter    time.Duration
+	presenceSeed      int64
+	presenceFade      time.Duration
+	presenceLatitude  float64
+	presenceLongitude float64
+	presencePlan      int
+)
+
+func init() {
+	rootCmd.AddCommand(presenceCmd)
+
+	presenceCmd.Flags().StringArrayVar(&presenceWindows, "window", []string{"18:30..23:00"}, "ON..OFF times the lights are on, may be repeated")
+	presenceCmd.Flags().DurationVar(&presenceJitter, "jitter", 30*time.Minute, "how far either way of the window each light's times may be, under half the shortest window")
+	presenceCmd.Flags().Int64Var(&presenceSeed, "seed", 0, "seed for the random times (0 picks one)")
+	presenceCmd.Flags().DurationVar(&presenceFade, "fade", time.Second, "how long the lights take to turn on and off")
+	presenceCmd.Flags().Float64Var(&presenceLatitude, "latitude", 0, "latitude for windows at sun events")
+	presenceCmd.Flags().Float64Var(&presenceLongitude, "longitude", 0, "longitude for windows at sun events")
+	presenceCmd.Flags().IntVar(&presencePlan, "plan", 0, "list this many of the coming changes instead of running them")
+}
+
+var presenceCmd = &cobra.Command{
+	Use:   "presence SELECTOR [TIMEOUT_MILLISECONDS]",
+	Short: "Makes a home look lived in",
+	Long: `Turns each of the LIFX lights identified by SELECTOR on and off around the ends of every --window, each light
+at its own random times every day, until interrupted. The ends of a window are clock times or sun events,
+sunrise, sunset, dawn, dusk or noon with an optional offset, which need --latitude and --longitude:
+  --window 18:30..23:00 --window sunset-30m..22:45
+
+Give the same --seed to get the same times, --plan lists them without changing any lights.
+
+` + selectorHelp,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s := &presence.Simulation{Jitter: presenceJitter, Seed: presenceSeed, Fade: presenceFade}
+		for _, arg := range presenceWindows {
+			w, err := presence.ParseWindow(arg)
+			if err != nil {
+				return err
+			}
+			s.Windows = append(s.Windows, w)
+		}
+		if cmd.Flags().Changed("latitude") || cmd.Flags().Changed("longitude") {
+			s.Latitude, s.Longitude = &presenceLatitude, &presenceLongitude
+		}
+		if s.Seed == 0 {
+			s.Seed = time.Now().UnixNano()
+			status("Using seed %v\n", s.Seed)
+		}
+
+		timeout, err := parseTimeout(args, 1)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		c, lights, err := selectClientLights(ctx, args[0], timeout)
+		if err != nil {
+			return err
+		}
+		f, err := s.File(lights)
+		if err != nil {
+			return err
+		}
+		if presencePlan > 0 {
+			return printResults(f.Upcoming(time.Now(), presencePlan))
+		}
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		go func() {
+			select {
+			case <-interrupt:
+				status("Stopping\n")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		status("Simulating presence with %v lights\n", len(lights))
+		scheduler := &schedule.Scheduler{Client: c, File: f, Wait: timeout}
+		return scheduler.Run(ctx)
+	},
+}
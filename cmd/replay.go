@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/capture"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/replay"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var replaySpeed float64
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().Float64Var(&replaySpeed, "speed", 1, "playback speed multiplier (0 sends as fast as possible)")
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay FILE",
+	Short: "Replays the requests in a capture against emulated devices",
+	Long: `Replays the requests recorded in a pcap or pcapng FILE, keeping their timing, against emulated devices built from the
+devices that answered in the capture, and compares what the emulated devices answer with the recorded responses.
+Each response is matched to the recorded one by type and target and the differences are printed. The command
+fails when any response is missing, unexpected or has a different payload.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		packets, err := capture.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		devices := replay.Devices(packets)
+		status("Replaying %v requests against %v emulated devices\n", len(replay.Requests(packets)), len(devices))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		out, in := emulator.StartUp(ctx, devices...)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- replay.Play(ctx, packets, replaySpeed, out)
+		}()
+
+		responses := make([]*capture.Packet, 0)
+		for {
+			select {
+			case err := <-done:
+				if err != nil {
+					return err
+				}
+				//give the last responses a moment to arrive
+				done = nil
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+					cancel()
+				}()
+			case <-ctx.Done():
+				differences := replay.Compare(packets, responses)
+				if err := printResults(differences); err != nil {
+					return err
+				}
+				if len(differences) > 0 {
+					cmd.SilenceUsage = true
+					return fmt.Errorf("%v responses differ from the capture", len(differences))
+				}
+				status("All %v responses match the capture\n", len(responses))
+				return nil
+			case payload := <-in:
+				h, err := header.Decode(payload.Data)
+				if err != nil {
+					continue
+				}
+				responses = append(responses, &capture.Packet{Time: time.Now(), Src: payload.Conn, Header: *h})
+			}
+		}
+	},
+}
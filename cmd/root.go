@@ -2,22 +2,45 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/nathanhack/lifx/cmd/internal/output"
 	"github.com/spf13/cobra"
 	"os"
 )
 
 var ip string
 var port int
+var outputFlag string
+var outputFormat output.Format
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", string(output.Text), "output format: text, json, yaml or csv")
+}
 
 var rootCmd = &cobra.Command{
 	Use:   "lifx",
 	Short: "Does things with LIFX bulbs",
-	Long:  `Does things with LIFX bulbs.`,
+	Long: `Does things with LIFX bulbs.
+
+Results are written to stdout in the --output format while progress messages go to stderr.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
+		outputFormat, err = output.ParseFormat(outputFlag)
+		return
+	},
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
+
+//printResults writes the command's results to stdout in the --output format.
+func printResults(records interface{}) error {
+	return output.Write(os.Stdout, outputFormat, records)
+}
+
+//status prints progress chatter to stderr so stdout stays machine readable.
+func status(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+}
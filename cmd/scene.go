@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/scene"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var sceneFile string
+var sceneApplyDuration uint32
+
+func init() {
+	rootCmd.AddCommand(sceneCmd)
+	sceneCmd.PersistentFlags().StringVar(&sceneFile, "file", scene.DefaultFile(), "scene file")
+
+	sceneCmd.AddCommand(sceneSaveCmd)
+	sceneCmd.AddCommand(sceneApplyCmd)
+	sceneApplyCmd.Flags().Uint32VarP(&sceneApplyDuration, "duration", "d", 0, "time in milliseconds for transition")
+	sceneCmd.AddCommand(sceneListCmd)
+}
+
+var sceneCmd = &cobra.Command{
+	Use:   "scene",
+	Short: "Saves and restores the state of many lights",
+	Long:  `Scene saves the power and color of a set of lights under a NAME and restores them later.`,
+}
+
+var sceneSaveCmd = &cobra.Command{
+	Use:   "save NAME SELECTOR [TIMEOUT_MILLISECONDS]",
+	Short: "Saves the power and color of lights as a scene",
+	Long: `Saves the power and color of the LIFX lights identified by SELECTOR as the scene NAME, replacing any scene
+already saved under NAME.
+
+` + selectorHelp,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, err := parseTimeout(args, 2)
+		if err != nil {
+			return err
+		}
+		scenes, err := scene.ReadFile(sceneFile)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		lights, err := selectLights(ctx, args[1], timeout)
+		if err != nil {
+			return err
+		}
+		cctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		s, err := scene.Capture(cctx, args[0], lights)
+		if err != nil {
+			status("%v\n", err)
+		}
+		if len(s.Lights) == 0 {
+			return fmt.Errorf("no lights answered, scene %v not saved", args[0])
+		}
+
+		scenes[s.Name] = s
+		if err := scene.WriteFile(sceneFile, scenes); err != nil {
+			return err
+		}
+		status("Saved %v lights as scene %v in %v\n", len(s.Lights), s.Name, sceneFile)
+		return printResults(s)
+	},
+}
+
+var sceneApplyCmd = &cobra.Command{
+	Use:   "apply NAME [TIMEOUT_MILLISECONDS]",
+	Short: "Restores the lights of a saved scene",
+	Long:  `Restores the power and color of every light saved in the scene NAME, transitioning all of them at once.`,
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, err := parseTimeout(args, 1)
+		if err != nil {
+			return err
+		}
+		scenes, err := scene.ReadFile(sceneFile)
+		if err != nil {
+			return err
+		}
+		s, has := scenes[args[0]]
+		if !has {
+			return fmt.Errorf("no scene named %q in %v", args[0], sceneFile)
+		}
+		targets, err := s.Targets()
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		c, err := client.StartUp(ctx)
+		if err != nil {
+			return err
+		}
+		fctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		lights, err := c.Find(fctx, targets...)
+		if err != nil {
+			return err
+		}
+
+		status("Applying scene %v to %v of %v lights\n", s.Name, len(lights), len(s.Lights))
+		actx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return s.Apply(actx, lights, time.Duration(sceneApplyDuration)*time.Millisecond)
+	},
+}
+
+var sceneListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the saved scenes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scenes, err := scene.ReadFile(sceneFile)
+		if err != nil {
+			return err
+		}
+		list := make([]*scene.Scene, 0, len(scenes))
+		for _, name := range scenes.Names() {
+			list = append(list, scenes[name])
+		}
+		return printResults(list)
+	},
+}
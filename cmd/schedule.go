@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/scene"
+	"github.com/nathanhack/lifx/core/schedule"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+)
+
+var scheduleSceneFile string
+var scheduleWait uint32
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleRunCmd)
+	scheduleCmd.AddCommand(scheduleNextCmd)
+
+	scheduleRunCmd.Flags().StringVar(&scheduleSceneFile, "scenes", scene.DefaultFile(), "scene file for scene jobs")
+	scheduleRunCmd.Flags().Uint32Var(&scheduleWait, "wait", uint32(schedule.DefaultWait/time.Millisecond), "time in milliseconds discovery listens for lights")
+}
+
+const scheduleHelp = `FILE is YAML:
+  latitude: 51.48          only needed for sun events
+  longitude: -0.01
+  jobs:
+    - name: wake up
+      at: "30 6 * * mon-fri"
+      lights: group:Bedroom
+      color: daylight brightness:100%
+      power: on
+      duration: 20m
+    - at: sunset-15m
+      scene: evening
+    - at: "0 21 * * *"
+      lights: label:Fireplace
+      effect: candle
+      duration: 2h
+at is a cron expression (minute hour day-of-month month day-of-week, or @daily, @hourly, ...) or a sun event,
+sunrise, sunset, dawn, dusk or noon, with an optional offset. Each job sets power and/or color on lights, applies
+a scene or runs an effect. lights is a selector as in the light commands.`
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Runs light changes on a schedule",
+	Long: `Schedule runs the jobs of a schedule file at times given by cron expressions or the sun.
+
+` + scheduleHelp,
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run FILE",
+	Short: "Runs the jobs in a schedule file until interrupted",
+	Long: `Runs the jobs in the schedule FILE as they come due, logging what each did to stderr, until interrupted.
+Discovered lights are reused between jobs.
+
+` + scheduleHelp,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := schedule.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		go func() {
+			select {
+			case <-interrupt:
+				status("Stopping\n")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		c, err := client.StartUp(ctx)
+		if err != nil {
+			return err
+		}
+		s := &schedule.Scheduler{
+			Client:    c,
+			File:      f,
+			SceneFile: scheduleSceneFile,
+			Wait:      time.Duration(scheduleWait) * time.Millisecond,
+		}
+		return s.Run(ctx)
+	},
+}
+
+var scheduleNextCmd = &cobra.Command{
+	Use:   "next FILE [COUNT]",
+	Short: "Lists the next runs of the jobs in a schedule file",
+	Long: `Checks the schedule FILE and lists the next COUNT (default 10) job runs.
+
+` + scheduleHelp,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		count := 10
+		if len(args) > 1 {
+			var err error
+			if count, err = strconv.Atoi(args[1]); err != nil {
+				return err
+			}
+		}
+		f, err := schedule.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		return printResults(f.Upcoming(time.Now(), count))
+	},
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/selector"
+	"gopkg.in/yaml.v2"
+	"net"
+	"strconv"
+	"time"
+)
+
+const selectorHelp = `SELECTOR picks the lights to use:
+  all                  every light found
+  id:TARGET_HEXSTR     a light by target, a bare TARGET_HEXSTR works too
+  label:Kitchen        lights by label
+  group:Upstairs       lights by group
+  location:Home        lights by location
+Values may use * and ? globs and are not case sensitive. Separate several with commas to use the union
+(label:Kitchen,group:Upstairs).
+
+Note if the IP and port are known include those tags then the broadcast step can be skipped, in that
+case SELECTOR must be a single TARGET_HEXSTR.`
+
+//parseTimeout reads the optional TIMEOUT_MILLISECONDS argument at index i.
+func parseTimeout(args []string, i int) (time.Duration, error) {
+	if len(args) <= i {
+		return defaultTimeout, nil
+	}
+	tmp, err := strconv.Atoi(args[i])
+	if err != nil {
+		return 0, err
+	}
+	timeout := time.Duration(tmp) * time.Millisecond
+	status("Timeout found, using %v\n", timeout)
+	return timeout, nil
+}
+
+//selectLights starts a client and resolves the SELECTOR argument to lights.
+func selectLights(ctx context.Context, arg string, timeout time.Duration) ([]*client.Light, error) {
+	_, lights, err := selectClientLights(ctx, arg, timeout)
+	return lights, err
+}
+
+//selectClientLights is selectLights for commands that keep using the client.
+func selectClientLights(ctx context.Context, arg string, timeout time.Duration) (*client.Client, []*client.Light, error) {
+	sel, err := selector.Parse(arg)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := client.StartUp(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ip != "" && port > 0 {
+		targets, exact := sel.Targets()
+		if !exact || len(targets) != 1 {
+			return nil, nil, fmt.Errorf("--ip and --port need a single TARGET_HEXSTR not %q", arg)
+		}
+		address, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%v:%v", ip, port))
+		if err != nil {
+			return nil, nil, err
+		}
+		return c, []*client.Light{c.Light(targets[0], address)}, nil
+	}
+
+	lights, err := sel.Resolve(ctx, c, timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(lights) == 0 {
+		return nil, nil, fmt.Errorf("could not find any light matching %v", sel)
+	}
+	return c, lights, nil
+}
+
+//eachLight runs action on every light at once, each with its own timeout. The results of the lights that
+//succeeded are in the same order as lights, the errors of the others are reported on stderr.
+func eachLight(ctx context.Context, lights []*client.Light, timeout time.Duration, action func(context.Context, *client.Light) (interface{}, error)) ([]lightResult, error) {
+	results := make([]lightResult, len(lights))
+	errs := make([]error, len(lights))
+	err := client.Each(lights, func(i int, l *client.Light) error {
+		actionCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		results[i].Target = l.Target
+		results[i].Value, errs[i] = action(actionCtx, l)
+		return errs[i]
+	})
+
+	succeeded := make([]lightResult, 0, len(lights))
+	for i, err := range errs {
+		if err != nil {
+			status("%x: %v\n", lights[i].Target, err)
+			continue
+		}
+		succeeded = append(succeeded, results[i])
+	}
+	return succeeded, err
+}
+
+//printLightResults prints a lone result the way the single target commands always have, otherwise
+//every result is tagged with its light's target.
+func printLightResults(results []lightResult) error {
+	if len(results) == 1 {
+		return printResults(results[0].Value)
+	}
+	return printResults(results)
+}
+
+//lightResult is one light's answer, tagged with its target.
+type lightResult struct {
+	Target []byte
+	Value  interface{}
+}
+
+func (r lightResult) String() string {
+	return fmt.Sprintf("%x %v", r.Target, r.Value)
+}
+
+//MarshalJSON adds a target field in front of the value's own fields.
+func (r lightResult) MarshalJSON() ([]byte, error) {
+	target, err := json.Marshal(fmt.Sprintf("%x", r.Target))
+	if err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(r.Value)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) > 2 && value[0] == '{' {
+		return bytes.Join([][]byte{[]byte(`{"target":`), target, []byte(","), value[1:]}, nil), nil
+	}
+	return bytes.Join([][]byte{[]byte(`{"target":`), target, []byte(`,"value":`), value, []byte("}")}, nil), nil
+}
+
+func (r lightResult) MarshalYAML() (interface{}, error) {
+	b, err := r.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, MapSlice keeps the field order
+	var m yaml.MapSlice
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/api"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/events"
+	"github.com/nathanhack/lifx/core/scene"
+	"github.com/nathanhack/lifx/core/selector"
+	"github.com/spf13/cobra"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var (
+	serveListen    string
+	serveToken     string
+	serveSceneFile string
+	serveWait      uint32
+	servePoll      uint32
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "address the HTTP server listens on")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "bearer token requests must send (none when empty)")
+	serveCmd.Flags().StringVar(&serveSceneFile, "scenes", scene.DefaultFile(), "scene file for /v1/scenes")
+	serveCmd.Flags().Uint32Var(&serveWait, "wait", uint32(selector.DefaultWait/time.Millisecond), "time in milliseconds discovery listens for lights")
+	serveCmd.Flags().Uint32Var(&servePoll, "poll", uint32(events.DefaultInterval/time.Millisecond), "time in milliseconds between polls for /v1/events (0 turns events off)")
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves a REST API and a web dashboard for the lights",
+	Long: `Serves the parts of the LIFX cloud HTTP API that work offline, backed by the LAN protocol, until interrupted:
+  GET  /v1/lights/:selector
+  PUT  /v1/lights/:selector/state             {"power":"on","color":"blue","brightness":0.5,"duration":2}
+  POST /v1/lights/:selector/toggle            {"duration":1}
+  POST /v1/lights/:selector/effects/:name     breathe, pulse, flame, off or any effect of "effect list"
+  GET  /v1/scenes
+  PUT  /v1/scenes/scene_id::name/activate     {"duration":2}
+  GET  /v1/events                             Server-Sent Events, or a WebSocket when asked to upgrade
+Durations are in seconds. Selectors are the ones of the light commands, e.g. /v1/lights/group:Kitchen/state.
+Discovered lights are reused between requests. Any other path serves a dashboard, open http://HOST:8080/ in a
+browser on the LAN to see the lights by group and change them or apply scenes.
+
+/v1/events sends added, removed, unreachable, reachable, power, color and label events as JSON, starting
+with an added event for every light already known. They come from polling the lights every --poll and
+from the state replies lights send to other apps.
+
+e.g. curl -X PUT -d '{"power":"on"}' http://localhost:8080/v1/lights/all/state`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		c, err := client.StartUp(ctx)
+		if err != nil {
+			return err
+		}
+
+		handler := &api.Server{
+			Client:    c,
+			Lights:    &selector.Cache{Client: c, Wait: time.Duration(serveWait) * time.Millisecond},
+			SceneFile: serveSceneFile,
+			Token:     serveToken,
+		}
+		// stopping the watcher ends the event streams, which shutting down waits for
+		watchCtx, stopWatching := context.WithCancel(ctx)
+		defer stopWatching()
+		if servePoll > 0 {
+			handler.Events = &events.Watcher{
+				Client:   c,
+				Interval: time.Duration(servePoll) * time.Millisecond,
+				Wait:     time.Duration(serveWait) * time.Millisecond,
+			}
+			go handler.Events.Run(watchCtx)
+		}
+		server := &http.Server{Addr: serveListen, Handler: handler}
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		go func() {
+			select {
+			case <-interrupt:
+				status("Stopping\n")
+				stopWatching()
+				shutdownCtx, done := context.WithTimeout(ctx, 5*time.Second)
+				defer done()
+				server.Shutdown(shutdownCtx)
+			case <-ctx.Done():
+			}
+		}()
+
+		status("Listening on %v\n", serveListen)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	},
+}
@@ -0,0 +1,175 @@
+package alarm
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/schedule"
+	"time"
+)
+
+//stepTimeout bounds each change sent to the lights so one that is gone doesn't hold up the sunrise
+const stepTimeout = 10 * time.Second
+
+//grace is how long past At an alarm still counts as on time, later than that a restart finds the lights
+//of an alarm without a ramp already woken
+const grace = time.Minute
+
+//Alarm is a sunrise that starts At and brightens the lights over Ramp.
+type Alarm struct {
+	ID string    `json:"id" yaml:"id"`
+	At time.Time `json:"at" yaml:"at"`
+	//Ramp is how long the sunrise takes
+	Ramp time.Duration `json:"ramp" yaml:"ramp"`
+	//Off turns the lights off this long after the sunrise ended, 0 leaves them on
+	Off time.Duration `json:"off,omitempty" yaml:"off,omitempty"`
+	//Lights is a selector
+	Lights string `json:"lights" yaml:"lights"`
+	//Repeat is a cron expression giving the next At once the alarm is done, empty for a one off
+	Repeat string `json:"repeat,omitempty" yaml:"repeat,omitempty"`
+}
+
+func (a *Alarm) String() string {
+	s := fmt.Sprintf("%v %v ramp:%v %v", a.ID, a.At.Format("Mon 2006-01-02 15:04"), a.Ramp, a.Lights)
+	if a.Off > 0 {
+		s += fmt.Sprintf(" off:%v", a.Off)
+	}
+	if a.Repeat != "" {
+		s += fmt.Sprintf(" repeat:%q", a.Repeat)
+	}
+	return s
+}
+
+//End is when the alarm has nothing more to do.
+func (a *Alarm) End() time.Time {
+	return a.At.Add(a.Ramp + a.Off)
+}
+
+//Next returns when a repeating alarm goes off after t, the zero time for a one off.
+func (a *Alarm) Next(t time.Time) (time.Time, error) {
+	if a.Repeat == "" {
+		return time.Time{}, nil
+	}
+	c, err := schedule.ParseCron(a.Repeat)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.Next(t), nil
+}
+
+//Stop is a color the sunrise passes through, At is the fraction of the ramp.
+type Stop struct {
+	At    float64
+	Color hsbk.HSBK
+}
+
+//Sunrise goes from a barely lit deep red through orange and a warm white to full daylight.
+var Sunrise = []Stop{
+	{0, hsbk.Color{Hue: 0, Saturation: 1, Brightness: 0.01, Kelvin: 2500}.HSBK()},
+	{0.25, hsbk.Color{Hue: 10, Saturation: 1, Brightness: 0.08, Kelvin: 2500}.HSBK()},
+	{0.5, hsbk.Color{Hue: 30, Saturation: 0.8, Brightness: 0.3, Kelvin: 2500}.HSBK()},
+	{0.75, hsbk.Color{Hue: 30, Saturation: 0, Brightness: 0.65, Kelvin: 2700}.HSBK()},
+	{1, hsbk.Color{Hue: 30, Saturation: 0, Brightness: 1, Kelvin: 6500}.HSBK()},
+}
+
+//ColorAt returns the color fraction of the way through the stops.
+func ColorAt(stops []Stop, fraction float64) hsbk.HSBK {
+	if fraction <= stops[0].At {
+		return stops[0].Color
+	}
+	for i := 1; i < len(stops); i++ {
+		if fraction < stops[i].At {
+			a, b := stops[i-1], stops[i]
+			return hsbk.Lerp(a.Color, b.Color, (fraction-a.At)/(b.At-a.At))
+		}
+	}
+	return stops[len(stops)-1].Color
+}
+
+//Wake waits for At and runs the sunrise on the lights, letting them fade from stop to stop themselves,
+//then turns them off if Off is set. Without a Ramp the lights go straight to daylight. When the alarm
+//already started, like after a restart, the lights jump to where the sunrise has got to and carry on from
+//there. Lights that fail a step are still sent the later ones.
+func (a *Alarm) Wake(ctx context.Context, lights []*client.Light) error {
+	var failed error
+	step := func(action func(ctx context.Context, l *client.Light) error) {
+		stepCtx, cancel := context.WithTimeout(ctx, stepTimeout)
+		defer cancel()
+		if err := client.Each(lights, func(_ int, l *client.Light) error { return action(stepCtx, l) }); err != nil {
+			failed = err
+		}
+	}
+	sleepUntil := func(t time.Time) bool {
+		timer := time.NewTimer(time.Until(t))
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		}
+	}
+
+	late := time.Now().After(a.At.Add(grace))
+	if !sleepUntil(a.At) {
+		return ctx.Err()
+	}
+	if a.Ramp <= 0 {
+		if !late {
+			daylight := Sunrise[len(Sunrise)-1].Color
+			step(func(ctx context.Context, l *client.Light) error {
+				if err := l.SetColor(ctx, daylight, 0); err != nil {
+					return err
+				}
+				return l.SetPower(ctx, true, 0)
+			})
+		}
+	} else if progress := a.progress(time.Now()); progress < 1 {
+		start := ColorAt(Sunrise, progress)
+		step(func(ctx context.Context, l *client.Light) error {
+			if err := l.SetColor(ctx, start, 0); err != nil {
+				return err
+			}
+			return l.SetPower(ctx, true, 0)
+		})
+		for _, stop := range Sunrise {
+			if stop.At <= progress {
+				continue
+			}
+			at := a.At.Add(time.Duration(stop.At * float64(a.Ramp)))
+			color := stop.Color
+			step(func(ctx context.Context, l *client.Light) error {
+				return l.SetColor(ctx, color, time.Until(at))
+			})
+			if !sleepUntil(at) {
+				return ctx.Err()
+			}
+		}
+	}
+
+	if a.Off > 0 {
+		if !sleepUntil(a.End()) {
+			return ctx.Err()
+		}
+		step(func(ctx context.Context, l *client.Light) error {
+			return l.SetPower(ctx, false, 2*time.Second)
+		})
+	}
+	return failed
+}
+
+//progress is how far through the ramp the alarm is at t.
+func (a *Alarm) progress(t time.Time) float64 {
+	if a.Ramp <= 0 {
+		if t.Before(a.At) {
+			return 0
+		}
+		return 1
+	}
+	p := float64(t.Sub(a.At)) / float64(a.Ramp)
+	if p < 0 {
+		return 0
+	}
+	return p
+}
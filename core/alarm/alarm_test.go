@@ -0,0 +1,149 @@
+package alarm
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestColorAt(t *testing.T) {
+	if ColorAt(Sunrise, -1) != Sunrise[0].Color || ColorAt(Sunrise, 2) != Sunrise[len(Sunrise)-1].Color {
+		t.Errorf("expected the ends outside the ramp")
+	}
+	if ColorAt(Sunrise, 0.5) != Sunrise[2].Color {
+		t.Errorf("expected a stop exactly at its fraction")
+	}
+	previous := ColorAt(Sunrise, 0)
+	for i := 1; i <= 100; i++ {
+		c := ColorAt(Sunrise, float64(i)/100)
+		if c.Brightness < previous.Brightness {
+			t.Fatalf("expected the sunrise never to get darker but %v went to %v at %v%%", previous, c, i)
+		}
+		previous = c
+	}
+}
+
+func TestAlarms_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alarms.json")
+	alarms, err := ReadFile(path)
+	if err != nil || len(alarms) != 0 {
+		t.Fatalf("expected no alarms before the file exists but got %v, %v", alarms, err)
+	}
+	later := time.Now().Add(time.Hour).Truncate(time.Second)
+	if alarms, err = alarms.Add(&Alarm{At: later, Ramp: time.Minute, Lights: "all", Repeat: "0 7 * * mon-fri"}); err != nil {
+		t.Fatal(err)
+	}
+	if alarms, err = alarms.Add(&Alarm{At: later.Add(-time.Minute), Lights: "label:Bed*"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []*Alarm{{At: later, Ramp: -time.Minute, Lights: "all"}, {At: later, Ramp: time.Minute}, {At: later, Ramp: time.Minute, Lights: "all", Repeat: "tuesdays"}} {
+		if _, err := alarms.Add(bad); err == nil {
+			t.Errorf("expected an error adding %v", bad)
+		}
+	}
+	if err := WriteFile(path, alarms); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 2 || read[0].ID != "2" || !read[1].At.Equal(later) || read[1].Repeat == "" {
+		t.Fatalf("expected the alarms back in time order but got %v", read)
+	}
+	if read = read.Remove("2"); len(read) != 1 || read.Find("1") == nil || read.Find("2") != nil {
+		t.Errorf("expected alarm 2 removed but got %v", read)
+	}
+}
+
+func TestAlarm_Wake(t *testing.T) {
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Color: hsbk.HSBK{Kelvin: 3500}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, bulb)
+	c := client.New(ctx, out, in)
+	l := c.Light(bulb.Target, bulb.Address)
+
+	// already half way, as after a restart
+	a := &Alarm{At: time.Now().Add(-200 * time.Millisecond), Ramp: 400 * time.Millisecond, Off: 200 * time.Millisecond}
+	done := make(chan error)
+	go func() {
+		done <- a.Wake(ctx, []*client.Light{l})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	state, err := l.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Power == 0 || state.Color.Brightness < Sunrise[1].Color.Brightness || state.Color.Brightness > Sunrise[3].Color.Brightness {
+		t.Errorf("expected the light on part way through the sunrise but got %v", state)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if state, _ = l.State(ctx); state.Power != 0 || state.Color != Sunrise[len(Sunrise)-1].Color {
+		t.Errorf("expected the light off after ending in daylight but got %v", state)
+	}
+
+	// no ramp goes straight to daylight
+	a = &Alarm{At: time.Now().Add(100 * time.Millisecond)}
+	if err := a.Wake(ctx, []*client.Light{l}); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ = l.State(ctx); state.Power == 0 || state.Color != Sunrise[len(Sunrise)-1].Color {
+		t.Errorf("expected the light on in daylight without a ramp but got %v", state)
+	}
+
+	path := filepath.Join(t.TempDir(), "alarms.json")
+	alarms, _ := Alarms{}.Add(&Alarm{At: time.Now().Add(100 * time.Millisecond), Ramp: time.Second, Lights: "all"})
+	alarms, _ = alarms.Add(&Alarm{At: time.Now().Add(-time.Hour), Ramp: time.Minute, Lights: "all"})
+	if err := WriteFile(path, alarms); err != nil {
+		t.Fatal(err)
+	}
+	r := &Runner{Client: c, File: path, Wait: 200 * time.Millisecond}
+	if err := r.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if alarms, _ = ReadFile(path); len(alarms) != 0 {
+		t.Errorf("expected the run and the missed alarm gone but got %v", alarms)
+	}
+	if state, _ = l.State(ctx); state.Power == 0 {
+		t.Errorf("expected the runner to wake the light")
+	}
+}
+
+func TestRunner_NoRamp(t *testing.T) {
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Color: hsbk.HSBK{Kelvin: 3500}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, bulb)
+	c := client.New(ctx, out, in)
+
+	path := filepath.Join(t.TempDir(), "alarms.json")
+	alarms, err := Alarms{}.Add(&Alarm{At: time.Now().Add(100 * time.Millisecond), Lights: "all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, alarms); err != nil {
+		t.Fatal(err)
+	}
+	r := &Runner{Client: c, File: path, Wait: 200 * time.Millisecond}
+	if err := r.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if alarms, _ = ReadFile(path); len(alarms) != 0 {
+		t.Errorf("expected the alarm done but got %v", alarms)
+	}
+	state, err := c.Light(bulb.Target, bulb.Address).State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Power == 0 || state.Color != Sunrise[len(Sunrise)-1].Color {
+		t.Errorf("expected the runner to turn the light on in daylight but got %v", state)
+	}
+}
@@ -0,0 +1,110 @@
+package alarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//Alarms is the content of an alarm file, the alarms still to go off or still running.
+type Alarms []*Alarm
+
+//DefaultFile is where alarms are kept unless told otherwise, lifx/alarms.json in the user's config directory.
+func DefaultFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "alarms.json"
+	}
+	return filepath.Join(dir, "lifx", "alarms.json")
+}
+
+//ReadFile loads an alarm file, a file that does not exist yet holds no alarms.
+func ReadFile(path string) (Alarms, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Alarms{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	alarms := Alarms{}
+	if err := json.Unmarshal(b, &alarms); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return alarms, nil
+}
+
+//WriteFile saves the alarms in time order, replacing the file in one step so a crash can't leave half a
+//file behind.
+func WriteFile(path string, alarms Alarms) error {
+	sort.SliceStable(alarms, func(i, j int) bool {
+		return alarms[i].At.Before(alarms[j].At)
+	})
+	b, err := json.MarshalIndent(alarms, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+//Add checks the alarm and gives it the next free ID.
+func (as Alarms) Add(a *Alarm) (Alarms, error) {
+	if a.Ramp < 0 {
+		return as, fmt.Errorf("the ramp can't be negative")
+	}
+	if a.Lights == "" {
+		return as, fmt.Errorf("no lights")
+	}
+	if _, err := a.Next(time.Now()); err != nil {
+		return as, err
+	}
+	highest := 0
+	for _, existing := range as {
+		if id, err := strconv.Atoi(existing.ID); err == nil && id > highest {
+			highest = id
+		}
+	}
+	a.ID = strconv.Itoa(highest + 1)
+	return append(as, a), nil
+}
+
+//Find returns the alarm with the ID, nil if there isn't one.
+func (as Alarms) Find(id string) *Alarm {
+	for _, a := range as {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+//Remove returns the alarms without the one with the ID.
+func (as Alarms) Remove(id string) Alarms {
+	kept := make(Alarms, 0, len(as))
+	for _, a := range as {
+		if a.ID != id {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
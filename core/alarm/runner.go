@@ -0,0 +1,153 @@
+package alarm
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/selector"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+//DefaultWait is how long the lights of an alarm are looked for when it goes off.
+const DefaultWait = 5 * time.Second
+
+//Runner wakes the alarms in an alarm file as they come due. The file is read again every minute so alarms
+//added by another process are picked up, and an alarm only leaves it once it is done, so a Runner started
+//again after a restart finishes the alarms the last one was running.
+type Runner struct {
+	Client *client.Client
+	File   string
+	//Wait is how long the lights are looked for, 0 is DefaultWait
+	Wait time.Duration
+	//Idle keeps Run going when no alarms are left, waiting for new ones
+	Idle bool
+	//Log gets what each alarm did, nil uses logrus' standard logger
+	Log logrus.FieldLogger
+}
+
+//Run wakes the alarms until ctx is done or, unless Idle, none are left.
+func (r *Runner) Run(ctx context.Context) error {
+	running := make(map[string]bool)
+	done := make(chan string)
+	defer func() {
+		for len(running) > 0 {
+			delete(running, <-done)
+		}
+	}()
+
+	for {
+		alarms, err := ReadFile(r.File)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		var next time.Time
+		pending := 0
+		for _, a := range alarms {
+			if running[a.ID] {
+				continue
+			}
+			if a.End().Add(grace).Before(now) {
+				r.log().WithField("alarm", a.ID).Warn("missed")
+				if err := r.finish(a.ID, now); err != nil {
+					return err
+				}
+				continue
+			}
+			pending++
+			if a.At.After(now) {
+				if next.IsZero() || a.At.Before(next) {
+					next = a.At
+				}
+				continue
+			}
+			running[a.ID] = true
+			go func(a *Alarm) {
+				r.wake(ctx, a)
+				done <- a.ID
+			}(a)
+		}
+		if len(running) == 0 && pending == 0 && !r.Idle {
+			return nil
+		}
+
+		// go by the wall clock at least every minute, timers stop while a computer sleeps
+		wait := time.Minute
+		if !next.IsZero() && time.Until(next) < wait {
+			wait = time.Until(next)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case id := <-done:
+			timer.Stop()
+			delete(running, id)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err := r.finish(id, time.Now()); err != nil {
+				return err
+			}
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *Runner) wake(ctx context.Context, a *Alarm) {
+	log := r.log().WithField("alarm", a.ID)
+	sel, err := selector.Parse(a.Lights)
+	if err != nil {
+		log.WithError(err).Error("failed")
+		return
+	}
+	wait := r.Wait
+	if wait <= 0 {
+		wait = DefaultWait
+	}
+	lights, err := sel.Resolve(ctx, r.Client, wait)
+	if err == nil && len(lights) == 0 {
+		err = fmt.Errorf("could not find any light matching %v", sel)
+	}
+	if err != nil {
+		log.WithError(err).Error("failed")
+		return
+	}
+
+	log.Infof("waking %v lights", len(lights))
+	if err := a.Wake(ctx, lights); err != nil {
+		log.WithError(err).Error("failed")
+		return
+	}
+	log.Info("done")
+}
+
+//finish removes a one off alarm from the file and moves a repeating one to its next time. Alarms cut short
+//by the runner stopping aren't finished, so the next run carries them on.
+func (r *Runner) finish(id string, now time.Time) error {
+	alarms, err := ReadFile(r.File)
+	if err != nil {
+		return err
+	}
+	a := alarms.Find(id)
+	if a == nil {
+		return nil
+	}
+	next, err := a.Next(now)
+	if err == nil && !next.IsZero() {
+		a.At = next
+		r.log().WithField("alarm", id).Infof("next at %v", next)
+	} else {
+		alarms = alarms.Remove(id)
+	}
+	return WriteFile(r.File, alarms)
+}
+
+func (r *Runner) log() logrus.FieldLogger {
+	if r.Log == nil {
+		return logrus.StandardLogger()
+	}
+	return r.Log
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/events"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/scene"
+	"github.com/nathanhack/lifx/core/selector"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startUp(t *testing.T, devices ...*emulator.Device) (context.Context, *Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	out, in := emulator.StartUp(ctx, devices...)
+	c := client.New(ctx, out, in)
+	s := &Server{Client: c, Lights: &selector.Cache{Client: c, Wait: 200 * time.Millisecond}, SceneFile: filepath.Join(t.TempDir(), "scenes.json")}
+	return ctx, s
+}
+
+func request(t *testing.T, s *Server, method, path, body string, v interface{}) int {
+	r := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%v %v: %v in %q", method, path, err, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+func TestServer_Lights(t *testing.T) {
+	kitchen := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Label: "Kitchen", Power: 0xffff,
+		Color: hsbk.HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500}, GroupLabel: "Downstairs"}
+	porch := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 2, 0}, Label: "Porch", Color: hsbk.HSBK{Brightness: 0xffff, Kelvin: 2700}}
+	ctx, s := startUp(t, kitchen, porch)
+
+	var listed []Light
+	if status := request(t, s, http.MethodGet, "/v1/lights/all", "", &listed); status != http.StatusOK || len(listed) != 2 {
+		t.Fatalf("unexpected %v %+v", status, listed)
+	}
+	if status := request(t, s, http.MethodGet, "/v1/lights/label:Kitchen", "", &listed); status != http.StatusOK || len(listed) != 1 {
+		t.Fatalf("unexpected %v %+v", status, listed)
+	}
+	l := listed[0]
+	if l.ID != "d073d5000001" || l.Label != "Kitchen" || l.Power != "on" || !l.Connected || l.Color.Hue != 120 || l.Brightness != 0.5 ||
+		l.Group == nil || l.Group.Name != "Downstairs" {
+		t.Errorf("unexpected %+v", l)
+	}
+
+	var results Results
+	status := request(t, s, http.MethodPut, "/v1/lights/label:Porch/state", `{"power":"on","color":"red","brightness":0.25}`, &results)
+	if status != http.StatusMultiStatus || len(results.Results) != 1 || results.Results[0].Status != "ok" || results.Results[0].Label != "Porch" {
+		t.Fatalf("unexpected %v %+v", status, results)
+	}
+	if porch.Power == 0 || porch.Color.Saturation != 0xffff || porch.Color.Hue != 0 || porch.Color.Brightness != hsbk.FromFraction(0.25) {
+		t.Errorf("unexpected porch %+v", porch)
+	}
+
+	// one light is on so toggling turns both off
+	if status := request(t, s, http.MethodPost, "/v1/lights/all/toggle", "", &results); status != http.StatusMultiStatus || len(results.Results) != 2 {
+		t.Fatalf("unexpected %v %+v", status, results)
+	}
+	for _, d := range []*emulator.Device{kitchen, porch} {
+		if on, _ := s.Client.Light(d.Target, d.Address).Power(ctx); on {
+			t.Errorf("expected %v turned off", d.Label)
+		}
+	}
+
+	var e apiError
+	if status := request(t, s, http.MethodGet, "/v1/lights/label:Attic", "", &e); status != http.StatusNotFound || e.Error == "" {
+		t.Errorf("unexpected %v %+v", status, e)
+	}
+	if status := request(t, s, http.MethodPut, "/v1/lights/all/state", `{"power":"dim"}`, &e); status != http.StatusUnprocessableEntity {
+		t.Errorf("unexpected %v %+v", status, e)
+	}
+	if status := request(t, s, http.MethodGet, "/v1/lights/all/state", "", &e); status != http.StatusMethodNotAllowed {
+		t.Errorf("unexpected %v %+v", status, e)
+	}
+
+	s.Token = "secret"
+	if status := request(t, s, http.MethodGet, "/v1/lights/all", "", &e); status != http.StatusUnauthorized {
+		t.Errorf("expected a request without the token refused but got %v", status)
+	}
+}
+
+func TestServer_Effects(t *testing.T) {
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Label: "Kitchen", Power: 0xffff, Color: hsbk.HSBK{Brightness: 0xffff, Kelvin: 3500}}
+	_, s := startUp(t, bulb)
+
+	var results Results
+	status := request(t, s, http.MethodPost, "/v1/lights/all/effects/breathe", `{"color":"blue","period":0.2,"cycles":1000}`, &results)
+	if status != http.StatusMultiStatus || len(results.Results) != 1 || results.Results[0].Status != "ok" {
+		t.Fatalf("unexpected %v %+v", status, results)
+	}
+	s.mutex.Lock()
+	running := s.effects["d073d500000100"]
+	s.mutex.Unlock()
+	if running == nil {
+		t.Fatalf("expected the effect to be running")
+	}
+	if status := request(t, s, http.MethodPost, "/v1/lights/all/effects/off", "", &results); status != http.StatusMultiStatus {
+		t.Fatalf("unexpected %v %+v", status, results)
+	}
+	select {
+	case <-running.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected effects/off to stop the effect")
+	}
+	if bulb.Color.Kelvin != 3500 || bulb.Color.Saturation != 0 {
+		t.Errorf("expected the light restored but got %+v", bulb.Color)
+	}
+
+	var e apiError
+	if status := request(t, s, http.MethodPost, "/v1/lights/all/effects/sparkle", "", &e); status != http.StatusNotFound {
+		t.Errorf("unexpected %v %+v", status, e)
+	}
+}
+
+func TestServer_Scenes(t *testing.T) {
+	red := hsbk.HSBK{Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Label: "Kitchen", Power: 0xffff, Color: red}
+	ctx, s := startUp(t, bulb)
+
+	sc, err := scene.Capture(ctx, "evening", []*client.Light{s.Client.Light(bulb.Target, bulb.Address)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc.Lights = append(sc.Lights, scene.Entry{Target: "d073d500000900"})
+	if err := scene.WriteFile(s.SceneFile, scene.Scenes{sc.Name: sc}); err != nil {
+		t.Fatal(err)
+	}
+
+	var listed []Scene
+	if status := request(t, s, http.MethodGet, "/v1/scenes", "", &listed); status != http.StatusOK || len(listed) != 1 {
+		t.Fatalf("unexpected %v %+v", status, listed)
+	}
+	if listed[0].UUID != "evening" || len(listed[0].States) != 2 || listed[0].States[0].Selector != "id:d073d500000100" || listed[0].States[0].Power != "on" {
+		t.Errorf("unexpected %+v", listed[0])
+	}
+
+	bulb.Power, bulb.Color = 0, hsbk.HSBK{Kelvin: 9000}
+	var results Results
+	status := request(t, s, http.MethodPut, "/v1/scenes/scene_id:evening/activate", "", &results)
+	if status != http.StatusMultiStatus || len(results.Results) != 2 || results.Results[0].Status != "ok" || results.Results[1].Status != "offline" {
+		t.Fatalf("unexpected %v %+v", status, results)
+	}
+	if bulb.Power == 0 || bulb.Color != red {
+		t.Errorf("expected the scene back but got %+v", bulb)
+	}
+
+	var e apiError
+	if status := request(t, s, http.MethodPut, "/v1/scenes/scene_id:morning/activate", "", &e); status != http.StatusNotFound {
+		t.Errorf("unexpected %v %+v", status, e)
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Label: "Kitchen", Color: hsbk.HSBK{Brightness: 0xffff, Kelvin: 3500}}
+	ctx, s := startUp(t, bulb)
+	s.Events = &events.Watcher{Client: s.Client, Wait: 200 * time.Millisecond}
+	s.Events.Find(ctx)
+	s.Events.Poll(ctx, time.Now())
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/v1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected %v", response.Header)
+	}
+	sse := bufio.NewReader(response.Body)
+	readEvent := func() (string, events.Event) {
+		var name string
+		var e events.Event
+		for {
+			line, err := sse.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimSpace(strings.TrimPrefix(line, "event: "))
+			case strings.HasPrefix(line, "data: "):
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+					t.Fatal(err)
+				}
+			case line == "\n":
+				return name, e
+			}
+		}
+	}
+	if name, e := readEvent(); name != "added" || e.ID != "d073d500000100" || e.Label != "Kitchen" {
+		t.Fatalf("unexpected %v %+v", name, e)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var e events.Event
+	if err := conn.ReadJSON(&e); err != nil || e.Type != events.Added {
+		t.Fatalf("unexpected %+v, %v", e, err)
+	}
+
+	if err := s.Client.Light(bulb.Target, bulb.Address).SetPower(ctx, true, 0); err != nil {
+		t.Fatal(err)
+	}
+	s.Events.Poll(ctx, time.Now())
+	if name, e := readEvent(); name != "power" || e.Power != "on" {
+		t.Errorf("unexpected %v %+v", name, e)
+	}
+	if err := conn.ReadJSON(&e); err != nil || e.Type != events.Power || e.Power != "on" {
+		t.Errorf("unexpected %+v, %v", e, err)
+	}
+}
+
+func TestServer_Dashboard(t *testing.T) {
+	_, s := startUp(t)
+	s.Token = "secret"
+	for _, path := range []string{"/", "/dashboard.js", "/dashboard.css"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != http.StatusOK || w.Body.Len() == 0 {
+			t.Errorf("%v: expected the dashboard without a token but got %v", path, w.Code)
+		}
+	}
+
+	s.Events = &events.Watcher{Client: s.Client}
+	r := httptest.NewRequest(http.MethodGet, "/v1/events?token=secret", nil)
+	if !s.authorized(r) {
+		t.Errorf("expected the event stream to take the token in the query")
+	}
+	r = httptest.NewRequest(http.MethodGet, "/v1/lights/all?token=secret", nil)
+	if s.authorized(r) {
+		t.Errorf("expected only the event stream to take the token in the query")
+	}
+}
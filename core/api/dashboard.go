@@ -0,0 +1,21 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//dashboardFiles is the web UI served at /, it uses the API like any other client so it needs no
+//handlers of its own.
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+var dashboard = func() http.Handler {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(files))
+}()
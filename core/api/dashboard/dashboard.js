@@ -0,0 +1,200 @@
+// The dashboard talks to the same REST API as any other client and follows /v1/events to stay current.
+"use strict";
+
+const lights = new Map(); // uuid -> light as GET /v1/lights lists it
+let token = localStorage.getItem("lifx-token") || "";
+let events = null;
+
+const $ = (selector, root = document) => root.querySelector(selector);
+
+function status(text) {
+  $("#status").textContent = text;
+}
+
+async function api(method, path, body) {
+  const headers = {"Content-Type": "application/json"};
+  if (token) headers.Authorization = "Bearer " + token;
+  const response = await fetch(path, {method, headers, body: body && JSON.stringify(body)});
+  if (response.status === 401) {
+    login();
+    throw new Error("unauthorized");
+  }
+  const data = await response.json();
+  if (!response.ok && response.status !== 207) throw new Error(data.error || response.statusText);
+  return data;
+}
+
+function login() {
+  if (events) events.close();
+  $("#login").hidden = false;
+  status("token needed");
+}
+
+$("#login").addEventListener("submit", e => {
+  e.preventDefault();
+  token = e.target.token.value;
+  localStorage.setItem("lifx-token", token);
+  $("#login").hidden = true;
+  start();
+});
+
+// kelvinRGB approximates the color of a white, good enough for a swatch
+function kelvinRGB(kelvin) {
+  const t = kelvin / 100;
+  const clamp = v => Math.max(0, Math.min(255, v));
+  const r = t <= 66 ? 255 : clamp(329.7 * Math.pow(t - 60, -0.1332));
+  const g = t <= 66 ? clamp(99.47 * Math.log(t) - 161.12) : clamp(288.12 * Math.pow(t - 60, -0.0755));
+  const b = t >= 66 ? 255 : t <= 19 ? 0 : clamp(138.52 * Math.log(t - 10) - 305.04);
+  return [r, g, b];
+}
+
+function hsvRGB(h, s, v) {
+  const f = n => {
+    const k = (n + h / 60) % 6;
+    return v - v * s * Math.max(0, Math.min(k, 4 - k, 1));
+  };
+  return [f(5) * 255, f(3) * 255, f(1) * 255];
+}
+
+function rgbHSV(r, g, b) {
+  r /= 255; g /= 255; b /= 255;
+  const max = Math.max(r, g, b), d = max - Math.min(r, g, b);
+  let h = 0;
+  if (d) {
+    if (max === r) h = ((g - b) / d) % 6;
+    else if (max === g) h = (b - r) / d + 2;
+    else h = (r - g) / d + 4;
+  }
+  return [(h * 60 + 360) % 360, max ? d / max : 0, max];
+}
+
+// swatch mixes the hue with the white of the kelvin by the saturation like the bulbs do
+function swatch(light) {
+  const white = kelvinRGB(light.color.kelvin || 3500);
+  const hue = hsvRGB(light.color.hue, 1, 1);
+  const s = light.color.saturation;
+  const rgb = hue.map((c, i) => (c * s + white[i] * (1 - s)) * (0.25 + 0.75 * light.brightness));
+  return "rgb(" + rgb.map(Math.round).join(",") + ")";
+}
+
+function hex(light) {
+  const rgb = hsvRGB(light.color.hue, light.color.saturation, 1);
+  return "#" + rgb.map(c => Math.round(c).toString(16).padStart(2, "0")).join("");
+}
+
+function render() {
+  const groups = new Map();
+  for (const light of lights.values()) {
+    const name = light.group ? light.group.name : "No group";
+    if (!groups.has(name)) groups.set(name, []);
+    groups.get(name).push(light);
+  }
+  const main = $("#groups");
+  main.replaceChildren();
+  for (const name of [...groups.keys()].sort()) {
+    const h2 = document.createElement("h2");
+    h2.textContent = name;
+    const list = document.createElement("div");
+    list.className = "lights";
+    for (const light of groups.get(name).sort((a, b) => a.label.localeCompare(b.label))) {
+      list.append(card(light));
+    }
+    main.append(h2, list);
+  }
+}
+
+function card(light) {
+  const node = $("#light").content.firstElementChild.cloneNode(true);
+  const selector = "/v1/lights/id:" + light.id;
+  const failed = err => status(err.message);
+  node.classList.toggle("offline", !light.connected);
+  $(".swatch", node).style.background = light.power === "on" ? swatch(light) : "#000";
+  $(".label", node).textContent = light.label || light.id;
+
+  const power = $(".power", node);
+  power.textContent = light.power === "on" ? "On" : "Off";
+  power.classList.toggle("on", light.power === "on");
+  power.onclick = () => api("POST", selector + "/toggle").catch(failed);
+
+  const color = $(".color", node);
+  color.value = hex(light);
+  color.onchange = () => {
+    const [h, s] = rgbHSV(...[1, 3, 5].map(i => parseInt(color.value.slice(i, i + 2), 16)));
+    api("PUT", selector + "/state", {color: `hue:${Math.round(h)} saturation:${s.toFixed(3)}`, power: "on"}).catch(failed);
+  };
+
+  const brightness = $(".brightness", node);
+  brightness.value = Math.round(light.brightness * 100);
+  brightness.onchange = () => api("PUT", selector + "/state", {brightness: brightness.value / 100}).catch(failed);
+
+  const kelvin = $(".kelvin", node);
+  kelvin.value = light.color.kelvin;
+  kelvin.onchange = () => api("PUT", selector + "/state", {color: "kelvin:" + kelvin.value + " saturation:0"}).catch(failed);
+  return node;
+}
+
+async function scenes() {
+  const list = await api("GET", "/v1/scenes");
+  const box = $("#scenes .scenes");
+  box.replaceChildren();
+  for (const scene of list) {
+    const button = document.createElement("button");
+    button.textContent = scene.name;
+    button.onclick = () => api("PUT", "/v1/scenes/scene_id:" + encodeURIComponent(scene.uuid) + "/activate", {duration: 1})
+      .then(() => status("applied " + scene.name), err => status(err.message));
+    box.append(button);
+  }
+  $("#scenes").hidden = list.length === 0;
+}
+
+// apply folds an event into the light it is about, the event's color is in percent unlike /v1/lights
+function apply(e) {
+  let light = lights.get(e.id);
+  if (e.type === "removed") {
+    lights.delete(e.id);
+    return light !== undefined;
+  }
+  if (!light) {
+    // a light the list didn't have yet, it gets its group on the next full reload
+    if (e.type !== "added") return false;
+    light = {id: e.id.slice(0, 12), uuid: e.id, label: "", power: "off", color: {hue: 0, saturation: 0, kelvin: 3500}, brightness: 1};
+    lights.set(e.id, light);
+  }
+  light.connected = e.type !== "unreachable";
+  if (e.label !== undefined && e.label !== "") light.label = e.label;
+  if (e.power) light.power = e.power;
+  if (e.color) {
+    light.color = {hue: e.color.hue, saturation: e.color.saturation / 100, kelvin: e.color.kelvin};
+    light.brightness = e.color.brightness / 100;
+  }
+  return true;
+}
+
+function follow() {
+  if (events) events.close();
+  // EventSource can't send headers so the token goes in the query
+  events = new EventSource("/v1/events" + (token ? "?token=" + encodeURIComponent(token) : ""));
+  events.onopen = () => status("live");
+  events.onerror = () => status("reconnecting…");
+  for (const type of ["added", "removed", "unreachable", "reachable", "power", "color", "label"]) {
+    events.addEventListener(type, message => {
+      if (apply(JSON.parse(message.data))) render();
+    });
+  }
+}
+
+async function start() {
+  try {
+    status("loading…");
+    for (const light of await api("GET", "/v1/lights/all")) lights.set(light.uuid, light);
+    render();
+    status("");
+  } catch (err) {
+    if (err.message === "unauthorized") return;
+    status(err.message);
+  }
+  scenes().catch(() => {});
+  follow();
+}
+
+start();
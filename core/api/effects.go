@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/effect"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"net/http"
+	"time"
+)
+
+//Effect is the body of POST /v1/lights/:selector/effects/:name. Period, Cycles and Duration are in
+//seconds and cycles, Duration wins over Cycles and without either an effect runs until effects/off.
+type Effect struct {
+	Color    string  `json:"color"`
+	Period   float64 `json:"period"`
+	Cycles   float64 `json:"cycles"`
+	Duration float64 `json:"duration"`
+	//Persist leaves the lights as the effect left them instead of restoring them
+	Persist bool `json:"persist"`
+}
+
+//effectNames are the cloud API's names for the effects that have one here.
+var effectNames = map[string]string{
+	"flame": "candle",
+	"pulse": "strobe",
+}
+
+func (s *Server) effect(ctx context.Context, arg, name string, r *http.Request) (int, interface{}, error) {
+	var body Effect
+	if err := decode(r, &body); err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+	lights, status, err := s.lights(ctx, arg)
+	if err != nil {
+		return status, nil, err
+	}
+	if name == "off" {
+		s.stopEffects(lights)
+		return http.StatusMultiStatus, each(ctx, lights, func(*client.Light, *light.State) error { return nil }), nil
+	}
+
+	// breathe and pulse default to one 1 second cycle like the cloud API
+	if name == "breathe" || name == "pulse" {
+		if body.Period == 0 {
+			body.Period = 1
+		}
+		if body.Cycles == 0 && body.Duration == 0 {
+			body.Cycles = 1
+		}
+	}
+	params := effect.Params{Period: seconds(body.Period)}
+	if body.Color != "" {
+		spec, err := hsbk.Parse(body.Color)
+		if err != nil {
+			return http.StatusUnprocessableEntity, nil, err
+		}
+		color := spec.Apply(hsbk.HSBK{Brightness: 0xffff, Kelvin: hsbk.NeutralKelvin})
+		params.Color = &color
+	}
+	if alias, has := effectNames[name]; has {
+		name = alias
+	}
+	e, err := effect.Named(name, params)
+	if err != nil {
+		return http.StatusNotFound, nil, err
+	}
+	duration := seconds(body.Duration)
+	if duration <= 0 {
+		duration = time.Duration(body.Cycles * float64(seconds(body.Period)))
+	}
+	if duration < 0 {
+		return http.StatusUnprocessableEntity, nil, fmt.Errorf("negative duration")
+	}
+
+	results := each(ctx, lights, func(*client.Light, *light.State) error { return nil })
+	s.stopEffects(lights)
+	// the effect outlives the request
+	running := effect.Start(context.Background(), e, lights, effect.Options{Duration: duration, Restore: !body.Persist})
+	s.mutex.Lock()
+	if s.effects == nil {
+		s.effects = make(map[string]*effect.Running)
+	}
+	for _, l := range lights {
+		s.effects[l.TargetHex()] = running
+	}
+	s.mutex.Unlock()
+	go func() {
+		<-running.Done()
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for target, r := range s.effects {
+			if r == running {
+				delete(s.effects, target)
+			}
+		}
+	}()
+	return http.StatusMultiStatus, results, nil
+}
+
+//stopEffects stops the effects running on any of the lights, which stops them on the other lights
+//started with them too.
+func (s *Server) stopEffects(lights []*client.Light) {
+	s.mutex.Lock()
+	stopping := make(map[*effect.Running]bool)
+	for _, l := range lights {
+		if running, has := s.effects[l.TargetHex()]; has {
+			stopping[running] = true
+		}
+	}
+	s.mutex.Unlock()
+	for running := range stopping {
+		running.Stop()
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/nathanhack/lifx/core/events"
+	"net/http"
+	"time"
+)
+
+//keepAlive is how often an idle stream is written to so proxies don't close it.
+const keepAlive = 30 * time.Second
+
+var upgrader = websocket.Upgrader{
+	// dashboards are usually served from elsewhere, the token is what guards the events
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+//events streams the watcher's events, over a WebSocket when the request asks for an upgrade, otherwise
+//as Server-Sent Events. Each stream starts with an added event for every light already known.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	if s.Events == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("events are not enabled"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%v needs %v", r.URL.Path, http.MethodGet))
+		return
+	}
+	subscribed, unsubscribe := s.Events.Subscribe()
+	defer unsubscribe()
+	if websocket.IsWebSocketUpgrade(r) {
+		s.webSocket(w, r, subscribed)
+		return
+	}
+	s.serverSent(w, r, subscribed)
+}
+
+func (s *Server) serverSent(w http.ResponseWriter, r *http.Request, subscribed <-chan events.Event) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(e events.Event) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %v\ndata: %s\n\n", e.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	for _, e := range s.Events.Snapshot() {
+		if write(e) != nil {
+			return
+		}
+	}
+	// sends the headers even when no light is known yet
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, open := <-subscribed:
+			if !open || write(e) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) webSocket(w http.ResponseWriter, r *http.Request, subscribed <-chan events.Event) {
+	// the upgrader answers failed upgrades itself
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// nothing is expected from the client, reading handles its pings and notices when it goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, e := range s.Events.Snapshot() {
+		if conn.WriteJSON(e) != nil {
+			return
+		}
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)) != nil {
+				return
+			}
+		case e, open := <-subscribed:
+			if !open {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+				return
+			}
+			if conn.WriteJSON(e) != nil {
+				return
+			}
+		}
+	}
+}
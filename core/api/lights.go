@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//Light is a light as GET /v1/lights lists it.
+type Light struct {
+	ID         string     `json:"id"`
+	UUID       string     `json:"uuid"`
+	Label      string     `json:"label"`
+	Connected  bool       `json:"connected"`
+	Power      string     `json:"power"`
+	Color      Color      `json:"color"`
+	Brightness float64    `json:"brightness"`
+	Group      *Reference `json:"group,omitempty"`
+	Location   *Reference `json:"location,omitempty"`
+	LastSeen   time.Time  `json:"last_seen"`
+	SinceSeen  float64    `json:"seconds_since_seen"`
+}
+
+//Color is the hue in degrees, the saturation from 0 to 1 and the kelvin.
+type Color struct {
+	Hue        float64 `json:"hue"`
+	Saturation float64 `json:"saturation"`
+	Kelvin     uint16  `json:"kelvin"`
+}
+
+//Reference is a group or location.
+type Reference struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+//Result is what happened to one light, Status is ok or timed_out.
+type Result struct {
+	ID     string `json:"id"`
+	Label  string `json:"label"`
+	Status string `json:"status"`
+}
+
+//Results is the body of every request changing lights, sent with 207 Multi-Status.
+type Results struct {
+	Results []Result `json:"results"`
+}
+
+//id is the light's serial number, the first 6 bytes of its target.
+func id(l *client.Light) string {
+	target := l.Target
+	if len(target) > 6 {
+		target = target[:6]
+	}
+	return hex.EncodeToString(target)
+}
+
+func newColor(c hsbk.HSBK) Color {
+	color := c.Color()
+	return Color{Hue: round(color.Hue), Saturation: round(color.Saturation), Kelvin: c.Kelvin}
+}
+
+func round(f float64) float64 {
+	return float64(int64(f*10000+0.5)) / 10000
+}
+
+func power(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func (s *Server) list(ctx context.Context, arg string) (int, interface{}, error) {
+	lights, status, err := s.lights(ctx, arg)
+	if err != nil {
+		return status, nil, err
+	}
+
+	listed := make([]Light, len(lights))
+	var wg sync.WaitGroup
+	for i, l := range lights {
+		wg.Add(1)
+		go func(i int, l *client.Light) {
+			defer wg.Done()
+			listed[i] = Light{ID: id(l), UUID: l.TargetHex(), Power: "off"}
+			state, err := l.State(ctx)
+			if err != nil {
+				return
+			}
+			now := time.Now()
+			listed[i].Connected = true
+			listed[i].LastSeen = now.UTC().Truncate(time.Second)
+			listed[i].Label = fields.Label(state.Label)
+			listed[i].Power = power(state.Power != 0)
+			listed[i].Color = newColor(state.Color)
+			listed[i].Brightness = round(hsbk.Fraction(state.Color.Brightness))
+			if g, err := l.Group(ctx); err == nil {
+				listed[i].Group = &Reference{ID: hex.EncodeToString(g.Group[:]), Name: fields.Label(g.Label)}
+			}
+			if loc, err := l.Location(ctx); err == nil {
+				listed[i].Location = &Reference{ID: hex.EncodeToString(loc.Location[:]), Name: fields.Label(loc.Label)}
+			}
+		}(i, l)
+	}
+	wg.Wait()
+	return http.StatusOK, listed, nil
+}
+
+//State is the body of PUT /v1/lights/:selector/state, fields left out are left as they are.
+type State struct {
+	Power      string   `json:"power"`
+	Color      string   `json:"color"`
+	Brightness *float64 `json:"brightness"`
+	//Duration is in seconds
+	Duration float64 `json:"duration"`
+}
+
+//spec is the color and brightness of the state as a color spec.
+func (st State) spec() (hsbk.Spec, error) {
+	var spec hsbk.Spec
+	if st.Color != "" {
+		var err error
+		if spec, err = hsbk.Parse(st.Color); err != nil {
+			return spec, err
+		}
+	}
+	if st.Brightness != nil {
+		if *st.Brightness < 0 || *st.Brightness > 1 {
+			return spec, fmt.Errorf("brightness %v is outside 0-1", *st.Brightness)
+		}
+		spec.Brightness = hsbk.FromFraction(*st.Brightness)
+		spec.Set |= hsbk.BrightnessField
+	}
+	return spec, nil
+}
+
+func (s *Server) setState(ctx context.Context, arg string, r *http.Request) (int, interface{}, error) {
+	var st State
+	if err := decode(r, &st); err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+	spec, err := st.spec()
+	if err != nil {
+		return http.StatusUnprocessableEntity, nil, err
+	}
+	if st.Power != "" && st.Power != "on" && st.Power != "off" {
+		return http.StatusUnprocessableEntity, nil, fmt.Errorf("power %q is not on or off", st.Power)
+	}
+	lights, status, err := s.lights(ctx, arg)
+	if err != nil {
+		return status, nil, err
+	}
+
+	duration := seconds(st.Duration)
+	return http.StatusMultiStatus, each(ctx, lights, func(l *client.Light, state *light.State) error {
+		if spec.Set != 0 {
+			if err := l.SetColor(ctx, spec.Apply(state.Color), duration); err != nil {
+				return err
+			}
+		}
+		if st.Power != "" {
+			return l.SetPower(ctx, st.Power == "on", duration)
+		}
+		return nil
+	}), nil
+}
+
+//Toggle is the body of POST /v1/lights/:selector/toggle.
+type Toggle struct {
+	//Duration is in seconds
+	Duration float64 `json:"duration"`
+}
+
+//toggle turns every light off when any of them is on, otherwise it turns them all on.
+func (s *Server) toggle(ctx context.Context, arg string, r *http.Request) (int, interface{}, error) {
+	var t Toggle
+	if err := decode(r, &t); err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+	lights, status, err := s.lights(ctx, arg)
+	if err != nil {
+		return status, nil, err
+	}
+
+	on := true
+	for _, m := range s.states(ctx, lights) {
+		if m != nil && m.Power != 0 {
+			on = false
+		}
+	}
+	return http.StatusMultiStatus, each(ctx, lights, func(l *client.Light, state *light.State) error {
+		return l.SetPower(ctx, on, seconds(t.Duration))
+	}), nil
+}
+
+//states asks every light for its state at once, nil for the ones that didn't answer.
+func (s *Server) states(ctx context.Context, lights []*client.Light) []*light.State {
+	states := make([]*light.State, len(lights))
+	var wg sync.WaitGroup
+	for i, l := range lights {
+		wg.Add(1)
+		go func(i int, l *client.Light) {
+			defer wg.Done()
+			states[i], _ = l.State(ctx)
+		}(i, l)
+	}
+	wg.Wait()
+	return states
+}
+
+//each gets every light's state and runs action with it, all at once, reporting each light's result.
+//The state gives the label and the color to keep for fields a request leaves out.
+func each(ctx context.Context, lights []*client.Light, action func(*client.Light, *light.State) error) Results {
+	results := make([]Result, len(lights))
+	var wg sync.WaitGroup
+	for i, l := range lights {
+		wg.Add(1)
+		go func(i int, l *client.Light) {
+			defer wg.Done()
+			results[i] = Result{ID: id(l), Status: "timed_out"}
+			state, err := l.State(ctx)
+			if err != nil {
+				return
+			}
+			results[i].Label = fields.Label(state.Label)
+			if err := action(l, state); err != nil {
+				return
+			}
+			results[i].Status = "ok"
+		}(i, l)
+	}
+	wg.Wait()
+	return Results{Results: results}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/scene"
+	"github.com/nathanhack/lifx/core/selector"
+	"net/http"
+	"strings"
+)
+
+//Scene is a scene as GET /v1/scenes lists it, the UUID is the scene's name.
+type Scene struct {
+	UUID      string       `json:"uuid"`
+	Name      string       `json:"name"`
+	States    []SceneState `json:"states"`
+	CreatedAt int64        `json:"created_at"`
+	UpdatedAt int64        `json:"updated_at"`
+}
+
+//SceneState is one light of a scene.
+type SceneState struct {
+	Selector   string  `json:"selector"`
+	Power      string  `json:"power"`
+	Color      Color   `json:"color"`
+	Brightness float64 `json:"brightness"`
+}
+
+//Activate is the body of PUT /v1/scenes/scene_id::uuid/activate.
+type Activate struct {
+	//Duration is in seconds
+	Duration float64 `json:"duration"`
+}
+
+func (s *Server) scenes() (int, interface{}, error) {
+	scenes, err := scene.ReadFile(s.SceneFile)
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+	listed := make([]Scene, 0, len(scenes))
+	for _, name := range scenes.Names() {
+		sc := scenes[name]
+		listed = append(listed, Scene{UUID: name, Name: name, CreatedAt: sc.Saved.Unix(), UpdatedAt: sc.Saved.Unix(), States: sceneStates(sc)})
+	}
+	return http.StatusOK, listed, nil
+}
+
+func sceneStates(sc *scene.Scene) []SceneState {
+	states := make([]SceneState, len(sc.Lights))
+	for i, e := range sc.Lights {
+		states[i] = SceneState{
+			Selector:   "id:" + e.Target,
+			Power:      power(e.State.Power != 0),
+			Color:      newColor(e.State.Color),
+			Brightness: round(hsbk.Fraction(e.State.Color.Brightness)),
+		}
+	}
+	return states
+}
+
+func (s *Server) activate(ctx context.Context, arg string, r *http.Request) (int, interface{}, error) {
+	if !strings.HasPrefix(arg, "scene_id:") {
+		return http.StatusUnprocessableEntity, nil, fmt.Errorf("expected scene_id:NAME not %q", arg)
+	}
+	var body Activate
+	if err := decode(r, &body); err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+	scenes, err := scene.ReadFile(s.SceneFile)
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+	sc, has := scenes[strings.TrimPrefix(arg, "scene_id:")]
+	if !has {
+		return http.StatusNotFound, nil, fmt.Errorf("no scene %q", strings.TrimPrefix(arg, "scene_id:"))
+	}
+
+	entries := make(map[string]scene.Entry)
+	sel := make(selector.Selector, 0, len(sc.Lights))
+	for _, e := range sc.Lights {
+		entries[e.Target] = e
+		sel = append(sel, selector.Term{Field: selector.ID, Pattern: e.Target})
+	}
+	lights, err := s.Lights.Lights(ctx, sel)
+	if err != nil {
+		return http.StatusNotFound, nil, err
+	}
+	duration := seconds(body.Duration)
+	results := each(ctx, lights, func(l *client.Light, _ *light.State) error {
+		return entries[l.TargetHex()].Apply(ctx, l, duration)
+	})
+
+	// the scene's lights that weren't found
+	found := make(map[string]bool)
+	for _, l := range lights {
+		found[l.TargetHex()] = true
+	}
+	for _, e := range sc.Lights {
+		if !found[e.Target] {
+			target, _ := hex.DecodeString(e.Target)
+			if len(target) > 6 {
+				target = target[:6]
+			}
+			results.Results = append(results.Results, Result{ID: hex.EncodeToString(target), Status: "offline"})
+		}
+	}
+	return http.StatusMultiStatus, results, nil
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/effect"
+	"github.com/nathanhack/lifx/core/events"
+	"github.com/nathanhack/lifx/core/selector"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+//DefaultTimeout bounds the LAN requests of each HTTP request.
+const DefaultTimeout = 5 * time.Second
+
+//Server answers the parts of the LIFX HTTP API that make sense offline, using the LAN protocol:
+//
+//	GET  /v1/lights/:selector
+//	PUT  /v1/lights/:selector/state
+//	POST /v1/lights/:selector/toggle
+//	POST /v1/lights/:selector/effects/:name    breathe, flame, off or any of effect.Names()
+//	GET  /v1/scenes
+//	PUT  /v1/scenes/scene_id::name/activate
+//	GET  /v1/events                            Server-Sent Events or a WebSocket of events.Event
+//
+//Selectors are the ones of the selector package, which covers all, id, label, group and location. Every
+//other path serves the dashboard, a web UI built on these endpoints.
+type Server struct {
+	Client *client.Client
+	//Lights finds the lights for a selector without broadcasting on every request
+	Lights *selector.Cache
+	//SceneFile is where the scenes are read from
+	SceneFile string
+	//Token, when set, must be sent as a bearer token like with the cloud API
+	Token string
+	//Events, when set, is streamed by /v1/events, its Run must be going for anything to be sent
+	Events *events.Watcher
+	//Timeout bounds the LAN requests of each HTTP request, 0 is DefaultTimeout
+	Timeout time.Duration
+
+	mutex   sync.Mutex
+	effects map[string]*effect.Running
+}
+
+//apiError is sent back with any status but 200 and 207.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the dashboard asks for the token itself
+	if !strings.HasPrefix(r.URL.Path, "/v1/") {
+		dashboard.ServeHTTP(w, r)
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or wrong bearer token"))
+		return
+	}
+
+	// streams last as long as the client listens
+	if strings.Trim(r.URL.Path, "/") == "v1/events" {
+		s.events(w, r)
+		return
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "v1" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no endpoint %v", r.URL.Path))
+		return
+	}
+	route := func(method string, handle func() (int, interface{}, error)) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%v needs %v", r.URL.Path, method))
+			return
+		}
+		status, body, err := handle()
+		if err != nil {
+			writeError(w, status, err)
+			return
+		}
+		writeJSON(w, status, body)
+	}
+
+	switch {
+	case parts[1] == "lights" && len(parts) == 3:
+		route(http.MethodGet, func() (int, interface{}, error) { return s.list(ctx, parts[2]) })
+	case parts[1] == "lights" && len(parts) == 4 && parts[3] == "state":
+		route(http.MethodPut, func() (int, interface{}, error) { return s.setState(ctx, parts[2], r) })
+	case parts[1] == "lights" && len(parts) == 4 && parts[3] == "toggle":
+		route(http.MethodPost, func() (int, interface{}, error) { return s.toggle(ctx, parts[2], r) })
+	case parts[1] == "lights" && len(parts) == 5 && parts[3] == "effects":
+		route(http.MethodPost, func() (int, interface{}, error) { return s.effect(ctx, parts[2], parts[4], r) })
+	case parts[1] == "scenes" && len(parts) == 2:
+		route(http.MethodGet, func() (int, interface{}, error) { return s.scenes() })
+	case parts[1] == "scenes" && len(parts) == 4 && parts[3] == "activate":
+		route(http.MethodPut, func() (int, interface{}, error) { return s.activate(ctx, parts[2], r) })
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no endpoint %v", r.URL.Path))
+	}
+}
+
+//authorized checks the bearer token. Browsers can't set headers on an EventSource so the event stream
+//also takes the token as ?token=.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" || r.Header.Get("Authorization") == "Bearer "+s.Token {
+		return true
+	}
+	return strings.Trim(r.URL.Path, "/") == "v1/events" && r.URL.Query().Get("token") == s.Token
+}
+
+//lights resolves the selector of a URL, a 404 when nothing matches like the cloud API.
+func (s *Server) lights(ctx context.Context, arg string) ([]*client.Light, int, error) {
+	sel, err := selector.Parse(arg)
+	if err != nil {
+		return nil, http.StatusUnprocessableEntity, err
+	}
+	lights, err := s.Lights.Lights(ctx, sel)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	return lights, http.StatusOK, nil
+}
+
+//decode reads a JSON body into v, an empty body leaves v as it is.
+func decode(r *http.Request, v interface{}) error {
+	if r.Body == nil || r.ContentLength == 0 {
+		return nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("bad request body: %v", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{Error: err.Error()})
+}
+
+//seconds turns the API's durations, floating point seconds, into a time.Duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
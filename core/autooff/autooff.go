@@ -0,0 +1,127 @@
+package autooff
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval = time.Minute
+	DefaultFade     = 10 * time.Second
+)
+
+//Policy turns lights off once they have been on for longer than After. Only the lights' state is looked
+//at, so a light counts as on from the first poll that finds it on and a light turned off and on again
+//between two polls keeps counting.
+type Policy struct {
+	//After is how long a light may stay on
+	After time.Duration
+	//Interval is how often the lights are polled, 0 is DefaultInterval
+	Interval time.Duration
+	//Fade is how long the lights take to go off, 0 is DefaultFade
+	Fade time.Duration
+	//Log gets every light turned off, nil uses logrus' standard logger
+	Log logrus.FieldLogger
+
+	mutex   sync.Mutex
+	onSince map[string]time.Time
+}
+
+//Run polls the lights every Interval until ctx is done.
+func (p *Policy) Run(ctx context.Context, lights []*client.Light) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		p.Check(checkCtx, lights, time.Now())
+		cancel()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+//Check polls every light once and turns off those found on for longer than After by now. It returns
+//the lights turned off.
+func (p *Policy) Check(ctx context.Context, lights []*client.Light, now time.Time) []*client.Light {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.onSince == nil {
+		p.onSince = make(map[string]time.Time)
+	}
+
+	off := make([]bool, len(lights))
+	since := make([]time.Time, len(lights))
+	for i, l := range lights {
+		since[i] = p.onSince[l.TargetHex()]
+	}
+	var wg sync.WaitGroup
+	for i, l := range lights {
+		wg.Add(1)
+		go func(i int, l *client.Light) {
+			defer wg.Done()
+			since[i], off[i] = p.check(ctx, l, since[i], now)
+		}(i, l)
+	}
+	wg.Wait()
+
+	turnedOff := make([]*client.Light, 0)
+	for i, l := range lights {
+		if since[i].IsZero() {
+			delete(p.onSince, l.TargetHex())
+		} else {
+			p.onSince[l.TargetHex()] = since[i]
+		}
+		if off[i] {
+			turnedOff = append(turnedOff, l)
+		}
+	}
+	return turnedOff
+}
+
+//check returns when the light was first seen on, zero if it is off, and whether it was turned off.
+func (p *Policy) check(ctx context.Context, l *client.Light, since, now time.Time) (time.Time, bool) {
+	log := p.log().WithField("light", l.TargetHex())
+	state, err := l.State(ctx)
+	if err != nil {
+		log.WithError(err).Warn("could not get state")
+		// a light that doesn't answer keeps its time
+		return since, false
+	}
+	if state.Power == 0 {
+		return time.Time{}, false
+	}
+	if since.IsZero() {
+		return now, false
+	}
+	if now.Sub(since) < p.After {
+		return since, false
+	}
+
+	fade := p.Fade
+	if fade <= 0 {
+		fade = DefaultFade
+	}
+	if err := l.SetPower(ctx, false, fade); err != nil {
+		log.WithError(err).Warn("could not turn off")
+		return since, false
+	}
+	log.Infof("on since %v, turned off", since.Format("15:04:05"))
+	return time.Time{}, true
+}
+
+func (p *Policy) log() logrus.FieldLogger {
+	if p.Log == nil {
+		return logrus.StandardLogger()
+	}
+	return p.Log
+}
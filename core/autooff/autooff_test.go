@@ -0,0 +1,53 @@
+package autooff
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"testing"
+	"time"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	devices := []*emulator.Device{
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Power: 0xffff},
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 2, 0}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, devices...)
+	c := client.New(ctx, out, in)
+	on, off := c.Light(devices[0].Target, devices[0].Address), c.Light(devices[1].Target, devices[1].Address)
+	lights := []*client.Light{on, off}
+
+	p := &Policy{After: time.Hour, Fade: time.Millisecond}
+	start := time.Now()
+	if turnedOff := p.Check(ctx, lights, start); len(turnedOff) != 0 {
+		t.Fatalf("expected nothing turned off on the first look but got %v", turnedOff)
+	}
+	if turnedOff := p.Check(ctx, lights, start.Add(30*time.Minute)); len(turnedOff) != 0 {
+		t.Fatalf("expected nothing turned off before an hour but got %v", turnedOff)
+	}
+
+	// the second light is turned on half way, its hour starts then
+	if err := off.SetPower(ctx, true, 0); err != nil {
+		t.Fatal(err)
+	}
+	p.Check(ctx, lights, start.Add(30*time.Minute))
+	turnedOff := p.Check(ctx, lights, start.Add(time.Hour))
+	if len(turnedOff) != 1 || turnedOff[0] != on {
+		t.Fatalf("expected only the first light turned off but got %v", turnedOff)
+	}
+	if power, _ := on.PowerLevel(ctx); power != 0 {
+		t.Errorf("expected the light off but its power is %v", power)
+	}
+
+	// turned back on, it counts from scratch
+	if err := on.SetPower(ctx, true, 0); err != nil {
+		t.Fatal(err)
+	}
+	turnedOff = p.Check(ctx, lights, start.Add(90*time.Minute))
+	if len(turnedOff) != 1 || turnedOff[0] != off {
+		t.Errorf("expected only the second light turned off but got %v", turnedOff)
+	}
+}
@@ -1,6 +1,8 @@
 package broadcast
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net"
 )
@@ -9,8 +11,57 @@ type BroadcastResult struct {
 	Target []byte
 	IP     net.IP
 	Port   int
+	Label  string // only filled in when labels were requested
 }
 
 func (l BroadcastResult) String() string {
 	return fmt.Sprintf("BroadcastResult{ Target:%x IP:%v Port:%v}", l.Target, l.IP, l.Port)
 }
+
+type broadcastResultJSON struct {
+	Target string `json:"target" yaml:"target"`
+	Label  string `json:"label,omitempty" yaml:"label,omitempty"`
+	IP     string `json:"ip" yaml:"ip"`
+	Port   int    `json:"port" yaml:"port"`
+}
+
+func (l BroadcastResult) toJSON() broadcastResultJSON {
+	return broadcastResultJSON{
+		Target: hex.EncodeToString(l.Target),
+		Label:  l.Label,
+		IP:     l.IP.String(),
+		Port:   l.Port,
+	}
+}
+
+func (l *BroadcastResult) fromJSON(j broadcastResultJSON) (err error) {
+	l.Target, err = hex.DecodeString(j.Target)
+	l.IP = net.ParseIP(j.IP)
+	l.Port = j.Port
+	l.Label = j.Label
+	return
+}
+
+func (l BroadcastResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.toJSON())
+}
+
+func (l *BroadcastResult) UnmarshalJSON(data []byte) error {
+	var j broadcastResultJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	return l.fromJSON(j)
+}
+
+func (l BroadcastResult) MarshalYAML() (interface{}, error) {
+	return l.toJSON(), nil
+}
+
+func (l *BroadcastResult) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j broadcastResultJSON
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	return l.fromJSON(j)
+}
@@ -0,0 +1,75 @@
+package capture
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/nathanhack/lifx/core/header"
+	"net"
+	"time"
+)
+
+//Link-layer types understood by the reader. Captures written by this package always use LinkTypeRaw.
+const (
+	LinkTypeNull      = 0
+	LinkTypeEthernet  = 1
+	LinkTypeRaw       = 101
+	LinkTypeLinuxSLL  = 113
+	LinkTypeIPv4      = 228
+	LinkTypeIPv6      = 229
+	LinkTypeLinuxSLL2 = 276
+
+	//linkTypeRawAlt is the value some BSDs use for LINKTYPE_RAW.
+	linkTypeRawAlt = 12
+)
+
+//Packet is a single LIFX datagram recovered from (or destined for) a capture file.
+type Packet struct {
+	Time   time.Time
+	Src    *net.UDPAddr
+	Dst    *net.UDPAddr
+	Header header.Header // the whole datagram, use Header.Data() for the payload
+}
+
+func (p Packet) String() string {
+	return fmt.Sprintf("%v %v -> %v %v", p.Time.Format(time.RFC3339Nano), p.Src, p.Dst, p.Header)
+}
+
+type packetJSON struct {
+	Time     time.Time `json:"time" yaml:"time"`
+	Src      string    `json:"src" yaml:"src"`
+	Dst      string    `json:"dst" yaml:"dst"`
+	Type     uint16    `json:"type" yaml:"type"`
+	Target   string    `json:"target" yaml:"target"`
+	Source   uint32    `json:"source" yaml:"source"`
+	Sequence byte      `json:"sequence" yaml:"sequence"`
+	Payload  string    `json:"payload" yaml:"payload"`
+}
+
+func (p Packet) toJSON() packetJSON {
+	return packetJSON{
+		Time:     p.Time.UTC(),
+		Src:      addressString(p.Src),
+		Dst:      addressString(p.Dst),
+		Type:     p.Header.Type(),
+		Target:   p.Header.TargetHex(),
+		Source:   p.Header.Source(),
+		Sequence: p.Header.Sequence(),
+		Payload:  hex.EncodeToString(p.Header.Data()),
+	}
+}
+
+func addressString(address *net.UDPAddr) string {
+	if address == nil {
+		return ""
+	}
+	return address.String()
+}
+
+func (p Packet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.toJSON())
+}
+
+func (p Packet) MarshalYAML() (interface{}, error) {
+	return p.toJSON(), nil
+}
@@ -0,0 +1,208 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/server"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func getServicePacket() []byte {
+	head := header.New(1)
+	message := device.GetService{}
+	message.RequiredHeader(head)
+	return []byte(*head)
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+	writer, err := NewWriter(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1577836800, 123456000)
+	local := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 56700}
+	remote := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 100), Port: 56700}
+	remote6 := &net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 56700}
+	data := getServicePacket()
+
+	if err := writer.Record(now, local, remote, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Record(now, remote6, local, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewReader(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 2 {
+		t.Fatalf("expect 2 packets but got %v", len(packets))
+	}
+
+	if !packets[0].Time.Equal(now) {
+		t.Errorf("expect %v but got %v", now, packets[0].Time)
+	}
+	if !packets[0].Src.IP.Equal(local.IP) || packets[0].Dst.String() != remote.String() {
+		t.Errorf("expect %v -> %v but got %v -> %v", local, remote, packets[0].Src, packets[0].Dst)
+	}
+	if !packets[1].Src.IP.Equal(remote6.IP) || !packets[1].Dst.IP.Equal(local.IP) {
+		t.Errorf("expect %v -> %v but got %v -> %v", remote6, local, packets[1].Src, packets[1].Dst)
+	}
+	for _, p := range packets {
+		if !reflect.DeepEqual([]byte(p.Header), data) {
+			t.Errorf("expect %x but got %x", data, []byte(p.Header))
+		}
+		if p.Header.Type() != device.GetServiceType {
+			t.Errorf("expect type %v but got %v", device.GetServiceType, p.Header.Type())
+		}
+	}
+}
+
+func TestWriter_Server(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{})
+	writer, err := NewWriter(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, in, err := server.StartUpWithRecorder(ctx, writer)
+	if err != nil {
+		t.Skipf("can not listen on the LIFX port: %v", err)
+	}
+
+	// the server listens on every interface so it reads back what it sends to itself
+	sent, done := context.WithCancel(ctx)
+	out <- &server.OutBoundPayload{Data: getServicePacket(), Address: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 56700}, Done: done}
+	<-sent.Done()
+	select {
+	case <-in:
+	case <-ctx.Done():
+		t.Fatal("expected the datagram back")
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewReader(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 2 {
+		t.Fatalf("expected the outbound and inbound datagrams but got %v", len(packets))
+	}
+	for _, p := range packets {
+		if len(p.Src.IP) != net.IPv4len || len(p.Dst.IP) != net.IPv4len {
+			t.Errorf("expected IPv4 but got %v -> %v", p.Src, p.Dst)
+		}
+	}
+}
+
+func TestReader_Pcapng(t *testing.T) {
+	data := getServicePacket()
+	frame := make([]byte, 14)
+	binary.BigEndian.PutUint16(frame[12:14], 0x0800)
+	frame = append(frame, encodeIPv4UDP(net.IPv4(10, 0, 0, 1).To4(), net.IPv4(10, 0, 0, 255).To4(), 56700, 56700, data)...)
+
+	block := func(blockType uint32, body []byte) []byte {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		b := make([]byte, 8, len(body)+12)
+		binary.LittleEndian.PutUint32(b[0:4], blockType)
+		binary.LittleEndian.PutUint32(b[4:8], uint32(len(body)+12))
+		b = append(b, body...)
+		return append(b, b[4:8]...)
+	}
+
+	section := make([]byte, 16)
+	binary.LittleEndian.PutUint32(section[0:4], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(section[4:6], 1)
+	binary.LittleEndian.PutUint64(section[8:16], 0xffffffffffffffff)
+
+	// ethernet interface with nanosecond timestamps
+	iface := make([]byte, 8)
+	binary.LittleEndian.PutUint16(iface[0:2], LinkTypeEthernet)
+	iface = append(iface, pcapngOptionTSResol, 0, 1, 0, 9, 0, 0, 0, 0, 0, 0, 0)
+
+	packet := make([]byte, 20)
+	ts := uint64(1577836800123456789)
+	binary.LittleEndian.PutUint32(packet[4:8], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(packet[8:12], uint32(ts))
+	binary.LittleEndian.PutUint32(packet[12:16], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(packet[16:20], uint32(len(frame)))
+	packet = append(packet, frame...)
+
+	file := block(pcapngSectionHeader, section)
+	file = append(file, block(pcapngInterfaceDescription, iface)...)
+	file = append(file, block(pcapngEnhancedPacket, packet)...)
+
+	reader, err := NewReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != 1 {
+		t.Fatalf("expect 1 packet but got %v", len(packets))
+	}
+	if packets[0].Time.UnixNano() != int64(ts) {
+		t.Errorf("expect %v but got %v", ts, packets[0].Time.UnixNano())
+	}
+	if packets[0].Dst.String() != "10.0.0.255:56700" {
+		t.Errorf("expect 10.0.0.255:56700 but got %v", packets[0].Dst)
+	}
+	if !reflect.DeepEqual([]byte(packets[0].Header), data) {
+		t.Errorf("expect %x but got %x", data, []byte(packets[0].Header))
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	buffer := bytes.NewBuffer([]byte{})
+	writer, err := NewWriter(buffer)
+	if err != nil {
+		f.Fatal(err)
+	}
+	local := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 56700}
+	remote := &net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 56700}
+	writer.Record(time.Unix(1577836800, 0), local, remote, getServicePacket())
+	writer.Record(time.Unix(1577836801, 0), remote, local, getServicePacket())
+	writer.Flush()
+	f.Add(buffer.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		for i := 0; i < 100; i++ {
+			p, err := reader.Next()
+			if err != nil {
+				return
+			}
+			_ = p.String()
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const (
+	ipv4HeaderLen = 20
+	ipv6HeaderLen = 40
+	udpHeaderLen  = 8
+	udpProtocol   = 17
+)
+
+//encodeIPUDP wraps data in a synthesized IPv4 (or IPv6 if either address requires it) and UDP header
+//so the datagram can be stored with LinkTypeRaw.
+func encodeIPUDP(src, dst *net.UDPAddr, data []byte) []byte {
+	src4, dst4 := src.IP.To4(), dst.IP.To4()
+	// a socket listening on every interface reports :: as its address, which goes with either version
+	srcAny, dstAny := src.IP == nil || src.IP.IsUnspecified(), dst.IP == nil || dst.IP.IsUnspecified()
+	if (src4 != nil || srcAny) && (dst4 != nil || dstAny) {
+		if src4 == nil {
+			src4 = net.IPv4zero.To4()
+		}
+		if dst4 == nil {
+			dst4 = net.IPv4zero.To4()
+		}
+		return encodeIPv4UDP(src4, dst4, src.Port, dst.Port, data)
+	}
+	return encodeIPv6UDP(to16(src.IP), to16(dst.IP), src.Port, dst.Port, data)
+}
+
+func to16(ip net.IP) net.IP {
+	if ip16 := ip.To16(); ip16 != nil {
+		return ip16
+	}
+	return net.IPv6unspecified
+}
+
+func encodeIPv4UDP(src, dst net.IP, srcPort, dstPort int, data []byte) []byte {
+	total := ipv4HeaderLen + udpHeaderLen + len(data)
+	b := make([]byte, total)
+	b[0] = 0x45 // version 4, 5 words
+	binary.BigEndian.PutUint16(b[2:4], uint16(total))
+	b[6] = 0x40 // don't fragment
+	b[8] = 64   // ttl
+	b[9] = udpProtocol
+	copy(b[12:16], src)
+	copy(b[16:20], dst)
+	binary.BigEndian.PutUint16(b[10:12], checksum(b[:ipv4HeaderLen], 0))
+
+	udp := b[ipv4HeaderLen:]
+	binary.BigEndian.PutUint16(udp[0:2], uint16(srcPort))
+	binary.BigEndian.PutUint16(udp[2:4], uint16(dstPort))
+	binary.BigEndian.PutUint16(udp[4:6], uint16(udpHeaderLen+len(data)))
+	copy(udp[udpHeaderLen:], data)
+	// the UDP checksum is optional over IPv4 so it is left as zero
+	return b
+}
+
+func encodeIPv6UDP(src, dst net.IP, srcPort, dstPort int, data []byte) []byte {
+	udpLen := udpHeaderLen + len(data)
+	b := make([]byte, ipv6HeaderLen+udpLen)
+	b[0] = 0x60
+	binary.BigEndian.PutUint16(b[4:6], uint16(udpLen))
+	b[6] = udpProtocol
+	b[7] = 64 // hop limit
+	copy(b[8:24], src)
+	copy(b[24:40], dst)
+
+	udp := b[ipv6HeaderLen:]
+	binary.BigEndian.PutUint16(udp[0:2], uint16(srcPort))
+	binary.BigEndian.PutUint16(udp[2:4], uint16(dstPort))
+	binary.BigEndian.PutUint16(udp[4:6], uint16(udpLen))
+	copy(udp[udpHeaderLen:], data)
+
+	// over IPv6 the UDP checksum is mandatory and covers a pseudo header
+	pseudo := make([]byte, 40)
+	copy(pseudo[0:16], src)
+	copy(pseudo[16:32], dst)
+	binary.BigEndian.PutUint32(pseudo[32:36], uint32(udpLen))
+	pseudo[39] = udpProtocol
+	sum := checksum(udp, sumWords(pseudo, 0))
+	if sum == 0 {
+		sum = 0xffff
+	}
+	binary.BigEndian.PutUint16(udp[6:8], sum)
+	return b
+}
+
+func sumWords(b []byte, sum uint32) uint32 {
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	return sum
+}
+
+func checksum(b []byte, initial uint32) uint16 {
+	sum := sumWords(b, initial)
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+//decodeIP extracts the UDP addresses and payload from an IPv4 or IPv6 packet.
+func decodeIP(b []byte) (src, dst *net.UDPAddr, data []byte, err error) {
+	if len(b) < 1 {
+		return nil, nil, nil, fmt.Errorf("empty IP packet")
+	}
+	switch b[0] >> 4 {
+	case 4:
+		return decodeIPv4(b)
+	case 6:
+		return decodeIPv6(b)
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown IP version %v", b[0]>>4)
+	}
+}
+
+func decodeIPv4(b []byte) (src, dst *net.UDPAddr, data []byte, err error) {
+	if len(b) < ipv4HeaderLen {
+		return nil, nil, nil, fmt.Errorf("expected %v or more bytes found %v", ipv4HeaderLen, len(b))
+	}
+	headerLen := int(b[0]&0x0f) * 4
+	total := int(binary.BigEndian.Uint16(b[2:4]))
+	if headerLen < ipv4HeaderLen || total < headerLen || total > len(b) {
+		return nil, nil, nil, fmt.Errorf("malformed IPv4 header")
+	}
+	if b[9] != udpProtocol {
+		return nil, nil, nil, fmt.Errorf("not a UDP packet")
+	}
+	if binary.BigEndian.Uint16(b[6:8])&0x3fff != 0 {
+		return nil, nil, nil, fmt.Errorf("fragmented packets are not supported")
+	}
+	srcIP := net.IP(append([]byte{}, b[12:16]...))
+	dstIP := net.IP(append([]byte{}, b[16:20]...))
+	return decodeUDP(srcIP, dstIP, b[headerLen:total])
+}
+
+func decodeIPv6(b []byte) (src, dst *net.UDPAddr, data []byte, err error) {
+	if len(b) < ipv6HeaderLen {
+		return nil, nil, nil, fmt.Errorf("expected %v or more bytes found %v", ipv6HeaderLen, len(b))
+	}
+	payloadLen := int(binary.BigEndian.Uint16(b[4:6]))
+	if ipv6HeaderLen+payloadLen > len(b) {
+		return nil, nil, nil, fmt.Errorf("malformed IPv6 header")
+	}
+	if b[6] != udpProtocol {
+		return nil, nil, nil, fmt.Errorf("not a UDP packet")
+	}
+	srcIP := net.IP(append([]byte{}, b[8:24]...))
+	dstIP := net.IP(append([]byte{}, b[24:40]...))
+	return decodeUDP(srcIP, dstIP, b[ipv6HeaderLen:ipv6HeaderLen+payloadLen])
+}
+
+func decodeUDP(srcIP, dstIP net.IP, b []byte) (src, dst *net.UDPAddr, data []byte, err error) {
+	if len(b) < udpHeaderLen {
+		return nil, nil, nil, fmt.Errorf("expected %v or more bytes found %v", udpHeaderLen, len(b))
+	}
+	length := int(binary.BigEndian.Uint16(b[4:6]))
+	if length < udpHeaderLen || length > len(b) {
+		return nil, nil, nil, fmt.Errorf("malformed UDP header")
+	}
+	src = &net.UDPAddr{IP: srcIP, Port: int(binary.BigEndian.Uint16(b[0:2]))}
+	dst = &net.UDPAddr{IP: dstIP, Port: int(binary.BigEndian.Uint16(b[2:4]))}
+	return src, dst, b[udpHeaderLen:length], nil
+}
+
+//decodeLink strips the link-layer framing and returns the IP packet it carries.
+func decodeLink(linkType uint32, b []byte) ([]byte, error) {
+	switch linkType {
+	case LinkTypeRaw, linkTypeRawAlt, LinkTypeIPv4, LinkTypeIPv6:
+		return b, nil
+	case LinkTypeNull:
+		if len(b) < 4 {
+			return nil, fmt.Errorf("truncated loopback header")
+		}
+		return b[4:], nil
+	case LinkTypeEthernet:
+		if len(b) < 14 {
+			return nil, fmt.Errorf("truncated ethernet header")
+		}
+		etherType := binary.BigEndian.Uint16(b[12:14])
+		b = b[14:]
+		for etherType == 0x8100 || etherType == 0x88a8 {
+			if len(b) < 4 {
+				return nil, fmt.Errorf("truncated VLAN header")
+			}
+			etherType = binary.BigEndian.Uint16(b[2:4])
+			b = b[4:]
+		}
+		return ipEtherType(etherType, b)
+	case LinkTypeLinuxSLL:
+		if len(b) < 16 {
+			return nil, fmt.Errorf("truncated SLL header")
+		}
+		return ipEtherType(binary.BigEndian.Uint16(b[14:16]), b[16:])
+	case LinkTypeLinuxSLL2:
+		if len(b) < 20 {
+			return nil, fmt.Errorf("truncated SLL2 header")
+		}
+		return ipEtherType(binary.BigEndian.Uint16(b[0:2]), b[20:])
+	default:
+		return nil, fmt.Errorf("unsupported link type %v", linkType)
+	}
+}
+
+func ipEtherType(etherType uint16, b []byte) ([]byte, error) {
+	if etherType != 0x0800 && etherType != 0x86dd {
+		return nil, fmt.Errorf("not an IP packet")
+	}
+	return b, nil
+}
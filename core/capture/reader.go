@@ -0,0 +1,324 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"github.com/nathanhack/lifx/core/header"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	pcapngSectionHeader        = 0x0a0d0d0a
+	pcapngInterfaceDescription = 0x00000001
+	pcapngSimplePacket         = 0x00000003
+	pcapngEnhancedPacket       = 0x00000006
+	pcapngByteOrderMagic       = 0x1a2b3c4d
+
+	pcapngOptionEnd      = 0
+	pcapngOptionTSResol  = 9
+	pcapngOptionTSOffset = 14
+
+	//maxRecordLen bounds allocations so a corrupt length field can't exhaust memory
+	maxRecordLen = 1 << 18
+)
+
+type rawRecord struct {
+	time     time.Time
+	linkType uint32
+	data     []byte
+}
+
+type pcapngInterface struct {
+	linkType uint32
+	toTime   func(ts uint64) time.Time
+}
+
+//Reader iterates the LIFX packets stored in a pcap or pcapng capture.
+type Reader struct {
+	r    *bufio.Reader
+	next func() (*rawRecord, error)
+
+	// pcap state
+	order    binary.ByteOrder
+	nano     bool
+	linkType uint32
+
+	// pcapng state
+	interfaces []pcapngInterface
+}
+
+//NewReader detects whether r holds a pcap or pcapng capture and reads its file header.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+	magic, err := reader.r.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("reading capture magic: %v", err)
+	}
+
+	switch {
+	case binary.LittleEndian.Uint32(magic) == pcapngSectionHeader:
+		reader.next = reader.nextPcapng
+		return reader, nil
+	case binary.LittleEndian.Uint32(magic) == pcapMagic:
+		reader.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(magic) == pcapMagic:
+		reader.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(magic) == pcapMagicNano:
+		reader.order, reader.nano = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(magic) == pcapMagicNano:
+		reader.order, reader.nano = binary.BigEndian, true
+	default:
+		return nil, fmt.Errorf("unknown capture format (magic %x)", magic)
+	}
+
+	fileHeader := make([]byte, 24)
+	if _, err := io.ReadFull(reader.r, fileHeader); err != nil {
+		return nil, fmt.Errorf("reading pcap header: %v", err)
+	}
+	reader.linkType = reader.order.Uint32(fileHeader[20:24]) & 0x0fffffff
+	reader.next = reader.nextPcap
+	return reader, nil
+}
+
+//Next returns the next LIFX packet in the capture, silently skipping frames that are not
+//LIFX datagrams. At the end of the capture it returns io.EOF.
+func (r *Reader) Next() (*Packet, error) {
+	for {
+		record, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		ip, err := decodeLink(record.linkType, record.data)
+		if err != nil {
+			continue
+		}
+		src, dst, data, err := decodeIP(ip)
+		if err != nil {
+			continue
+		}
+		h, err := header.Decode(data)
+		if err != nil {
+			continue
+		}
+		return &Packet{
+			Time:   record.time,
+			Src:    src,
+			Dst:    dst,
+			Header: *h,
+		}, nil
+	}
+}
+
+//ReadAll returns every remaining LIFX packet in the capture.
+func (r *Reader) ReadAll() ([]*Packet, error) {
+	packets := make([]*Packet, 0)
+	for {
+		p, err := r.Next()
+		if err == io.EOF {
+			return packets, nil
+		}
+		if err != nil {
+			return packets, err
+		}
+		packets = append(packets, p)
+	}
+}
+
+//ReadFile loads every LIFX packet from the pcap or pcapng file at path.
+func ReadFile(path string) ([]*Packet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	return reader.ReadAll()
+}
+
+func (r *Reader) nextPcap() (*rawRecord, error) {
+	recordHeader := make([]byte, 16)
+	if _, err := io.ReadFull(r.r, recordHeader); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("truncated pcap record header")
+		}
+		return nil, err
+	}
+	seconds := r.order.Uint32(recordHeader[0:4])
+	fraction := r.order.Uint32(recordHeader[4:8])
+	length := r.order.Uint32(recordHeader[8:12])
+	if length > maxRecordLen {
+		return nil, fmt.Errorf("pcap record length %v too large", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, fmt.Errorf("truncated pcap record: %v", err)
+	}
+
+	nanos := int64(fraction)
+	if !r.nano {
+		nanos *= 1000
+	}
+	return &rawRecord{
+		time:     time.Unix(int64(seconds), nanos),
+		linkType: r.linkType,
+		data:     data,
+	}, nil
+}
+
+func (r *Reader) nextPcapng() (*rawRecord, error) {
+	for {
+		blockType, body, err := r.readBlock()
+		if err != nil {
+			return nil, err
+		}
+
+		switch blockType {
+		case pcapngSectionHeader:
+			// each section starts over with its own interfaces
+			r.interfaces = nil
+		case pcapngInterfaceDescription:
+			if len(body) < 8 {
+				return nil, fmt.Errorf("truncated interface description block")
+			}
+			r.interfaces = append(r.interfaces, r.parseInterface(body))
+		case pcapngEnhancedPacket:
+			if len(body) < 20 {
+				return nil, fmt.Errorf("truncated enhanced packet block")
+			}
+			id := r.order.Uint32(body[0:4])
+			if int64(id) >= int64(len(r.interfaces)) {
+				return nil, fmt.Errorf("packet references unknown interface %v", id)
+			}
+			timestamp := uint64(r.order.Uint32(body[4:8]))<<32 | uint64(r.order.Uint32(body[8:12]))
+			length := r.order.Uint32(body[12:16])
+			if uint64(length) > uint64(len(body)-20) {
+				return nil, fmt.Errorf("enhanced packet length %v exceeds block", length)
+			}
+			return &rawRecord{
+				time:     r.interfaces[id].toTime(timestamp),
+				linkType: r.interfaces[id].linkType,
+				data:     body[20 : 20+length],
+			}, nil
+		case pcapngSimplePacket:
+			if len(body) < 4 {
+				return nil, fmt.Errorf("truncated simple packet block")
+			}
+			if len(r.interfaces) == 0 {
+				return nil, fmt.Errorf("simple packet block without an interface")
+			}
+			length := r.order.Uint32(body[0:4])
+			if uint64(length) > uint64(len(body)-4) {
+				length = uint32(len(body) - 4)
+			}
+			return &rawRecord{
+				linkType: r.interfaces[0].linkType,
+				data:     body[4 : 4+length],
+			}, nil
+		}
+	}
+}
+
+//readBlock reads one pcapng block returning its type and body (without the type and length fields).
+func (r *Reader) readBlock() (blockType uint32, body []byte, err error) {
+	prefix := make([]byte, 8)
+	if _, err := io.ReadFull(r.r, prefix); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, fmt.Errorf("truncated pcapng block")
+		}
+		return 0, nil, err
+	}
+
+	if binary.LittleEndian.Uint32(prefix[0:4]) == pcapngSectionHeader {
+		magic, err := r.r.Peek(4)
+		if err != nil {
+			return 0, nil, fmt.Errorf("truncated section header block")
+		}
+		switch {
+		case binary.LittleEndian.Uint32(magic) == pcapngByteOrderMagic:
+			r.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic) == pcapngByteOrderMagic:
+			r.order = binary.BigEndian
+		default:
+			return 0, nil, fmt.Errorf("bad section header byte order magic %x", magic)
+		}
+	} else if r.order == nil {
+		return 0, nil, fmt.Errorf("pcapng block before section header")
+	}
+
+	blockType = r.order.Uint32(prefix[0:4])
+	length := r.order.Uint32(prefix[4:8])
+	if length < 12 || length%4 != 0 || length > maxRecordLen {
+		return 0, nil, fmt.Errorf("invalid pcapng block length %v", length)
+	}
+
+	rest := make([]byte, length-8)
+	if _, err := io.ReadFull(r.r, rest); err != nil {
+		return 0, nil, fmt.Errorf("truncated pcapng block: %v", err)
+	}
+	return blockType, rest[:len(rest)-4], nil
+}
+
+func (r *Reader) parseInterface(body []byte) pcapngInterface {
+	linkType := uint32(r.order.Uint16(body[0:2]))
+	resolution := byte(6)
+	var offset int64
+
+	options := body[8:]
+	for len(options) >= 4 {
+		code := r.order.Uint16(options[0:2])
+		length := int(r.order.Uint16(options[2:4]))
+		options = options[4:]
+		if code == pcapngOptionEnd || length > len(options) {
+			break
+		}
+		value := options[:length]
+		switch {
+		case code == pcapngOptionTSResol && length == 1:
+			resolution = value[0]
+		case code == pcapngOptionTSOffset && length == 8:
+			offset = int64(r.order.Uint64(value))
+		}
+		padded := (length + 3) &^ 3
+		if padded > len(options) {
+			break
+		}
+		options = options[padded:]
+	}
+
+	return pcapngInterface{
+		linkType: linkType,
+		toTime:   timestampConverter(resolution, offset),
+	}
+}
+
+//timestampConverter turns pcapng timestamps in the interface's resolution into time.Time.
+func timestampConverter(resolution byte, offset int64) func(uint64) time.Time {
+	var unitsPerSecond float64
+	if resolution&0x80 == 0 {
+		unitsPerSecond = math.Pow(10, float64(resolution))
+	} else {
+		unitsPerSecond = math.Pow(2, float64(resolution&0x7f))
+	}
+	if unitsPerSecond < 1 || unitsPerSecond > 1e18 {
+		// nonsensical resolutions still shouldn't break iteration
+		return func(ts uint64) time.Time {
+			return time.Unix(offset, 0).Add(time.Duration(float64(ts) / unitsPerSecond * 1e9))
+		}
+	}
+
+	units := uint64(unitsPerSecond)
+	return func(ts uint64) time.Time {
+		seconds := ts / units
+		nanos := int64(float64(ts%units) / unitsPerSecond * 1e9)
+		return time.Unix(int64(seconds)+offset, nanos)
+	}
+}
@@ -0,0 +1,66 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	pcapMagic        = 0xa1b2c3d4
+	pcapMagicNano    = 0xa1b23c4d
+	pcapVersionMajor = 2
+	pcapVersionMinor = 4
+	pcapSnapLen      = 65535
+)
+
+//Writer writes datagrams to a pcap file. It is safe for concurrent use so it can be handed to
+//server.StartUpWithRecorder directly.
+type Writer struct {
+	mux sync.Mutex
+	w   *bufio.Writer
+}
+
+//NewWriter writes the pcap file header to w and returns a Writer ready for records.
+func NewWriter(w io.Writer) (*Writer, error) {
+	buffered := bufio.NewWriter(w)
+	fileHeader := make([]byte, 24)
+	binary.LittleEndian.PutUint32(fileHeader[0:4], pcapMagic)
+	binary.LittleEndian.PutUint16(fileHeader[4:6], pcapVersionMajor)
+	binary.LittleEndian.PutUint16(fileHeader[6:8], pcapVersionMinor)
+	binary.LittleEndian.PutUint32(fileHeader[16:20], pcapSnapLen)
+	binary.LittleEndian.PutUint32(fileHeader[20:24], LinkTypeRaw)
+	if _, err := buffered.Write(fileHeader); err != nil {
+		return nil, err
+	}
+	return &Writer{w: buffered}, nil
+}
+
+//Record appends a single datagram travelling from src to dst.
+func (w *Writer) Record(timestamp time.Time, src, dst *net.UDPAddr, data []byte) error {
+	packet := encodeIPUDP(src, dst, data)
+
+	recordHeader := make([]byte, 16)
+	binary.LittleEndian.PutUint32(recordHeader[0:4], uint32(timestamp.Unix()))
+	binary.LittleEndian.PutUint32(recordHeader[4:8], uint32(timestamp.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(recordHeader[8:12], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(recordHeader[12:16], uint32(len(packet)))
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if _, err := w.w.Write(recordHeader); err != nil {
+		return err
+	}
+	_, err := w.w.Write(packet)
+	return err
+}
+
+//Flush writes any buffered records to the underlying writer.
+func (w *Writer) Flush() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return w.w.Flush()
+}
@@ -0,0 +1,146 @@
+package circadian
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval   = time.Minute
+	DefaultTransition = 5 * time.Second
+)
+
+//Adaptive keeps lights on the curve's white. A light is only changed while it is on and is left alone
+//once someone changes its color by hand, until it is turned off or ResumeAfter has passed.
+type Adaptive struct {
+	Curve *Curve
+	//Interval is how often the lights are checked and moved along the curve, 0 is DefaultInterval
+	Interval time.Duration
+	//Transition is how long each change fades for, 0 is DefaultTransition. Keep it well under Interval so
+	//a light is done fading when it is next checked.
+	Transition time.Duration
+	//ResumeAfter takes back a light changed by hand after this long, 0 waits for it to be turned off
+	ResumeAfter time.Duration
+	//Log gets every pause and resume, nil uses logrus' standard logger
+	Log logrus.FieldLogger
+
+	mutex   sync.Mutex
+	tracked map[string]*tracked
+}
+
+//tracked is what Adaptive remembers about a light that is on.
+type tracked struct {
+	//sent is the last color set, nil until the first
+	sent     *hsbk.HSBK
+	paused   bool
+	pausedAt time.Time
+}
+
+//Run moves the lights along the curve every Interval until ctx is done.
+func (a *Adaptive) Run(ctx context.Context, lights []*client.Light) error {
+	interval := a.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stepCtx, cancel := context.WithTimeout(ctx, interval)
+		a.Step(stepCtx, lights, time.Now())
+		cancel()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+//Step checks every light once and sets the ones that are on and not paused to the curve's white at now.
+func (a *Adaptive) Step(ctx context.Context, lights []*client.Light, now time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.tracked == nil {
+		a.tracked = make(map[string]*tracked)
+	}
+
+	kelvin, brightness := a.Curve.At(now)
+	var wg sync.WaitGroup
+	for _, l := range lights {
+		t, has := a.tracked[l.TargetHex()]
+		if !has {
+			t = &tracked{}
+			a.tracked[l.TargetHex()] = t
+		}
+		wg.Add(1)
+		go func(l *client.Light, t *tracked) {
+			defer wg.Done()
+			a.step(ctx, l, t, kelvin, brightness, now)
+		}(l, t)
+	}
+	wg.Wait()
+}
+
+func (a *Adaptive) step(ctx context.Context, l *client.Light, t *tracked, kelvin, brightness uint16, now time.Time) {
+	log := a.log().WithField("light", l.TargetHex())
+	state, err := l.State(ctx)
+	if err != nil {
+		log.WithError(err).Warn("could not get state")
+		return
+	}
+	if state.Power == 0 {
+		if t.paused {
+			log.Info("turned off, resuming")
+		}
+		*t = tracked{}
+		return
+	}
+
+	if t.paused {
+		if a.ResumeAfter <= 0 || now.Sub(t.pausedAt) < a.ResumeAfter {
+			return
+		}
+		log.Info("resuming")
+		t.paused = false
+	} else if t.sent != nil && !near(state.Color, *t.sent) {
+		log.WithField("color", state.Color).Info("changed by hand, pausing")
+		t.paused, t.pausedAt = true, now
+		return
+	}
+
+	color := hsbk.HSBK{Hue: state.Color.Hue, Saturation: 0, Brightness: brightness, Kelvin: kelvin}
+	if t.sent != nil && *t.sent == color {
+		return
+	}
+	transition := a.Transition
+	if transition <= 0 {
+		transition = DefaultTransition
+	}
+	if err := l.SetColor(ctx, color, transition); err != nil {
+		log.WithError(err).Warn("could not set color")
+		return
+	}
+	t.sent = &color
+}
+
+func (a *Adaptive) log() logrus.FieldLogger {
+	if a.Log == nil {
+		return logrus.StandardLogger()
+	}
+	return a.Log
+}
+
+//near allows for the little rounding a light may do to the color it was sent, anything more was a person.
+func near(a, b hsbk.HSBK) bool {
+	within := func(x, y uint16, tolerance int) bool {
+		d := int(x) - int(y)
+		return d >= -tolerance && d <= tolerance
+	}
+	return within(a.Saturation, b.Saturation, 0x0400) &&
+		within(a.Brightness, b.Brightness, 0x0400) &&
+		within(a.Kelvin, b.Kelvin, 50)
+}
@@ -0,0 +1,127 @@
+package circadian
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCurve_At(t *testing.T) {
+	c := &Curve{Points: []*Point{
+		{At: "06:00", Kelvin: 2000, Brightness: 0},
+		{At: "12:00", Kelvin: 6000, Brightness: 100},
+		{At: "18:00", Kelvin: 2000, Brightness: 0},
+	}}
+	if err := c.Check(); err != nil {
+		t.Fatal(err)
+	}
+	day := func(hour, minute int) time.Time {
+		return time.Date(2024, 6, 21, hour, minute, 0, 0, time.UTC)
+	}
+
+	if kelvin, brightness := c.At(day(12, 0)); kelvin != 6000 || brightness != 0xffff {
+		t.Errorf("expected the noon point exactly but got %v %v", kelvin, brightness)
+	}
+	if kelvin, brightness := c.At(day(9, 0)); kelvin != hsbk.MixKelvin(2000, 6000, 0.5) || brightness != hsbk.FromFraction(0.5) {
+		t.Errorf("expected half way between morning and noon but got %v %v", kelvin, brightness)
+	}
+	// the night holds 18:00 until 06:00 the next day
+	if kelvin, brightness := c.At(day(0, 0)); kelvin != 2000 || brightness != 0 {
+		t.Errorf("expected the night across midnight but got %v %v", kelvin, brightness)
+	}
+
+	latitude, longitude := 51.48, 0.0
+	sun := &Curve{Latitude: &latitude, Longitude: &longitude, Points: []*Point{
+		{At: "sunrise", Kelvin: 2700, Brightness: 50},
+		{At: "sunset+1h", Kelvin: 2200, Brightness: 10},
+		{At: "13:00", Kelvin: 6500, Brightness: 100},
+	}}
+	if err := sun.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if kelvin, _ := sun.At(day(13, 0)); kelvin != 6500 {
+		t.Errorf("expected the 13:00 point between the sun points but got %v", kelvin)
+	}
+
+	for _, bad := range []*Curve{
+		{},
+		{Points: []*Point{{At: "sunset", Kelvin: 2700}}},
+		{Points: []*Point{{At: "25:00", Kelvin: 2700}}},
+		{Points: []*Point{{At: "12:00", Kelvin: 100}}},
+		{Points: []*Point{{At: "12:00", Kelvin: 2700, Brightness: 120}}},
+	} {
+		if err := bad.Check(); err == nil {
+			t.Errorf("expected an error for %v", bad.Points)
+		}
+	}
+}
+
+func TestReadCurve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "curve.yaml")
+	if err := ioutil.WriteFile(path, []byte("points:\n  - at: \"07:30\"\n    kelvin: 3000\n    brightness: 40\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadCurve(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kelvin, brightness := c.At(time.Now()); kelvin != 3000 || brightness != hsbk.FromFraction(0.4) {
+		t.Errorf("expected a single point to hold all day but got %v %v", kelvin, brightness)
+	}
+}
+
+func TestAdaptive_Step(t *testing.T) {
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Power: 0xffff, Color: hsbk.HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, bulb)
+	l := client.New(ctx, out, in).Light(bulb.Target, bulb.Address)
+	lights := []*client.Light{l}
+
+	a := &Adaptive{Curve: DefaultCurve(), Transition: time.Millisecond, ResumeAfter: time.Hour}
+	noon := time.Date(2024, 6, 21, 12, 0, 0, 0, time.Local)
+	a.Step(ctx, lights, noon)
+	state, _ := l.State(ctx)
+	if state.Color.Kelvin != 6000 || state.Color.Saturation != 0 || state.Color.Brightness != 0xffff {
+		t.Fatalf("expected a bright cool white at noon but got %v", state.Color)
+	}
+
+	// someone picks a color
+	red := hsbk.HSBK{Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500}
+	if err := l.SetColor(ctx, red, 0); err != nil {
+		t.Fatal(err)
+	}
+	a.Step(ctx, lights, noon.Add(time.Minute))
+	if state, _ = l.State(ctx); state.Color != red {
+		t.Errorf("expected a light changed by hand left alone but got %v", state.Color)
+	}
+	a.Step(ctx, lights, noon.Add(2*time.Hour))
+	if state, _ = l.State(ctx); state.Color == red {
+		t.Errorf("expected the light taken back after ResumeAfter")
+	}
+
+	// off lights are left off, and forget the pause
+	if err := l.SetColor(ctx, red, 0); err != nil {
+		t.Fatal(err)
+	}
+	a.Step(ctx, lights, noon.Add(3*time.Hour))
+	if err := l.SetPower(ctx, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	a.Step(ctx, lights, noon.Add(4*time.Hour))
+	if state, _ = l.State(ctx); state.Power != 0 || state.Color != red {
+		t.Errorf("expected an off light untouched but got %v", state)
+	}
+	if err := l.SetPower(ctx, true, 0); err != nil {
+		t.Fatal(err)
+	}
+	a.Step(ctx, lights, noon.Add(4*time.Hour))
+	if state, _ = l.State(ctx); state.Color.Saturation != 0 {
+		t.Errorf("expected the light back on the curve after turning it on but got %v", state.Color)
+	}
+}
@@ -0,0 +1,167 @@
+package circadian
+
+import (
+	"fmt"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/schedule"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Point is the white a light should have at one time of day.
+type Point struct {
+	//At is a clock time, 06:30, or a sun event with an optional offset, sunset+30m, see schedule.ParseSolar
+	At     string `yaml:"at"`
+	Kelvin uint16 `yaml:"kelvin"`
+	//Brightness is in percent
+	Brightness float64 `yaml:"brightness"`
+
+	when func(day time.Time) (time.Time, bool)
+}
+
+//Curve is the day's whites, the lights follow a straight line from one point to the next wrapping around
+//midnight. Kelvin is mixed with hsbk.MixKelvin so the change looks even.
+type Curve struct {
+	//Latitude and longitude are only needed by points at sun events
+	Latitude  *float64 `yaml:"latitude,omitempty"`
+	Longitude *float64 `yaml:"longitude,omitempty"`
+	Points    []*Point `yaml:"points"`
+}
+
+//DefaultCurve is warm and dim at night, cool and bright around noon.
+func DefaultCurve() *Curve {
+	c := &Curve{Points: []*Point{
+		{At: "00:00", Kelvin: 2200, Brightness: 10},
+		{At: "06:00", Kelvin: 2500, Brightness: 30},
+		{At: "09:00", Kelvin: 4500, Brightness: 90},
+		{At: "12:00", Kelvin: 6000, Brightness: 100},
+		{At: "16:00", Kelvin: 4500, Brightness: 90},
+		{At: "19:00", Kelvin: 2700, Brightness: 60},
+		{At: "22:00", Kelvin: 2200, Brightness: 20},
+	}}
+	if err := c.Check(); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+//ReadCurve loads a curve file:
+//
+//	latitude: 51.48
+//	longitude: -0.01
+//	points:
+//	  - at: "00:00"
+//	    kelvin: 2200
+//	    brightness: 10
+//	  - at: sunrise
+//	    kelvin: 2700
+//	    brightness: 40
+//	  - at: "12:00"
+//	    kelvin: 6000
+//	    brightness: 100
+//	  - at: sunset+1h
+//	    kelvin: 2700
+//	    brightness: 50
+func ReadCurve(path string) (*Curve, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Curve
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	if err := c.Check(); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return &c, nil
+}
+
+//Check parses the times of the points and checks their values.
+func (c *Curve) Check() error {
+	if len(c.Points) == 0 {
+		return fmt.Errorf("the curve has no points")
+	}
+	for _, p := range c.Points {
+		if p.Kelvin < 1500 || p.Kelvin > 9000 {
+			return fmt.Errorf("%v: kelvin %v is outside 1500-9000", p.At, p.Kelvin)
+		}
+		if p.Brightness < 0 || p.Brightness > 100 {
+			return fmt.Errorf("%v: brightness %v is outside 0-100", p.At, p.Brightness)
+		}
+		if clock, err := time.Parse("15:04", strings.TrimSpace(p.At)); err == nil {
+			hour, minute := clock.Hour(), clock.Minute()
+			p.when = func(day time.Time) (time.Time, bool) {
+				return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location()), true
+			}
+			continue
+		}
+		if c.Latitude == nil || c.Longitude == nil {
+			return fmt.Errorf("%q is not a clock time and sun events need the latitude and longitude", p.At)
+		}
+		solar, err := schedule.ParseSolar(p.At, *c.Latitude, *c.Longitude)
+		if err != nil {
+			return err
+		}
+		p.when = func(day time.Time) (time.Time, bool) {
+			at, ok := schedule.SunTime(day, solar.Event, solar.Latitude, solar.Longitude)
+			return at.Add(solar.Offset), ok
+		}
+	}
+	return nil
+}
+
+type timedPoint struct {
+	at time.Time
+	*Point
+}
+
+//points returns the points on the day of t in time order, leaving out sun events that don't happen that day.
+func (c *Curve) points(t time.Time) []timedPoint {
+	points := make([]timedPoint, 0, len(c.Points))
+	for _, p := range c.Points {
+		if at, ok := p.when(t); ok {
+			points = append(points, timedPoint{at, p})
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].at.Before(points[j].at)
+	})
+	return points
+}
+
+//At returns the kelvin and brightness of the curve at t.
+func (c *Curve) At(t time.Time) (kelvin, brightness uint16) {
+	today := c.points(t)
+	if len(today) == 0 {
+		// every point is a sun event that doesn't happen today, hold the first one
+		p := c.Points[0]
+		return p.Kelvin, hsbk.FromFraction(p.Brightness / 100)
+	}
+
+	// the points either side of t, borrowing from yesterday and tomorrow around midnight
+	before, after := today[len(today)-1], today[0]
+	i := sort.Search(len(today), func(i int) bool {
+		return today[i].at.After(t)
+	})
+	if i > 0 {
+		before = today[i-1]
+	} else if yesterday := c.points(t.AddDate(0, 0, -1)); len(yesterday) > 0 {
+		before = yesterday[len(yesterday)-1]
+	}
+	if i < len(today) {
+		after = today[i]
+	} else if tomorrow := c.points(t.AddDate(0, 0, 1)); len(tomorrow) > 0 {
+		after = tomorrow[0]
+	}
+
+	fraction := 0.0
+	if span := after.at.Sub(before.at); span > 0 && !t.Before(before.at) {
+		fraction = float64(t.Sub(before.at)) / float64(span)
+	}
+	percent := before.Brightness*(1-fraction) + after.Brightness*fraction
+	return hsbk.MixKelvin(before.Kelvin, after.Kelvin, fraction), hsbk.FromFraction(percent / 100)
+}
@@ -0,0 +1,380 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/server"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	//retryInterval is how long to wait for an answer before sending a request again
+	retryInterval = 500 * time.Millisecond
+	//discoveryInterval is how often discovery repeats its broadcast
+	discoveryInterval = time.Second
+	//DefaultMinInterval keeps to the 20 messages a second LIFX recommends sending a device
+	DefaultMinInterval = time.Second / 20
+)
+
+var defaultBroadcastAddress = &net.UDPAddr{IP: net.IPv4bcast, Port: 56700}
+
+//Client sends requests over the channels returned by server.StartUp (or a stand-in such as
+//emulator.StartUp) and matches the responses to them. All the Device and Light methods go through it.
+type Client struct {
+	// the counters come first to stay 64-bit aligned for sync/atomic on 32-bit platforms
+	sent, received, dropped uint64
+
+	//BroadcastAddress is where discovery is sent, 255.255.255.255:56700 unless changed
+	BroadcastAddress *net.UDPAddr
+	//MinInterval is the least time between two messages to the same device, sends wait for their turn
+	MinInterval time.Duration
+
+	outBound chan *server.OutBoundPayload
+	inbound  chan *server.InboundPayload
+	source   uint32
+
+	mux         sync.Mutex
+	sequence    byte
+	pending     map[byte]chan *server.InboundPayload
+	slots       map[string]time.Time
+	subscribers map[int]func(header.Header, *net.UDPAddr)
+	subscriber  int
+}
+
+//New creates a client on top of already started channels. It reads inbound until ctx is done so
+//nothing else should read from it.
+func New(ctx context.Context, outBound chan *server.OutBoundPayload, inbound chan *server.InboundPayload) *Client {
+	c := &Client{
+		BroadcastAddress: defaultBroadcastAddress,
+		MinInterval:      DefaultMinInterval,
+		outBound:         outBound,
+		inbound:          inbound,
+		source:           rand.Uint32() | 1, // zero means broadcast replies to every client
+		pending:          make(map[byte]chan *server.InboundPayload),
+		slots:            make(map[string]time.Time),
+		subscribers:      make(map[int]func(header.Header, *net.UDPAddr)),
+	}
+	go c.dispatch(ctx)
+	return c
+}
+
+//StartUp starts the UDP server and a client on top of it.
+func StartUp(ctx context.Context) (*Client, error) {
+	out, in, err := server.StartUp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return New(ctx, out, in), nil
+}
+
+func (c *Client) dispatch(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-c.inbound:
+			atomic.AddUint64(&c.received, 1)
+			h, err := header.Decode(payload.Data)
+			if err != nil || !h.Validate(true) {
+				atomic.AddUint64(&c.dropped, 1)
+				continue
+			}
+			c.mux.Lock()
+			responses, has := c.pending[h.Sequence()]
+			if h.Source() != c.source {
+				has = false
+			}
+			subscribers := make([]func(header.Header, *net.UDPAddr), 0, len(c.subscribers))
+			for _, s := range c.subscribers {
+				subscribers = append(subscribers, s)
+			}
+			c.mux.Unlock()
+			if !has {
+				if len(subscribers) == 0 {
+					atomic.AddUint64(&c.dropped, 1)
+				}
+				for _, s := range subscribers {
+					s(*h, payload.Conn)
+				}
+				continue
+			}
+			select {
+			case responses <- payload:
+			default:
+				// the requester stopped listening or is too slow, drop it like the network would
+				atomic.AddUint64(&c.dropped, 1)
+			}
+		}
+	}
+}
+
+//Stats counts the client's packets since it was created.
+type Stats struct {
+	//Sent is the datagrams handed to the server, retries included
+	Sent uint64
+	//Received is the datagrams read from inbound
+	Received uint64
+	//Dropped is the received datagrams that were malformed or went to no request and no subscriber
+	Dropped uint64
+}
+
+//Stats returns the packet counts so far.
+func (c *Client) Stats() Stats {
+	return Stats{
+		Sent:     atomic.LoadUint64(&c.sent),
+		Received: atomic.LoadUint64(&c.received),
+		Dropped:  atomic.LoadUint64(&c.dropped),
+	}
+}
+
+//Subscribe calls handler with every packet that doesn't answer one of this client's requests, like the
+//state replies devices broadcast or send to other apps and answers that came after their request gave up.
+//The handler runs on the goroutine reading inbound so it must return quickly. Call unsubscribe to stop it.
+func (c *Client) Subscribe(handler func(h header.Header, from *net.UDPAddr)) (unsubscribe func()) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.subscriber++
+	id := c.subscriber
+	c.subscribers[id] = handler
+	return func() {
+		c.mux.Lock()
+		defer c.mux.Unlock()
+		delete(c.subscribers, id)
+	}
+}
+
+//register reserves the next sequence number, responses carrying it are delivered on the returned channel
+//until release is called.
+func (c *Client) register() (sequence byte, responses chan *server.InboundPayload, release func()) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	for i := 0; i < 256; i++ {
+		c.sequence++
+		if _, used := c.pending[c.sequence]; !used {
+			break
+		}
+	}
+	sequence = c.sequence
+	responses = make(chan *server.InboundPayload, 32)
+	c.pending[sequence] = responses
+	return sequence, responses, func() {
+		c.mux.Lock()
+		defer c.mux.Unlock()
+		delete(c.pending, sequence)
+	}
+}
+
+//wait blocks until address may be sent another message, broadcasts are not limited.
+func (c *Client) wait(ctx context.Context, address *net.UDPAddr) error {
+	if address == nil || address.IP.Equal(c.BroadcastAddress.IP) || c.MinInterval <= 0 {
+		return nil
+	}
+	c.mux.Lock()
+	key := address.String()
+	now := time.Now()
+	slot := c.slots[key]
+	if slot.Before(now) {
+		slot = now
+	}
+	c.slots[key] = slot.Add(c.MinInterval)
+	c.mux.Unlock()
+
+	if delay := slot.Sub(now); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+//send writes one datagram and waits for it to leave.
+func (c *Client) send(ctx context.Context, data []byte, address *net.UDPAddr) error {
+	if err := c.wait(ctx, address); err != nil {
+		return err
+	}
+	sent, done := context.WithCancel(context.Background())
+	select {
+	case <-ctx.Done():
+		done()
+		return ctx.Err()
+	case c.outBound <- &server.OutBoundPayload{Data: data, Address: address, Done: done}:
+	}
+	atomic.AddUint64(&c.sent, 1)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-sent.Done():
+		return nil
+	}
+}
+
+//request sends the message to target at address until a response of responseType arrives or ctx is done.
+//requiredHeader is the message's RequiredHeader method, it fills in the type and size.
+func (c *Client) request(ctx context.Context, target []byte, address *net.UDPAddr, requiredHeader func(*header.Header), message interface{}, responseType uint16) (*header.Header, error) {
+	sequence, responses, release := c.register()
+	defer release()
+
+	head := header.New(sequence)
+	head.SetSource(c.source)
+	head.SetTarget(target)
+	requiredHeader(head)
+	if responseType == device.AcknowledgementType {
+		head.SetAcknowledgementRequired(true)
+	}
+	data, err := encode(head, message)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if err := c.send(ctx, data, address); err != nil {
+			return nil, err
+		}
+
+		retry := time.After(retryInterval)
+	waiting:
+		for {
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("no response from %x: %v", target, ctx.Err())
+			case <-retry:
+				break waiting
+			case payload := <-responses:
+				h, err := header.Decode(payload.Data)
+				if err != nil {
+					continue
+				}
+				if h.Type() == responseType {
+					return h, nil
+				}
+			}
+		}
+	}
+}
+
+//post sends the message once without asking for an acknowledgement. It is meant for streams of updates
+//where a lost packet is soon replaced by the next one.
+func (c *Client) post(ctx context.Context, target []byte, address *net.UDPAddr, requiredHeader func(*header.Header), message interface{}) error {
+	sequence, _, release := c.register()
+	release()
+
+	head := header.New(sequence)
+	head.SetSource(c.source)
+	head.SetTarget(target)
+	requiredHeader(head)
+	data, err := encode(head, message)
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, data, address)
+}
+
+//Discover broadcasts for devices until ctx is done and returns every light that answered.
+func (c *Client) Discover(ctx context.Context) ([]*Light, error) {
+	return c.discover(ctx, nil)
+}
+
+//Find broadcasts until every light in targets answered or ctx is done. Lights that did not answer are
+//missing from the result.
+func (c *Client) Find(ctx context.Context, targets ...[]byte) ([]*Light, error) {
+	if len(targets) == 0 {
+		return []*Light{}, nil
+	}
+	return c.discover(ctx, targets)
+}
+
+func (c *Client) discover(ctx context.Context, targets [][]byte) ([]*Light, error) {
+	sequence, responses, release := c.register()
+	defer release()
+
+	head := header.New(sequence)
+	head.SetSource(c.source)
+	device.GetService{}.RequiredHeader(head)
+	data, err := encode(head, &device.GetService{})
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, t := range targets {
+		wanted[fmt.Sprintf("%x", t)] = true
+	}
+
+	found := make(map[string]*Light)
+	lights := make([]*Light, 0)
+	for {
+		if err := c.send(ctx, data, c.BroadcastAddress); err != nil {
+			return lights, nil
+		}
+
+		again := time.After(discoveryInterval)
+	waiting:
+		for {
+			select {
+			case <-ctx.Done():
+				return lights, nil
+			case <-again:
+				break waiting
+			case payload := <-responses:
+				h, err := header.Decode(payload.Data)
+				if err != nil || h.Type() != device.StateServiceType {
+					continue
+				}
+				var s device.StateService
+				if err := device.DecodeFromHeader(*h, &s); err != nil || s.Service != 1 {
+					continue
+				}
+				key := h.TargetHex()
+				if _, has := found[key]; has || (len(wanted) > 0 && !wanted[key]) {
+					continue
+				}
+				address := &net.UDPAddr{IP: payload.Conn.IP, Port: int(s.Port), Zone: payload.Conn.Zone}
+				found[key] = c.Light(h.Target(), address)
+				lights = append(lights, found[key])
+				if len(wanted) > 0 && len(found) == len(wanted) {
+					return lights, nil
+				}
+			}
+		}
+	}
+}
+
+//Device returns a handle for a device whose target and address are already known.
+func (c *Client) Device(target []byte, address *net.UDPAddr) *Device {
+	return &Device{
+		Target:  append([]byte{}, target...),
+		Address: address,
+		client:  c,
+	}
+}
+
+//Light returns a handle for a light whose target and address are already known.
+func (c *Client) Light(target []byte, address *net.UDPAddr) *Light {
+	return &Light{Device: c.Device(target, address)}
+}
+
+func encode(head *header.Header, message interface{}) ([]byte, error) {
+	buffer := bytes.NewBuffer([]byte{})
+	err := binary.Write(buffer, binary.LittleEndian, head)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(buffer, binary.LittleEndian, message)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
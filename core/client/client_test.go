@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/server"
+	"net"
+	"testing"
+	"time"
+)
+
+func startEmulated(t *testing.T, devices ...*emulator.Device) (*Client, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	out, in := emulator.StartUp(ctx, devices...)
+	return New(ctx, out, in), ctx
+}
+
+func TestClient_Discover(t *testing.T) {
+	kitchen := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0x00, 0x00, 0x01, 0x00}, Label: "Kitchen"}
+	porch := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0x00, 0x00, 0x02, 0x00}, Label: "Porch"}
+	c, ctx := startEmulated(t, kitchen, porch)
+
+	found, err := c.Find(ctx, porch.Target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].TargetHex() != "d073d500000200" {
+		t.Fatalf("expected only the porch light but found %v", found)
+	}
+	if !found[0].Address.IP.Equal(porch.Address.IP) || found[0].Address.Port != 56700 {
+		t.Errorf("expected the porch light at %v but got %v", porch.Address, found[0].Address)
+	}
+
+	discoverCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	all, err := c.Discover(discoverCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Errorf("expected 2 lights but found %v", all)
+	}
+}
+
+func TestLight_Methods(t *testing.T) {
+	bulb := &emulator.Device{
+		Target:  []byte{0xd0, 0x73, 0xd5, 0x00, 0x00, 0x01, 0x00},
+		Label:   "Kitchen",
+		Vendor:  1,
+		Product: 27,
+	}
+	c, ctx := startEmulated(t, bulb)
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	l := c.Light(bulb.Target, bulb.Address)
+
+	label, err := l.Label(ctx)
+	if err != nil || label != "Kitchen" {
+		t.Errorf("expected Kitchen but got %q (%v)", label, err)
+	}
+	version, err := l.Version(ctx)
+	if err != nil || version.Product != 27 {
+		t.Errorf("expected product 27 but got %v (%v)", version, err)
+	}
+
+	red := hsbk.HSBK{Hue: 0, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}
+	if err := l.SetColor(ctx, red, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetPower(ctx, true, 0); err != nil {
+		t.Fatal(err)
+	}
+	state, err := l.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Color != red || state.Power != 0xffff {
+		t.Errorf("expected red and on but got %v", state)
+	}
+
+	if err := l.Device.SetPower(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	on, err := l.Power(ctx)
+	if err != nil || on {
+		t.Errorf("expected the light to be off but got %v (%v)", on, err)
+	}
+}
+
+func TestDevice_NoResponse(t *testing.T) {
+	c, ctx := startEmulated(t)
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	missing := c.Device([]byte{0xd0, 0x73, 0xd5, 0xff, 0xff, 0xff, 0x00}, nil)
+	if _, err := missing.Label(ctx); err == nil {
+		t.Errorf("expected an error from a device that is not there")
+	}
+}
+
+func TestClient_MinInterval(t *testing.T) {
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0x00, 0x00, 0x01, 0x00}}
+	c, ctx := startEmulated(t, bulb)
+	c.MinInterval = 20 * time.Millisecond
+	l := c.Light(bulb.Target, bulb.Address)
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := l.SendColor(ctx, hsbk.HSBK{Hue: uint16(i)}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected 6 messages to one light to take at least 100ms but took %v", elapsed)
+	}
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0x00, 0x00, 0x01, 0x00}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, bulb)
+	c := New(ctx, out, in)
+	// another app on the same network whose answers c reads
+	other := New(ctx, out, make(chan *server.InboundPayload))
+
+	seen := make(chan header.Header, 10)
+	unsubscribe := c.Subscribe(func(h header.Header, from *net.UDPAddr) { seen <- h })
+	if _, err := c.Light(bulb.Target, bulb.Address).Power(ctx); err != nil {
+		t.Fatal(err)
+	}
+	otherCtx, done := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer done()
+	other.Light(bulb.Target, bulb.Address).SetPower(otherCtx, true, 0)
+	select {
+	case h := <-seen:
+		if h.Source() != other.source || h.Type() != device.AcknowledgementType {
+			t.Errorf("expected the acknowledgement for the other app but got %v", h)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the other app's acknowledgement")
+	}
+
+	unsubscribe()
+	otherCtx, done = context.WithTimeout(ctx, 200*time.Millisecond)
+	defer done()
+	other.Light(bulb.Target, bulb.Address).SetPower(otherCtx, false, 0)
+	select {
+	case h := <-seen:
+		t.Errorf("expected nothing after unsubscribing but got %v", h)
+	default:
+	}
+}
+
+func TestClient_Stats(t *testing.T) {
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0x00, 0x00, 0x01, 0x00}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, bulb)
+	c := New(ctx, out, in)
+	other := New(ctx, out, make(chan *server.InboundPayload))
+
+	if _, err := c.Light(bulb.Target, bulb.Address).Power(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stats := c.Stats()
+	if stats.Sent < 1 || stats.Received < 1 || stats.Dropped != 0 {
+		t.Errorf("expected a packet sent and received and none dropped but got %+v", stats)
+	}
+
+	// nobody is waiting for or subscribed to the other app's acknowledgement
+	otherCtx, done := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer done()
+	other.Light(bulb.Target, bulb.Address).SetPower(otherCtx, true, 0)
+	<-otherCtx.Done()
+	if stats := c.Stats(); stats.Dropped < 1 {
+		t.Errorf("expected the other app's acknowledgement dropped but got %+v", stats)
+	}
+}
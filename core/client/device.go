@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"net"
+)
+
+//Device is a LIFX device on the network. Its methods block until the device answers or ctx is done.
+type Device struct {
+	Target  []byte
+	Address *net.UDPAddr
+
+	client *Client
+}
+
+//TargetHex is the TARGET_HEXSTR used on the commandline for this device.
+func (d *Device) TargetHex() string {
+	return fmt.Sprintf("%x", d.Target)
+}
+
+func (d *Device) String() string {
+	return fmt.Sprintf("Device{ Target:%x Address:%v }", d.Target, d.Address)
+}
+
+//get sends a Get* message and decodes the answer into state.
+func (d *Device) get(ctx context.Context, requiredHeader func(*header.Header), message interface{}, responseType uint16, state interface{}) error {
+	h, err := d.client.request(ctx, d.Target, d.Address, requiredHeader, message, responseType)
+	if err != nil {
+		return err
+	}
+	return decode(*h, state)
+}
+
+//set sends a Set* message and waits for the device to acknowledge it.
+func (d *Device) set(ctx context.Context, requiredHeader func(*header.Header), message interface{}) error {
+	_, err := d.client.request(ctx, d.Target, d.Address, requiredHeader, message, device.AcknowledgementType)
+	return err
+}
+
+//Power reports if the device is on.
+func (d *Device) Power(ctx context.Context) (bool, error) {
+	level, err := d.PowerLevel(ctx)
+	return level == 0xffff, err
+}
+
+//PowerLevel returns the raw power level, 0 is off and 65535 is on.
+func (d *Device) PowerLevel(ctx context.Context) (uint16, error) {
+	var state device.StatePower
+	message := device.GetPower{}
+	if err := d.get(ctx, message.RequiredHeader, &message, device.StatePowerType, &state); err != nil {
+		return 0, err
+	}
+	return state.Level, nil
+}
+
+//SetPower turns the device on or off (standby).
+func (d *Device) SetPower(ctx context.Context, on bool) error {
+	message := device.SetPower{}
+	message.SetLevel(on)
+	return d.set(ctx, func(h *header.Header) { message.RequiredHeader(h, false) }, &message)
+}
+
+//Label returns the device's label without its NUL padding.
+func (d *Device) Label(ctx context.Context) (string, error) {
+	var state device.StateLabel
+	message := device.GetLabel{}
+	if err := d.get(ctx, message.RequiredHeader, &message, device.StateLabelType, &state); err != nil {
+		return "", err
+	}
+	return fields.Label(state.Label), nil
+}
+
+//Version returns the vendor, product and hardware version of the device.
+func (d *Device) Version(ctx context.Context) (*device.StateVersion, error) {
+	var state device.StateVersion
+	message := device.GetVersion{}
+	if err := d.get(ctx, message.RequiredHeader, &message, device.StateVersionType, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+//Info returns the device's time, uptime and downtime.
+func (d *Device) Info(ctx context.Context) (*device.StateInfo, error) {
+	var state device.StateInfo
+	message := device.GetInfo{}
+	if err := d.get(ctx, message.RequiredHeader, &message, device.StateInfoType, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+//HostFirmware returns the firmware build and version.
+func (d *Device) HostFirmware(ctx context.Context) (*device.StateHostFirmware, error) {
+	var state device.StateHostFirmware
+	message := device.GetHostFirmware{}
+	if err := d.get(ctx, message.RequiredHeader, &message, device.StateHostFirmwareType, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+//WifiInfo returns the signal strength and traffic counters of the device's wifi.
+func (d *Device) WifiInfo(ctx context.Context) (*device.StateWifiInfo, error) {
+	var state device.StateWifiInfo
+	message := device.GetWifiInfo{}
+	if err := d.get(ctx, message.RequiredHeader, &message, device.StateWifiInfoType, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+//Location returns the location the device belongs to.
+func (d *Device) Location(ctx context.Context) (*device.StateLocation, error) {
+	var state device.StateLocation
+	message := device.GetLocation{}
+	if err := d.get(ctx, message.RequiredHeader, &message, device.StateLocationType, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+//Group returns the group the device belongs to.
+func (d *Device) Group(ctx context.Context) (*device.StateGroup, error) {
+	var state device.StateGroup
+	message := device.GetGroup{}
+	if err := d.get(ctx, message.RequiredHeader, &message, device.StateGroupType, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
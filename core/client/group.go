@@ -0,0 +1,294 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+//Location is a set of groups sharing a location UUID, usually a home or an office.
+type Location struct {
+	ID        [16]byte
+	Label     string
+	UpdatedAt time.Time
+	Groups    []*Group
+}
+
+//Group is a set of lights sharing a group UUID, usually a room.
+type Group struct {
+	ID        [16]byte
+	Label     string
+	UpdatedAt time.Time
+	Location  *Location
+	Lights    []*Light
+}
+
+//Membership is what a light reported about its group and location.
+type Membership struct {
+	Light    *Light
+	Group    device.StateGroup
+	Location device.StateLocation
+}
+
+//GroupState is the combined state of a set of lights. Power is on if any light is on and Color is the
+//average color of the lights that are on (or of all of them when none are).
+type GroupState struct {
+	Label  string    `json:"label" yaml:"label"`
+	Power  bool      `json:"power" yaml:"power"`
+	On     int       `json:"on" yaml:"on"`
+	Lights int       `json:"lights" yaml:"lights"`
+	Color  hsbk.HSBK `json:"color" yaml:"color"`
+}
+
+func (s GroupState) String() string {
+	return fmt.Sprintf("{Label:%v Power:%v On:%v/%v Color:%v}", s.Label, fields.Power(powerLevel(s.Power)), s.On, s.Lights, s.Color)
+}
+
+//Memberships asks every light for its group and location. Lights that don't answer before ctx is done
+//are left out.
+func (c *Client) Memberships(ctx context.Context, lights []*Light) []Membership {
+	memberships := make([]*Membership, len(lights))
+	var wg sync.WaitGroup
+	for i, l := range lights {
+		wg.Add(1)
+		go func(i int, l *Light) {
+			defer wg.Done()
+			group, err := l.Group(ctx)
+			if err != nil {
+				return
+			}
+			location, err := l.Location(ctx)
+			if err != nil {
+				return
+			}
+			memberships[i] = &Membership{Light: l, Group: *group, Location: *location}
+		}(i, l)
+	}
+	wg.Wait()
+
+	result := make([]Membership, 0, len(lights))
+	for _, m := range memberships {
+		if m != nil {
+			result = append(result, *m)
+		}
+	}
+	return result
+}
+
+//Locations queries the lights and builds the Location -> Group -> Light hierarchy from the answers.
+func (c *Client) Locations(ctx context.Context, lights []*Light) []*Location {
+	return Hierarchy(c.Memberships(ctx, lights))
+}
+
+//Hierarchy groups lights by location and group UUID. Devices can disagree about the label of a group or
+//location when it was renamed while some of them were offline, the label with the newest UpdatedAt wins.
+//Locations, groups and lights are sorted by label then id so the result is stable.
+func Hierarchy(memberships []Membership) []*Location {
+	locations := make(map[[16]byte]*Location)
+	groups := make(map[[16]byte]*Group)
+	for _, m := range memberships {
+		location, has := locations[m.Location.Location]
+		updated := fields.Time(m.Location.UpdatedAt)
+		if !has {
+			location = &Location{ID: m.Location.Location, Groups: make([]*Group, 0)}
+			locations[location.ID] = location
+		}
+		if !has || updated.After(location.UpdatedAt) {
+			location.Label = fields.Label(m.Location.Label)
+			location.UpdatedAt = updated
+		}
+
+		group, has := groups[m.Group.Group]
+		updated = fields.Time(m.Group.UpdatedAt)
+		if !has {
+			group = &Group{ID: m.Group.Group, Location: location, Lights: make([]*Light, 0)}
+			groups[group.ID] = group
+			location.Groups = append(location.Groups, group)
+		}
+		if !has || updated.After(group.UpdatedAt) {
+			group.Label = fields.Label(m.Group.Label)
+			group.UpdatedAt = updated
+		}
+		group.Lights = append(group.Lights, m.Light)
+	}
+
+	result := make([]*Location, 0, len(locations))
+	for _, location := range locations {
+		sort.Slice(location.Groups, func(i, j int) bool {
+			return less(location.Groups[i].Label, location.Groups[j].Label, location.Groups[i].ID[:], location.Groups[j].ID[:])
+		})
+		for _, group := range location.Groups {
+			sort.Slice(group.Lights, func(i, j int) bool {
+				return group.Lights[i].TargetHex() < group.Lights[j].TargetHex()
+			})
+		}
+		result = append(result, location)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return less(result[i].Label, result[j].Label, result[i].ID[:], result[j].ID[:])
+	})
+	return result
+}
+
+func less(labelA, labelB string, idA, idB []byte) bool {
+	if labelA != labelB {
+		return labelA < labelB
+	}
+	return fmt.Sprintf("%x", idA) < fmt.Sprintf("%x", idB)
+}
+
+func (g *Group) String() string {
+	return fmt.Sprintf("Group{ %v (%v) Lights:%v }", g.Label, fields.UUID(g.ID), len(g.Lights))
+}
+
+//SetPower turns every light in the group on or off at the same time.
+func (g *Group) SetPower(ctx context.Context, on bool, duration time.Duration) error {
+	return Each(g.Lights, func(_ int, l *Light) error { return l.SetPower(ctx, on, duration) })
+}
+
+//SetColor changes the color of every light in the group at the same time.
+func (g *Group) SetColor(ctx context.Context, color hsbk.HSBK, duration time.Duration) error {
+	return Each(g.Lights, func(_ int, l *Light) error { return l.SetColor(ctx, color, duration) })
+}
+
+//State combines the state of the group's lights, lights that don't answer are left out.
+func (g *Group) State(ctx context.Context) (*GroupState, error) {
+	state, err := aggregate(ctx, g.Lights)
+	if state != nil {
+		state.Label = g.Label
+	}
+	return state, err
+}
+
+func (l *Location) String() string {
+	return fmt.Sprintf("Location{ %v (%v) Groups:%v }", l.Label, fields.UUID(l.ID), len(l.Groups))
+}
+
+//Lights returns the lights of every group in the location.
+func (l *Location) Lights() []*Light {
+	lights := make([]*Light, 0)
+	for _, g := range l.Groups {
+		lights = append(lights, g.Lights...)
+	}
+	return lights
+}
+
+//SetPower turns every light in the location on or off at the same time.
+func (l *Location) SetPower(ctx context.Context, on bool, duration time.Duration) error {
+	return Each(l.Lights(), func(_ int, light *Light) error { return light.SetPower(ctx, on, duration) })
+}
+
+//SetColor changes the color of every light in the location at the same time.
+func (l *Location) SetColor(ctx context.Context, color hsbk.HSBK, duration time.Duration) error {
+	return Each(l.Lights(), func(_ int, light *Light) error { return light.SetColor(ctx, color, duration) })
+}
+
+//State combines the state of every light in the location, lights that don't answer are left out.
+func (l *Location) State(ctx context.Context) (*GroupState, error) {
+	state, err := aggregate(ctx, l.Lights())
+	if state != nil {
+		state.Label = l.Label
+	}
+	return state, err
+}
+
+//Each runs action on every light at once, i is the light's index in lights. It waits for them all and
+//reports how many failed along with the first error.
+func Each(lights []*Light, action func(i int, l *Light) error) error {
+	errs := make([]error, len(lights))
+	var wg sync.WaitGroup
+	for i, l := range lights {
+		wg.Add(1)
+		go func(i int, l *Light) {
+			defer wg.Done()
+			errs[i] = action(i, l)
+		}(i, l)
+	}
+	wg.Wait()
+
+	failed := 0
+	var first error
+	for _, err := range errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%v of %v lights failed: %v", failed, len(lights), first)
+	}
+	return nil
+}
+
+func aggregate(ctx context.Context, lights []*Light) (*GroupState, error) {
+	var mux sync.Mutex
+	colors := make([]hsbk.HSBK, 0)
+	onColors := make([]hsbk.HSBK, 0)
+	err := Each(lights, func(_ int, l *Light) error {
+		state, err := l.State(ctx)
+		if err != nil {
+			return err
+		}
+		mux.Lock()
+		defer mux.Unlock()
+		colors = append(colors, state.Color)
+		if state.Power != 0 {
+			onColors = append(onColors, state.Color)
+		}
+		return nil
+	})
+	if len(colors) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no lights")
+		}
+		return nil, err
+	}
+
+	result := &GroupState{Power: len(onColors) > 0, On: len(onColors), Lights: len(colors)}
+	if len(onColors) > 0 {
+		result.Color = average(onColors)
+	} else {
+		result.Color = average(colors)
+	}
+	// a partial answer is still useful, the caller decides what to do with the error
+	return result, err
+}
+
+//average is the mean color, hue is averaged around the color wheel so red (0) and red (65535) stay red.
+func average(colors []hsbk.HSBK) hsbk.HSBK {
+	var x, y, s, b, k float64
+	for _, c := range colors {
+		angle := float64(c.Hue) / 0x10000 * 2 * math.Pi
+		x += math.Cos(angle)
+		y += math.Sin(angle)
+		s += float64(c.Saturation)
+		b += float64(c.Brightness)
+		k += float64(c.Kelvin)
+	}
+	n := float64(len(colors))
+	angle := math.Atan2(y, x)
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	return hsbk.HSBK{
+		Hue:        uint16(int(math.Round(angle/(2*math.Pi)*0x10000)) % 0x10000),
+		Saturation: uint16(math.Round(s / n)),
+		Brightness: uint16(math.Round(b / n)),
+		Kelvin:     uint16(math.Round(k / n)),
+	}
+}
+
+func powerLevel(on bool) uint16 {
+	if on {
+		return 0xffff
+	}
+	return 0
+}
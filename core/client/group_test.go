@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"testing"
+	"time"
+)
+
+func TestHierarchy(t *testing.T) {
+	home := [16]byte{1}
+	upstairs, downstairs := [16]byte{2}, [16]byte{3}
+	membership := func(target byte, group [16]byte, groupLabel string, groupUpdated uint64, locationLabel string, locationUpdated uint64) Membership {
+		return Membership{
+			Light:    &Light{Device: &Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, target, 0}}},
+			Group:    device.StateGroup{Group: group, Label: fields.ToLabel(groupLabel), UpdatedAt: groupUpdated},
+			Location: device.StateLocation{Location: home, Label: fields.ToLabel(locationLabel), UpdatedAt: locationUpdated},
+		}
+	}
+
+	locations := Hierarchy([]Membership{
+		membership(3, upstairs, "Upstairs", 10, "Home", 5),
+		membership(1, downstairs, "Downstairs", 10, "House", 1),
+		// renamed while light 3 was offline
+		membership(2, upstairs, "Top Floor", 20, "Home", 5),
+	})
+
+	if len(locations) != 1 {
+		t.Fatalf("expected one location but got %v", locations)
+	}
+	if locations[0].Label != "Home" {
+		t.Errorf("expected the newest location label Home but got %v", locations[0].Label)
+	}
+	groups := locations[0].Groups
+	if len(groups) != 2 || groups[0].Label != "Downstairs" || groups[1].Label != "Top Floor" {
+		t.Fatalf("expected Downstairs and Top Floor but got %v", groups)
+	}
+	if len(groups[1].Lights) != 2 || groups[1].Lights[0].Target[5] != 2 || groups[1].Lights[1].Target[5] != 3 {
+		t.Errorf("expected lights 2 and 3 in Top Floor but got %v", groups[1].Lights)
+	}
+	if groups[1].Location != locations[0] || len(locations[0].Lights()) != 3 {
+		t.Errorf("expected the groups to point back at their location")
+	}
+}
+
+func TestGroup_Aggregate(t *testing.T) {
+	group := [16]byte{9}
+	red := hsbk.HSBK{Hue: 0xfff0, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500}
+	devices := []*emulator.Device{
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Group: group, GroupLabel: "Office", Color: red, Power: 0xffff},
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 2, 0}, Group: group, GroupLabel: "Office", Color: hsbk.HSBK{Hue: 0x0010, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500}, Power: 0xffff},
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 3, 0}, Group: group, GroupLabel: "Office"},
+	}
+	c, ctx := startEmulated(t, devices...)
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	lights := make([]*Light, 0)
+	for _, d := range devices {
+		lights = append(lights, c.Light(d.Target, d.Address))
+	}
+	locations := c.Locations(ctx, lights)
+	if len(locations) != 1 || len(locations[0].Groups) != 1 {
+		t.Fatalf("expected a single group but got %v", locations)
+	}
+	office := locations[0].Groups[0]
+
+	state, err := office.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Power || state.On != 2 || state.Lights != 3 || state.Label != "Office" {
+		t.Errorf("expected 2 of 3 Office lights on but got %v", state)
+	}
+	if state.Color.Hue != 0 || state.Color.Brightness != 0x8000 {
+		t.Errorf("expected the average of the reds to stay red but got %v", state.Color)
+	}
+
+	if err := office.SetPower(ctx, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	state, err = office.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Power || state.On != 0 {
+		t.Errorf("expected the office to be off but got %v", state)
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"time"
+)
+
+//Light is a LIFX bulb, it has all the Device methods plus the light messages.
+type Light struct {
+	*Device
+}
+
+func (l *Light) String() string {
+	return fmt.Sprintf("Light{ Target:%x Address:%v }", l.Target, l.Address)
+}
+
+//State returns the light's color, power and label.
+func (l *Light) State(ctx context.Context) (*light.State, error) {
+	var state light.State
+	message := light.Get{}
+	if err := l.get(ctx, message.RequiredHeader, &message, light.StateType, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+//SetColor changes the light's color over duration.
+func (l *Light) SetColor(ctx context.Context, color hsbk.HSBK, duration time.Duration) error {
+	message := light.SetColor{Color: color, Duration: milliseconds(duration)}
+	return l.set(ctx, func(h *header.Header) { message.RequiredHeader(h, false) }, &message)
+}
+
+//SendColor is SetColor without waiting for the light to acknowledge it, for animations sending many
+//colors a second.
+func (l *Light) SendColor(ctx context.Context, color hsbk.HSBK, duration time.Duration) error {
+	message := light.SetColor{Color: color, Duration: milliseconds(duration)}
+	return l.client.post(ctx, l.Target, l.Address, func(h *header.Header) { message.RequiredHeader(h, false) }, &message)
+}
+
+//SetPower turns the light on or off (standby) fading over duration.
+func (l *Light) SetPower(ctx context.Context, on bool, duration time.Duration) error {
+	message := light.SetPower{Duration: milliseconds(duration)}
+	message.SetLevel(on)
+	return l.set(ctx, func(h *header.Header) { message.RequiredHeader(h, false) }, &message)
+}
+
+func milliseconds(duration time.Duration) uint32 {
+	if duration <= 0 {
+		return 0
+	}
+	return uint32(duration / time.Millisecond)
+}
+
+//decode picks the message package matching the response type.
+func decode(h header.Header, state interface{}) error {
+	if err := device.DecodeFromHeader(h, state); err == nil {
+		return nil
+	}
+	return light.DecodeFromHeader(h, state)
+}
@@ -0,0 +1,148 @@
+package effect
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"math/rand"
+	"time"
+)
+
+//DefaultFPS is how many colors a second each light is sent unless told otherwise, the client's
+//MinInterval still applies on top.
+const DefaultFPS = 10
+
+//Frame is what an effect knows when picking a light's next color.
+type Frame struct {
+	//Elapsed is the time since the effect started
+	Elapsed time.Duration
+	//Interval is the time until the next frame
+	Interval time.Duration
+	//Index of the light among Count lights running the effect, for effects spread over a group
+	Index, Count int
+	//Base is the light's color when the effect started
+	Base hsbk.HSBK
+	//Rand is the light's own random source
+	Rand *rand.Rand
+}
+
+//Effect picks the color of each frame and how long the light takes to fade to it.
+type Effect interface {
+	Frame(f Frame) (color hsbk.HSBK, fade time.Duration)
+}
+
+//Options controls a running effect.
+type Options struct {
+	//FPS is how many frames a second each light is sent, 0 is DefaultFPS
+	FPS int
+	//Duration stops the effect by itself, 0 runs until Stop
+	Duration time.Duration
+	//Seed makes the random effects repeatable, 0 picks one from the clock
+	Seed int64
+	//Restore puts the lights back to how they were when the effect stops
+	Restore bool
+}
+
+//Running is an effect running on a set of lights.
+type Running struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+//Start runs the effect on every light in its own goroutine until ctx is done, Stop is called or
+//opts.Duration has passed. Lights that can't be reached are left out and counted in Wait's error.
+func Start(ctx context.Context, effect Effect, lights []*client.Light, opts Options) *Running {
+	var cancel context.CancelFunc
+	if opts.Duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	fps := opts.FPS
+	if fps <= 0 {
+		fps = DefaultFPS
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	r := &Running{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		r.err = client.Each(lights, func(i int, l *client.Light) error {
+			return run(ctx, effect, l, i, len(lights), time.Second/time.Duration(fps), rand.New(rand.NewSource(seed+int64(i))), opts.Restore)
+		})
+	}()
+	return r
+}
+
+//Stop ends the effect and waits for the lights to be restored.
+func (r *Running) Stop() error {
+	r.cancel()
+	return r.Wait()
+}
+
+//Wait blocks until the effect ends by itself or is stopped.
+func (r *Running) Wait() error {
+	<-r.done
+	return r.err
+}
+
+//Done is closed once the effect ended.
+func (r *Running) Done() <-chan struct{} {
+	return r.done
+}
+
+func run(ctx context.Context, effect Effect, l *client.Light, index, count int, interval time.Duration, random *rand.Rand, restore bool) error {
+	stateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	original, err := l.State(stateCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if restore {
+		defer restoreState(l, original)
+	}
+	if original.Power == 0 {
+		if err := l.SetPower(ctx, true, 0); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		color, fade := effect.Frame(Frame{
+			Elapsed:  time.Since(start),
+			Interval: interval,
+			Index:    index,
+			Count:    count,
+			Base:     original.Color,
+			Rand:     random,
+		})
+		if err := l.SendColor(ctx, color, fade); err != nil && ctx.Err() == nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+//restoreState runs after the effect's context is gone so it gets its own.
+func restoreState(l *client.Light, original *light.State) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.SetColor(ctx, original.Color, 0); err != nil {
+		return
+	}
+	if original.Power == 0 {
+		l.SetPower(ctx, false, 0)
+	}
+}
@@ -0,0 +1,122 @@
+package effect
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func frame(elapsed time.Duration, index, count int) Frame {
+	return Frame{
+		Elapsed:  elapsed,
+		Interval: 100 * time.Millisecond,
+		Index:    index,
+		Count:    count,
+		Base:     hsbk.HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500},
+		Rand:     rand.New(rand.NewSource(1)),
+	}
+}
+
+func TestEffects_Frames(t *testing.T) {
+	for _, name := range Names() {
+		e, err := Named(name, Params{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 50; i++ {
+			if _, fade := e.Frame(frame(time.Duration(i)*100*time.Millisecond, i%3, 3)); fade < 0 {
+				t.Errorf("%v: negative fade", name)
+			}
+		}
+	}
+	if _, err := Named("disco", Params{}); err == nil {
+		t.Errorf("expected an unknown effect to fail")
+	}
+
+	breathe := Breathe{Period: 4 * time.Second}
+	full, _ := breathe.Frame(frame(0, 0, 1))
+	dim, _ := breathe.Frame(frame(2*time.Second, 0, 1))
+	if full.Brightness != 0x8000 || dim.Brightness >= full.Brightness/5 || dim.Hue != full.Hue {
+		t.Errorf("expected breathe to dim the light's color half way but got %v and %v", full, dim)
+	}
+
+	rainbow := Rainbow{Period: time.Second}
+	a, _ := rainbow.Frame(frame(0, 0, 2))
+	b, _ := rainbow.Frame(frame(0, 1, 2))
+	if a.Hue != 0 || b.Hue != hsbk.FromDegrees(180) {
+		t.Errorf("expected two lights on opposite sides of the wheel but got %v and %v", a, b)
+	}
+
+	police, _ := Named("police", Params{})
+	left, fade := police.Frame(frame(0, 0, 2))
+	right, _ := police.Frame(frame(0, 1, 2))
+	if left == right || fade != 0 {
+		t.Errorf("expected neighbouring police lights to differ without fading but got %v and %v", left, right)
+	}
+}
+
+func TestEffects_Seeded(t *testing.T) {
+	candle := Candle{}
+	run := func() []hsbk.HSBK {
+		f := frame(0, 0, 1)
+		colors := make([]hsbk.HSBK, 10)
+		for i := range colors {
+			colors[i], _ = candle.Frame(f)
+		}
+		return colors
+	}
+	first, second := run(), run()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("expected the same seed to flicker the same but got %v and %v", first, second)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	original := hsbk.HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500}
+	devices := []*emulator.Device{
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Color: original},
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 2, 0}, Color: original, Power: 0xffff},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, devices...)
+	c := client.New(ctx, out, in)
+	lights := []*client.Light{c.Light(devices[0].Target, devices[0].Address), c.Light(devices[1].Target, devices[1].Address)}
+
+	running := Start(ctx, Rainbow{Period: time.Second}, lights, Options{FPS: 50, Restore: true})
+	time.Sleep(300 * time.Millisecond)
+	state, err := lights[0].State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Power == 0 || state.Color == original {
+		t.Errorf("expected the effect to turn the light on and change it but got %v", state)
+	}
+	if err := running.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, l := range lights {
+		state, err := l.State(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.Color != original || state.Power != devices[i].Power {
+			t.Errorf("%v: expected the light restored but got %v", l, state)
+		}
+	}
+
+	start := time.Now()
+	if err := Start(ctx, Candle{}, lights[:1], Options{Duration: 200 * time.Millisecond}).Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("expected the effect to stop by itself after 200ms but took %v", elapsed)
+	}
+}
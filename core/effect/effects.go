@@ -0,0 +1,196 @@
+package effect
+
+import (
+	"fmt"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"math"
+	"sort"
+	"time"
+)
+
+//Params are the settings the named effects share, zero values pick each effect's default.
+type Params struct {
+	//Color replaces the light's own color for the effects that use one
+	Color *hsbk.HSBK
+	//Period is the length of one cycle, breath or flash
+	Period time.Duration
+}
+
+//Effects are the built in effects by the names used on the commandline.
+var Effects = map[string]func(Params) Effect{
+	"breathe": func(p Params) Effect {
+		return Breathe{Color: p.Color, Period: orDefault(p.Period, 4*time.Second)}
+	},
+	"candle": func(p Params) Effect {
+		return Candle{Color: p.Color}
+	},
+	"colorcycle": func(p Params) Effect {
+		return ColorCycle{Period: orDefault(p.Period, 30*time.Second)}
+	},
+	"rainbow": func(p Params) Effect {
+		return Rainbow{Period: orDefault(p.Period, 10*time.Second)}
+	},
+	"strobe": func(p Params) Effect {
+		color := hsbk.HSBK{Brightness: 0xffff, Kelvin: 6500}
+		if p.Color != nil {
+			color = *p.Color
+		}
+		return Strobe{Colors: []hsbk.HSBK{color, {Kelvin: color.Kelvin}}, Period: orDefault(p.Period, 200*time.Millisecond)}
+	},
+	"police": func(p Params) Effect {
+		return Strobe{Colors: []hsbk.HSBK{policeRed, policeBlue}, Period: orDefault(p.Period, 500*time.Millisecond), Alternate: true}
+	},
+	"lightning": func(p Params) Effect {
+		return Lightning{Color: p.Color}
+	},
+}
+
+//Names returns the names in Effects sorted.
+func Names() []string {
+	names := make([]string, 0, len(Effects))
+	for name := range Effects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Named builds one of the built in effects.
+func Named(name string, p Params) (Effect, error) {
+	build, has := Effects[name]
+	if !has {
+		return nil, fmt.Errorf("unknown effect %q (expected one of %v)", name, Names())
+	}
+	return build(p), nil
+}
+
+var (
+	policeRed  = hsbk.HSBK{Hue: 0, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}
+	policeBlue = hsbk.HSBK{Hue: 0xaaaa, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}
+	candleBase = hsbk.Color{Hue: 25, Saturation: 0.85, Brightness: 0.6, Kelvin: 2500}.HSBK()
+	stormBase  = hsbk.Color{Hue: 230, Saturation: 0.6, Brightness: 0.05, Kelvin: 6500}.HSBK()
+)
+
+func orDefault(d, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+func colorOr(color *hsbk.HSBK, fallback hsbk.HSBK) hsbk.HSBK {
+	if color != nil {
+		return *color
+	}
+	return fallback
+}
+
+//phase is how far into the current period elapsed is, in [0,1).
+func phase(elapsed, period time.Duration) float64 {
+	if period <= 0 {
+		return 0
+	}
+	return float64(elapsed%period) / float64(period)
+}
+
+//Breathe slowly dims the color to a tenth and back.
+type Breathe struct {
+	//Color is breathed, nil uses the light's own color
+	Color  *hsbk.HSBK
+	Period time.Duration
+}
+
+func (b Breathe) Frame(f Frame) (hsbk.HSBK, time.Duration) {
+	color := colorOr(b.Color, f.Base).Color()
+	// a raised cosine, full at the start of each period and dimmest half way
+	level := 0.55 + 0.45*math.Cos(2*math.Pi*phase(f.Elapsed, b.Period))
+	color.Brightness *= level
+	return color.HSBK(), f.Interval
+}
+
+//Candle flickers a warm orange at random brightness like a flame.
+type Candle struct {
+	//Color is flickered, nil is a candle orange
+	Color *hsbk.HSBK
+}
+
+func (c Candle) Frame(f Frame) (hsbk.HSBK, time.Duration) {
+	color := colorOr(c.Color, candleBase).Color()
+	color.Brightness *= 0.6 + 0.4*f.Rand.Float64()
+	color.Hue += 4 * (f.Rand.Float64() - 0.5)
+	// uneven fades look more like a flame than the frame rate
+	fade := time.Duration(float64(f.Interval) * (0.5 + f.Rand.Float64()))
+	return color.HSBK(), fade
+}
+
+//ColorCycle turns every light around the color wheel together, keeping each light's brightness.
+type ColorCycle struct {
+	Period time.Duration
+}
+
+func (c ColorCycle) Frame(f Frame) (hsbk.HSBK, time.Duration) {
+	color := f.Base.Color()
+	color.Hue = 360 * phase(f.Elapsed, c.Period)
+	color.Saturation = 1
+	if color.Brightness == 0 {
+		color.Brightness = 1
+	}
+	return color.HSBK(), f.Interval
+}
+
+//Rainbow spreads the color wheel over the lights and rotates it.
+type Rainbow struct {
+	Period time.Duration
+}
+
+func (r Rainbow) Frame(f Frame) (hsbk.HSBK, time.Duration) {
+	count := f.Count
+	if count < 1 {
+		count = 1
+	}
+	color := f.Base.Color()
+	color.Hue = 360 * (phase(f.Elapsed, r.Period) + float64(f.Index)/float64(count))
+	color.Saturation = 1
+	if color.Brightness == 0 {
+		color.Brightness = 1
+	}
+	return color.HSBK(), f.Interval
+}
+
+//Strobe steps through Colors every Period without fading. With Alternate neighbouring lights are out of
+//step, like police lights.
+type Strobe struct {
+	Colors    []hsbk.HSBK
+	Period    time.Duration
+	Alternate bool
+}
+
+func (s Strobe) Frame(f Frame) (hsbk.HSBK, time.Duration) {
+	if len(s.Colors) == 0 {
+		return f.Base, 0
+	}
+	step := 0
+	if s.Period > 0 {
+		step = int(f.Elapsed / s.Period)
+	}
+	if s.Alternate {
+		step += f.Index
+	}
+	return s.Colors[step%len(s.Colors)], 0
+}
+
+//Lightning is a dark stormy blue with sudden flashes of white.
+type Lightning struct {
+	//Color is the sky between flashes, nil is a dark blue
+	Color *hsbk.HSBK
+}
+
+func (l Lightning) Frame(f Frame) (hsbk.HSBK, time.Duration) {
+	// about one flash every four seconds whatever the frame rate
+	chance := f.Interval.Seconds() / 4
+	if f.Rand.Float64() < chance {
+		return hsbk.HSBK{Brightness: 0xffff, Kelvin: 6500}, 0
+	}
+	// the sky fades back slowly after a flash
+	return colorOr(l.Color, stormBase), 3 * f.Interval
+}
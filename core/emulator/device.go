@@ -0,0 +1,259 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"net"
+	"sync"
+	"time"
+)
+
+//Device is an in-memory LIFX bulb. It answers the device and light messages the library knows about
+//and keeps the state the setters change.
+type Device struct {
+	Target  []byte
+	Address *net.UDPAddr
+
+	Label    string
+	Power    uint16
+	Color    hsbk.HSBK
+	Infrared uint16
+
+	Vendor  uint32
+	Product uint32
+	Version uint32
+
+	FirmwareBuild uint64
+	FirmwareMajor uint64
+	FirmwareMinor uint64
+	//Signal is the wifi signal as the device reports it, 0 answers as no signal
+	Signal float32
+	Tx     uint32
+	Rx     uint32
+	//Downtime is how long the device says it was last powered off
+	Downtime time.Duration
+
+	Location          [16]byte
+	LocationLabel     string
+	LocationUpdatedAt uint64
+	Group             [16]byte
+	GroupLabel        string
+	GroupUpdatedAt    uint64
+
+	mux     sync.Mutex
+	started time.Time
+}
+
+//Handle processes a request and returns the datagrams the device sends back, which may be none.
+func (d *Device) Handle(request header.Header) ([][]byte, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if d.started.IsZero() {
+		d.started = time.Now()
+	}
+
+	if !d.addressed(request) {
+		return nil, nil
+	}
+
+	responses := make([][]byte, 0)
+	if request.AcknowledgementRequired() {
+		ack, err := d.response(request, device.AcknowledgementType, &device.Acknowledgement{})
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, ack)
+	}
+
+	// setters only answer when asked to, getters always do
+	var responseType uint16
+	var message interface{}
+	switch request.Type() {
+	case device.GetServiceType:
+		responseType, message = device.StateServiceType, &device.StateService{Service: 1, Port: 56700}
+	case device.GetPowerType:
+		responseType, message = device.StatePowerType, &device.StatePower{Level: d.Power}
+	case device.SetPowerType:
+		var s device.SetPower
+		if err := device.DecodeFromHeader(request, &s); err != nil {
+			return nil, err
+		}
+		d.Power = s.Level
+		if request.ResponseRequired() {
+			responseType, message = device.StatePowerType, &device.StatePower{Level: d.Power}
+		}
+	case device.GetLabelType:
+		responseType, message = device.StateLabelType, &device.StateLabel{Label: fields.ToLabel(d.Label)}
+	case device.SetLabelType:
+		var s device.SetLabel
+		if err := device.DecodeFromHeader(request, &s); err != nil {
+			return nil, err
+		}
+		d.Label = fields.Label(s.Label)
+		if request.ResponseRequired() {
+			responseType, message = device.StateLabelType, &device.StateLabel{Label: s.Label}
+		}
+	case device.GetVersionType:
+		responseType, message = device.StateVersionType, &device.StateVersion{Vendor: d.Vendor, Product: d.Product, Version: d.Version}
+	case device.GetHostFirmwareType:
+		responseType, message = device.StateHostFirmwareType, &device.StateHostFirmware{
+			Build:        d.FirmwareBuild,
+			VersionMinor: d.FirmwareMinor,
+			VersionMajor: d.FirmwareMajor,
+		}
+	case device.GetWifiInfoType:
+		responseType, message = device.StateWifiInfoType, &device.StateWifiInfo{Signal: d.Signal, Tx: d.Tx, Rx: d.Rx}
+	case device.GetInfoType:
+		now := time.Now()
+		responseType, message = device.StateInfoType, &device.StateInfo{
+			Time:     uint64(now.UnixNano()),
+			Uptime:   uint64(now.Sub(d.started).Nanoseconds()),
+			Downtime: uint64(d.Downtime.Nanoseconds()),
+		}
+	case device.GetLocationType:
+		responseType, message = device.StateLocationType, d.stateLocation()
+	case device.SetLocationType:
+		var s device.SetLocation
+		if err := device.DecodeFromHeader(request, &s); err != nil {
+			return nil, err
+		}
+		d.Location, d.LocationLabel, d.LocationUpdatedAt = s.Location, fields.Label(s.Label), s.UpdatedAt
+		if request.ResponseRequired() {
+			responseType, message = device.StateLocationType, d.stateLocation()
+		}
+	case device.GetGroupType:
+		responseType, message = device.StateGroupType, d.stateGroup()
+	case device.SetGroupType:
+		var s device.SetGroup
+		if err := device.DecodeFromHeader(request, &s); err != nil {
+			return nil, err
+		}
+		d.Group, d.GroupLabel, d.GroupUpdatedAt = s.Group, fields.Label(s.Label), s.UpdatedAt
+		if request.ResponseRequired() {
+			responseType, message = device.StateGroupType, d.stateGroup()
+		}
+	case device.EchoRequestType:
+		var s device.EchoRequest
+		if err := device.DecodeFromHeader(request, &s); err != nil {
+			return nil, err
+		}
+		echo := device.EchoResponse(s)
+		responseType, message = device.EchoResponseType, &echo
+	case light.GetType:
+		responseType, message = light.StateType, d.lightState()
+	case light.SetColorType:
+		var s light.SetColor
+		if err := light.DecodeFromHeader(request, &s); err != nil {
+			return nil, err
+		}
+		d.Color = s.Color
+		if request.ResponseRequired() {
+			responseType, message = light.StateType, d.lightState()
+		}
+	case light.GetPowerType:
+		responseType, message = light.StatePowerType, &light.StatePower{Level: d.Power}
+	case light.SetPowerType:
+		var s light.SetPower
+		if err := light.DecodeFromHeader(request, &s); err != nil {
+			return nil, err
+		}
+		d.Power = s.Level
+		if request.ResponseRequired() {
+			responseType, message = light.StatePowerType, &light.StatePower{Level: d.Power}
+		}
+	case light.GetInfraredType:
+		responseType, message = light.StateInfraredType, &light.StateInfrared{Brightness: d.Infrared}
+	case light.SetInfraredType:
+		var s light.SetInfrared
+		if err := light.DecodeFromHeader(request, &s); err != nil {
+			return nil, err
+		}
+		d.Infrared = s.Brightness
+		if request.ResponseRequired() {
+			responseType, message = light.StateInfraredType, &light.StateInfrared{Brightness: d.Infrared}
+		}
+	default:
+		return responses, fmt.Errorf("unsupported message type %v", request.Type())
+	}
+
+	if message == nil {
+		return responses, nil
+	}
+	response, err := d.response(request, responseType, message)
+	if err != nil {
+		return nil, err
+	}
+	return append(responses, response), nil
+}
+
+//addressed reports if the request is meant for this device.
+func (d *Device) addressed(request header.Header) bool {
+	if request.Tagged() {
+		return true
+	}
+	target := request.Target()
+	if bytes.Equal(target, make([]byte, len(target))) {
+		return true
+	}
+	// only the MAC counts, targets are written with and without the padding after it
+	return bytes.Equal(mac(d.Target), mac(target))
+}
+
+//mac is the first 6 bytes of target, zero filled when it is shorter.
+func mac(target []byte) []byte {
+	m := make([]byte, 6)
+	copy(m, target)
+	return m
+}
+
+//response builds a reply that correlates with request (same source and sequence).
+func (d *Device) response(request header.Header, responseType uint16, message interface{}) ([]byte, error) {
+	head := header.New(request.Sequence())
+	head.SetSource(request.Source())
+	head.SetTarget(d.Target)
+	head.SetFrameAddressReserved([]byte("LIFXV2"))
+	head.SetType(responseType)
+	head.SetSize(uint16(header.HeaderLen + binary.Size(message)))
+
+	buffer := bytes.NewBuffer([]byte{})
+	err := binary.Write(buffer, binary.LittleEndian, head)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Write(buffer, binary.LittleEndian, message)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (d *Device) lightState() *light.State {
+	return &light.State{
+		Color: d.Color,
+		Power: d.Power,
+		Label: fields.ToLabel(d.Label),
+	}
+}
+
+func (d *Device) stateLocation() *device.StateLocation {
+	return &device.StateLocation{
+		Location:  d.Location,
+		Label:     fields.ToLabel(d.LocationLabel),
+		UpdatedAt: d.LocationUpdatedAt,
+	}
+}
+
+func (d *Device) stateGroup() *device.StateGroup {
+	return &device.StateGroup{
+		Group:     d.Group,
+		Label:     fields.ToLabel(d.GroupLabel),
+		UpdatedAt: d.GroupUpdatedAt,
+	}
+}
@@ -0,0 +1,32 @@
+package emulator
+
+import (
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"testing"
+)
+
+func TestDevice_Addressed(t *testing.T) {
+	kitchen := &Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1}, Label: "Kitchen"}
+	hall := &Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 2, 0}, Label: "Hall"}
+
+	for _, target := range [][]byte{{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, {0xd0, 0x73, 0xd5, 0, 0, 1, 0xff}} {
+		request := header.New(1)
+		device.GetLabel{}.RequiredHeader(request)
+		request.SetTarget(target)
+		if responses, err := kitchen.Handle(*request); err != nil || len(responses) != 1 {
+			t.Errorf("expected the kitchen to answer %x but got %v, %v", target, len(responses), err)
+		}
+		if responses, err := hall.Handle(*request); err != nil || len(responses) != 0 {
+			t.Errorf("expected the hall to ignore %x but got %v, %v", target, len(responses), err)
+		}
+	}
+
+	everyone := header.New(2)
+	device.GetLabel{}.RequiredHeader(everyone)
+	for _, d := range []*Device{kitchen, hall} {
+		if responses, err := d.Handle(*everyone); err != nil || len(responses) != 1 {
+			t.Errorf("expected %v to answer a packet for every device but got %v, %v", d.Label, len(responses), err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package emulator
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/server"
+	"github.com/sirupsen/logrus"
+	"net"
+)
+
+//StartUp is a stand-in for server.StartUp: instead of a UDP socket the returned channels are wired to
+//the given devices. Devices without an Address are given one on 10.0.0.0/24.
+func StartUp(ctx context.Context, devices ...*Device) (outBound chan *server.OutBoundPayload, inbound chan *server.InboundPayload) {
+	outBound = make(chan *server.OutBoundPayload, 10)
+	inbound = make(chan *server.InboundPayload, 100)
+
+	for i, d := range devices {
+		if d.Address == nil {
+			d.Address = &net.UDPAddr{IP: net.IPv4(10, 0, 0, byte(i+2)), Port: 56700}
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case payload := <-outBound:
+				deliver(ctx, devices, payload, inbound)
+				if payload.Done != nil {
+					payload.Done()
+				}
+			}
+		}
+	}()
+
+	return outBound, inbound
+}
+
+func deliver(ctx context.Context, devices []*Device, payload *server.OutBoundPayload, inbound chan *server.InboundPayload) {
+	h, err := header.Decode(payload.Data)
+	if err != nil {
+		logrus.Debugf("emulator dropping datagram: %v", err)
+		return
+	}
+
+	broadcast := payload.Address == nil || payload.Address.IP.Equal(net.IPv4bcast)
+	for _, d := range devices {
+		if !broadcast && !d.Address.IP.Equal(payload.Address.IP) {
+			continue
+		}
+		responses, err := d.Handle(*h)
+		if err != nil {
+			logrus.Debugf("emulator %x: %v", d.Target, err)
+		}
+		for _, response := range responses {
+			select {
+			case <-ctx.Done():
+				return
+			case inbound <- &server.InboundPayload{Data: response, Conn: d.Address}:
+			}
+		}
+	}
+}
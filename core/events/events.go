@@ -0,0 +1,362 @@
+package events
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/sirupsen/logrus"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval    = 5 * time.Second
+	DefaultDiscover    = time.Minute
+	DefaultWait        = 3 * time.Second
+	DefaultRemoveAfter = 5 * time.Minute
+	//buffer is how many events a subscriber may fall behind before it misses some
+	buffer = 64
+)
+
+//Type is what changed.
+type Type string
+
+const (
+	//Added is a light seen for the first time, or again after being removed, with its whole state
+	Added Type = "added"
+	//Removed is a light that didn't answer for RemoveAfter, it is forgotten
+	Removed Type = "removed"
+	//Unreachable is a known light that stopped answering
+	Unreachable Type = "unreachable"
+	//Reachable is an unreachable light answering again, with its whole state
+	Reachable Type = "reachable"
+	Power     Type = "power"
+	Color     Type = "color"
+	Label     Type = "label"
+)
+
+//Event is one change to one light. Added and Reachable events carry the whole state, the others only
+//what changed.
+type Event struct {
+	Type  Type       `json:"type" yaml:"type"`
+	Time  time.Time  `json:"time" yaml:"time"`
+	ID    string     `json:"id" yaml:"id"`
+	Label string     `json:"label,omitempty" yaml:"label,omitempty"`
+	Power string     `json:"power,omitempty" yaml:"power,omitempty"`
+	Color *hsbk.HSBK `json:"color,omitempty" yaml:"color,omitempty"`
+}
+
+//Watcher turns what it learns about the lights into events: it discovers lights every Discover, polls
+//the known ones every Interval and follows the state replies the client didn't ask for, which devices
+//send when other apps change them.
+type Watcher struct {
+	Client *client.Client
+	//Interval is how often the known lights are polled, 0 is DefaultInterval
+	Interval time.Duration
+	//Discover is how often new lights are looked for, 0 is DefaultDiscover
+	Discover time.Duration
+	//Wait is how long discovery listens for lights, 0 is DefaultWait
+	Wait time.Duration
+	//RemoveAfter is how long a light may not answer before it is removed, 0 is DefaultRemoveAfter
+	RemoveAfter time.Duration
+	//Log gets subscribers too slow to keep up, nil uses logrus' standard logger
+	Log logrus.FieldLogger
+
+	mutex       sync.Mutex
+	lights      map[string]*tracked
+	subscribers map[chan Event]bool
+	stopped     bool
+}
+
+//tracked is what is known about one light, known is false until it first answered.
+type tracked struct {
+	light     *client.Light
+	known     bool
+	reachable bool
+	seen      time.Time
+	label     string
+	power     bool
+	color     hsbk.HSBK
+}
+
+//Subscribe returns a channel getting every event from now on, it is closed when Run returns or after
+//unsubscribe. A subscriber that falls too far behind misses events.
+func (w *Watcher) Subscribe() (events <-chan Event, unsubscribe func()) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	c := make(chan Event, buffer)
+	if w.stopped {
+		close(c)
+		return c, func() {}
+	}
+	if w.subscribers == nil {
+		w.subscribers = make(map[chan Event]bool)
+	}
+	w.subscribers[c] = true
+	return c, func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		if w.subscribers[c] {
+			delete(w.subscribers, c)
+			close(c)
+		}
+	}
+}
+
+//Snapshot returns an Added event for every light that answered and is reachable, which gives new
+//subscribers the state the following events change.
+func (w *Watcher) Snapshot() []Event {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	snapshot := make([]Event, 0, len(w.lights))
+	for target, t := range w.lights {
+		if t.known && t.reachable {
+			snapshot = append(snapshot, t.event(Added, target, t.seen))
+		}
+	}
+	return snapshot
+}
+
+//Light returns the tracked light with the target, the one events with that ID are about.
+func (w *Watcher) Light(target string) (*client.Light, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	t, has := w.lights[target]
+	if !has {
+		return nil, false
+	}
+	return t.light, true
+}
+
+//Run discovers and polls until ctx is done, then closes the subscribers' channels.
+func (w *Watcher) Run(ctx context.Context) error {
+	unsubscribe := w.Client.Subscribe(w.unsolicited)
+	defer func() {
+		unsubscribe()
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		w.stopped = true
+		for c := range w.subscribers {
+			delete(w.subscribers, c)
+			close(c)
+		}
+	}()
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	discover := w.Discover
+	if discover <= 0 {
+		discover = DefaultDiscover
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var discovered time.Time
+	for {
+		if time.Since(discovered) >= discover {
+			w.Find(ctx)
+			discovered = time.Now()
+		}
+		pollCtx, cancel := context.WithTimeout(ctx, interval)
+		w.Poll(pollCtx, time.Now())
+		cancel()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+//Find discovers lights for Wait and starts tracking the new ones, they are Added once they answer a poll.
+func (w *Watcher) Find(ctx context.Context) {
+	wait := w.Wait
+	if wait <= 0 {
+		wait = DefaultWait
+	}
+	discoverCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	lights, _ := w.Client.Discover(discoverCtx)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.lights == nil {
+		w.lights = make(map[string]*tracked)
+	}
+	for _, l := range lights {
+		if t, has := w.lights[l.TargetHex()]; has {
+			// the light may have a new address
+			t.light = l
+			continue
+		}
+		w.lights[l.TargetHex()] = &tracked{light: l, seen: time.Now()}
+	}
+}
+
+//Poll asks every tracked light for its state at once and sends the events for what changed since.
+func (w *Watcher) Poll(ctx context.Context, now time.Time) {
+	w.mutex.Lock()
+	lights := make([]*client.Light, 0, len(w.lights))
+	for _, t := range w.lights {
+		lights = append(lights, t.light)
+	}
+	w.mutex.Unlock()
+
+	states := make([]*light.State, len(lights))
+	var wg sync.WaitGroup
+	for i, l := range lights {
+		wg.Add(1)
+		go func(i int, l *client.Light) {
+			defer wg.Done()
+			states[i], _ = l.State(ctx)
+		}(i, l)
+	}
+	wg.Wait()
+
+	removeAfter := w.RemoveAfter
+	if removeAfter <= 0 {
+		removeAfter = DefaultRemoveAfter
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for i, l := range lights {
+		target := l.TargetHex()
+		t, has := w.lights[target]
+		if !has {
+			continue
+		}
+		if states[i] != nil {
+			label, power, color := fields.Label(states[i].Label), states[i].Power != 0, states[i].Color
+			w.observe(target, now, &label, &power, &color)
+			continue
+		}
+		switch {
+		case !t.known:
+			// found by discovery but never answered, forgotten without an event
+			if now.Sub(t.seen) >= removeAfter {
+				delete(w.lights, target)
+			}
+		case t.reachable:
+			t.reachable = false
+			w.send(Event{Type: Unreachable, Time: now, ID: target, Label: t.label})
+		case now.Sub(t.seen) >= removeAfter:
+			delete(w.lights, target)
+			w.send(Event{Type: Removed, Time: now, ID: target, Label: t.label})
+		}
+	}
+}
+
+//unsolicited follows the state replies meant for other apps, it runs on the client's reading goroutine.
+func (w *Watcher) unsolicited(h header.Header, from *net.UDPAddr) {
+	var label *string
+	var power *bool
+	var color *hsbk.HSBK
+	switch h.Type() {
+	case light.StateType:
+		var s light.State
+		if light.DecodeFromHeader(h, &s) != nil {
+			return
+		}
+		l, p := fields.Label(s.Label), s.Power != 0
+		label, power, color = &l, &p, &s.Color
+	case light.StatePowerType:
+		var s light.StatePower
+		if light.DecodeFromHeader(h, &s) != nil {
+			return
+		}
+		p := s.Level != 0
+		power = &p
+	case device.StatePowerType:
+		var s device.StatePower
+		if device.DecodeFromHeader(h, &s) != nil {
+			return
+		}
+		p := s.Level != 0
+		power = &p
+	case device.StateLabelType:
+		var s device.StateLabel
+		if device.DecodeFromHeader(h, &s) != nil {
+			return
+		}
+		l := fields.Label(s.Label)
+		label = &l
+	default:
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	// only lights already answering, a light is Added with its whole state
+	if t, has := w.lights[h.TargetHex()]; has && t.known {
+		w.observe(h.TargetHex(), time.Now(), label, power, color)
+	}
+}
+
+//observe records what a light answered, the fields not given are left as they are. The mutex must be held.
+func (w *Watcher) observe(target string, now time.Time, label *string, power *bool, color *hsbk.HSBK) {
+	t := w.lights[target]
+	wasKnown, wasReachable := t.known, t.reachable
+	t.seen, t.known, t.reachable = now, true, true
+
+	var changed []Event
+	if label != nil && *label != t.label {
+		t.label = *label
+		changed = append(changed, Event{Type: Label, Time: now, ID: target, Label: t.label})
+	}
+	if power != nil && *power != t.power {
+		t.power = *power
+		changed = append(changed, Event{Type: Power, Time: now, ID: target, Label: t.label, Power: onOff(t.power)})
+	}
+	if color != nil && *color != t.color {
+		t.color = *color
+		c := t.color
+		changed = append(changed, Event{Type: Color, Time: now, ID: target, Label: t.label, Color: &c})
+	}
+
+	switch {
+	case !wasKnown:
+		w.send(t.event(Added, target, now))
+	case !wasReachable:
+		w.send(t.event(Reachable, target, now))
+	default:
+		for _, e := range changed {
+			w.send(e)
+		}
+	}
+}
+
+func (t *tracked) event(eventType Type, target string, now time.Time) Event {
+	c := t.color
+	return Event{Type: eventType, Time: now, ID: target, Label: t.label, Power: onOff(t.power), Color: &c}
+}
+
+//send gives e to every subscriber that has room for it. The mutex must be held.
+func (w *Watcher) send(e Event) {
+	for c := range w.subscribers {
+		select {
+		case c <- e:
+		default:
+			w.log().WithField("light", e.ID).Warnf("subscriber fell behind, dropped %v event", e.Type)
+		}
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func (w *Watcher) log() logrus.FieldLogger {
+	if w.Log == nil {
+		return logrus.StandardLogger()
+	}
+	return w.Log
+}
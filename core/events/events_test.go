@@ -0,0 +1,113 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/server"
+	"net"
+	"testing"
+	"time"
+)
+
+//next returns the next event or fails the test if none comes.
+func next(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected an event")
+		return Event{}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Label: "Kitchen", Color: hsbk.HSBK{Brightness: 0xffff, Kelvin: 3500}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, emulated := emulator.StartUp(ctx, bulb)
+	// lets the test add packets the emulator didn't send
+	in := make(chan *server.InboundPayload)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case p := <-emulated:
+				in <- p
+			}
+		}
+	}()
+	c := client.New(ctx, out, in)
+
+	w := &Watcher{Client: c, Wait: 200 * time.Millisecond, RemoveAfter: time.Minute}
+	events, unsubscribe := w.Subscribe()
+	defer unsubscribe()
+	unsubscribeClient := c.Subscribe(w.unsolicited)
+	defer unsubscribeClient()
+
+	now := time.Now()
+	w.Find(ctx)
+	w.Poll(ctx, now)
+	if e := next(t, events); e.Type != Added || e.ID != "d073d500000100" || e.Label != "Kitchen" || e.Power != "off" || e.Color == nil {
+		t.Fatalf("unexpected %+v", e)
+	}
+	if snapshot := w.Snapshot(); len(snapshot) != 1 || snapshot[0].Type != Added {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+
+	l := c.Light(bulb.Target, bulb.Address)
+	if err := l.SetPower(ctx, true, 0); err != nil {
+		t.Fatal(err)
+	}
+	w.Poll(ctx, now.Add(time.Second))
+	if e := next(t, events); e.Type != Power || e.Power != "on" {
+		t.Fatalf("unexpected %+v", e)
+	}
+	w.Poll(ctx, now.Add(2*time.Second))
+	select {
+	case e := <-events:
+		t.Fatalf("expected nothing new but got %+v", e)
+	default:
+	}
+
+	// another app changed the color and the light told it so
+	red := hsbk.HSBK{Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}
+	head := header.New(0)
+	head.SetSource(42)
+	head.SetTarget(bulb.Target)
+	head.SetFrameAddressReserved([]byte("LIFXV2"))
+	head.SetType(light.StateType)
+	state := light.State{Color: red, Power: 0xffff, Label: fields.ToLabel("Kitchen")}
+	head.SetSize(uint16(header.HeaderLen + binary.Size(state)))
+	buffer := bytes.NewBuffer([]byte{})
+	binary.Write(buffer, binary.LittleEndian, head)
+	binary.Write(buffer, binary.LittleEndian, state)
+	in <- &server.InboundPayload{Data: buffer.Bytes(), Conn: bulb.Address}
+	if e := next(t, events); e.Type != Color || *e.Color != red {
+		t.Fatalf("unexpected %+v", e)
+	}
+
+	// a light that stops answering is unreachable then removed
+	w.lights["d073d500000100"].light = c.Light(bulb.Target, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 99), Port: 56700})
+	pollCtx, done := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer done()
+	w.Poll(pollCtx, now.Add(3*time.Second))
+	if e := next(t, events); e.Type != Unreachable {
+		t.Fatalf("unexpected %+v", e)
+	}
+	w.Poll(pollCtx, now.Add(2*time.Minute))
+	if e := next(t, events); e.Type != Removed {
+		t.Fatalf("unexpected %+v", e)
+	}
+	if snapshot := w.Snapshot(); len(snapshot) != 0 {
+		t.Errorf("expected the light forgotten but got %+v", snapshot)
+	}
+}
@@ -0,0 +1,235 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/products"
+	"github.com/nathanhack/lifx/core/selector"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+//DefaultTimeout bounds the LAN requests of one scrape.
+const DefaultTimeout = 5 * time.Second
+
+//signalQuality orders the signal classes so they can be graphed and alerted on.
+var signalQuality = map[device.WifiStrength]float64{
+	device.NoSignal:    0,
+	device.VeryBad:     1,
+	device.SomewhatBad: 2,
+	device.Alright:     3,
+	device.Good:        4,
+}
+
+//Exporter serves the lights' state and wifi health in the Prometheus text format. Every scrape asks each
+//light in parallel, a light that was found once and stops answering is reported with lifx_device_up 0.
+type Exporter struct {
+	Client *client.Client
+	//Lights finds the lights without broadcasting on every scrape
+	Lights *selector.Cache
+	//Timeout bounds the LAN requests of one scrape, 0 is DefaultTimeout
+	Timeout time.Duration
+	//Log gets the lights that failed to answer, nil uses logrus' standard logger
+	Log logrus.FieldLogger
+
+	mutex   sync.Mutex
+	devices map[string]*tracked
+}
+
+//tracked is what the exporter remembers of a light between scrapes.
+type tracked struct {
+	light   *client.Light
+	label   string
+	product string
+}
+
+//reading is what one scrape got from a light, the optional parts are nil when their request failed.
+type reading struct {
+	id, label, product string
+	up                 bool
+	latency            time.Duration
+	power              bool
+	color              hsbk.HSBK
+	wifi               *device.StateWifiInfo
+	info               *device.StateInfo
+	firmware           *device.StateHostFirmware
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	// buffered so a failed scrape can still answer with an error status
+	var buffer bytes.Buffer
+	if err := e.Write(ctx, &buffer); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buffer.Bytes())
+}
+
+//Write scrapes the lights and writes the metrics.
+func (e *Exporter) Write(ctx context.Context, w io.Writer) error {
+	start := time.Now()
+	readings := e.scrape(ctx)
+
+	var (
+		up         = gauge("lifx_device_up", "Whether the light answered this scrape.")
+		info       = gauge("lifx_device_info", "The light's product and firmware version, always 1.")
+		latency    = gauge("lifx_device_request_latency_seconds", "Time the light took to answer its state request, retries included.")
+		power      = gauge("lifx_device_power", "Whether the light is on.")
+		brightness = gauge("lifx_device_brightness", "Brightness of the light from 0 to 1.")
+		kelvin     = gauge("lifx_device_kelvin", "Color temperature of the light in kelvin.")
+		signal     = gauge("lifx_device_wifi_signal", "Wifi signal as the light reports it, the RSSI in dBm when negative.")
+		quality    = gauge("lifx_device_wifi_signal_quality", "Wifi signal class from 0 (no signal) to 4 (good), the class label names it.")
+		tx         = counter("lifx_device_wifi_tx_bytes_total", "Bytes the light sent over wifi.")
+		rx         = counter("lifx_device_wifi_rx_bytes_total", "Bytes the light received over wifi.")
+		uptime     = gauge("lifx_device_uptime_seconds", "Time since the light powered up.")
+		downtime   = gauge("lifx_device_downtime_seconds", "Time the light was powered off before it last powered up.")
+		sent       = counter("lifx_client_packets_sent_total", "Packets the exporter sent, retries included.")
+		received   = counter("lifx_client_packets_received_total", "Packets the exporter received.")
+		dropped    = counter("lifx_client_packets_dropped_total", "Received packets that were malformed or answered no pending request.")
+		duration   = gauge("lifx_exporter_scrape_duration_seconds", "Time the scrape took.")
+	)
+	for _, r := range readings {
+		id := []string{"id", r.id, "label", r.label}
+		up.add(boolValue(r.up), id...)
+		if !r.up {
+			continue
+		}
+		version := ""
+		if r.firmware != nil {
+			version = fmt.Sprintf("%v.%v", r.firmware.VersionMajor, r.firmware.VersionMinor)
+		}
+		info.add(1, append(id, "product", r.product, "firmware", version)...)
+		latency.add(r.latency.Seconds(), id...)
+		power.add(boolValue(r.power), id...)
+		brightness.add(r.color.Color().Brightness, id...)
+		kelvin.add(float64(r.color.Kelvin), id...)
+		if r.wifi != nil {
+			class := r.wifi.SignalInfo()
+			signal.add(float64(r.wifi.Signal), id...)
+			quality.add(signalQuality[class], append(id, "class", string(class))...)
+			tx.add(float64(r.wifi.Tx), id...)
+			rx.add(float64(r.wifi.Rx), id...)
+		}
+		if r.info != nil {
+			uptime.add(time.Duration(r.info.Uptime).Seconds(), id...)
+			downtime.add(time.Duration(r.info.Downtime).Seconds(), id...)
+		}
+	}
+	stats := e.Client.Stats()
+	sent.add(float64(stats.Sent))
+	received.add(float64(stats.Received))
+	dropped.add(float64(stats.Dropped))
+	duration.add(time.Since(start).Seconds())
+
+	return write(w, []*metric{up, info, latency, power, brightness, kelvin, signal, quality, tx, rx, uptime,
+		downtime, sent, received, dropped, duration})
+}
+
+//scrape reads every light found now or before, sorted by id.
+func (e *Exporter) scrape(ctx context.Context) []reading {
+	lights, err := e.Lights.Lights(ctx, selector.Selector{{Field: selector.All}})
+	if err != nil {
+		// no lights this time, the known ones are still reported
+		e.log().WithError(err).Debug("discovery found nothing")
+	}
+	e.mutex.Lock()
+	if e.devices == nil {
+		e.devices = make(map[string]*tracked)
+	}
+	for _, l := range lights {
+		if _, has := e.devices[l.TargetHex()]; !has {
+			e.devices[l.TargetHex()] = &tracked{light: l}
+		}
+	}
+	devices := make([]*tracked, 0, len(e.devices))
+	for _, d := range e.devices {
+		devices = append(devices, d)
+	}
+	e.mutex.Unlock()
+
+	readings := make([]reading, len(devices))
+	var wait sync.WaitGroup
+	for i, d := range devices {
+		wait.Add(1)
+		go func(i int, d *tracked) {
+			defer wait.Done()
+			readings[i] = e.read(ctx, d)
+		}(i, d)
+	}
+	wait.Wait()
+	sort.Slice(readings, func(i, j int) bool { return readings[i].id < readings[j].id })
+	return readings
+}
+
+//read asks the light for its state first, the rest is only asked when it answers.
+func (e *Exporter) read(ctx context.Context, d *tracked) reading {
+	id := d.light.TargetHex()
+	log := e.log().WithField("light", id)
+	start := time.Now()
+	state, err := d.light.State(ctx)
+	latency := time.Since(start)
+	if err != nil {
+		log.WithError(err).Debug("no state")
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		return reading{id: id, label: d.label, product: d.product}
+	}
+
+	r := reading{id: id, label: fields.Label(state.Label), up: true, latency: latency, power: state.Power != 0, color: state.Color}
+	if r.wifi, err = d.light.WifiInfo(ctx); err != nil {
+		log.WithError(err).Debug("no wifi info")
+	}
+	if r.info, err = d.light.Info(ctx); err != nil {
+		log.WithError(err).Debug("no info")
+	}
+	if r.firmware, err = d.light.HostFirmware(ctx); err != nil {
+		log.WithError(err).Debug("no host firmware")
+	}
+	e.mutex.Lock()
+	r.product = d.product
+	e.mutex.Unlock()
+	if r.product == "" {
+		// the product doesn't change, so it is only asked for until it answers
+		if version, err := d.light.Version(ctx); err == nil {
+			p, _ := products.Lookup(version.Vendor, version.Product)
+			r.product = p.Name
+		} else {
+			log.WithError(err).Debug("no version")
+		}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	d.label, d.product = r.label, r.product
+	return r
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (e *Exporter) log() logrus.FieldLogger {
+	if e.Log == nil {
+		return logrus.StandardLogger()
+	}
+	return e.Log
+}
@@ -0,0 +1,77 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/selector"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExporter(t *testing.T) {
+	kitchen := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Label: `Kitchen "main"`, Power: 0xffff,
+		Color: hsbk.HSBK{Brightness: 0x8000, Kelvin: 3500}, Vendor: 1, Product: 27,
+		FirmwareMajor: 3, FirmwareMinor: 70, Signal: -65, Tx: 1200, Rx: 3400, Downtime: 90 * time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, kitchen)
+	c := client.New(ctx, out, in)
+	e := &Exporter{Client: c, Lights: &selector.Cache{Client: c, Wait: 200 * time.Millisecond}, Timeout: time.Second}
+	// found by an earlier scrape and gone since
+	gone := c.Light([]byte{0xd0, 0x73, 0xd5, 0, 0, 2, 0}, kitchen.Address)
+	e.devices = map[string]*tracked{gone.TargetHex(): {light: gone, label: "Porch", product: "LIFX Mini"}}
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected %v %v", w.Code, w.Header())
+	}
+	body := w.Body.String()
+	kitchenID := `id="d073d500000100",label="Kitchen \"main\""`
+	for _, line := range []string{
+		"# TYPE lifx_device_up gauge",
+		"lifx_device_up{" + kitchenID + "} 1",
+		`lifx_device_up{id="d073d500000200",label="Porch"} 0`,
+		"lifx_device_info{" + kitchenID + `,product="LIFX A19",firmware="3.70"} 1`,
+		"lifx_device_power{" + kitchenID + "} 1",
+		"lifx_device_kelvin{" + kitchenID + "} 3500",
+		"lifx_device_wifi_signal{" + kitchenID + "} -65",
+		"lifx_device_wifi_signal_quality{" + kitchenID + `,class="Alright signal"} 3`,
+		"# TYPE lifx_device_wifi_tx_bytes_total counter",
+		"lifx_device_wifi_tx_bytes_total{" + kitchenID + "} 1200",
+		"lifx_device_wifi_rx_bytes_total{" + kitchenID + "} 3400",
+		"# TYPE lifx_device_downtime_seconds gauge",
+		"lifx_device_downtime_seconds{" + kitchenID + "} 90",
+		"# TYPE lifx_client_packets_sent_total counter",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected %q in\n%v", line, body)
+		}
+	}
+	if strings.Contains(body, `lifx_device_power{id="d073d500000200"`) {
+		t.Errorf("expected only lifx_device_up for the light that is gone in\n%v", body)
+	}
+	if !strings.Contains(body, "lifx_device_brightness{"+kitchenID+"} 0.5") {
+		t.Errorf("expected the brightness in\n%v", body)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	m := gauge("test_value", "A value\nover two lines.")
+	m.add(1.5, "name", `a\b`)
+	m.add(2)
+	var out bytes.Buffer
+	if err := write(&out, []*metric{m, counter("test_empty_total", "Left out.")}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "# HELP test_value A value\\nover two lines.\n# TYPE test_value gauge\ntest_value{name=\"a\\\\b\"} 1.5\ntest_value 2\n"
+	if out.String() != expected {
+		t.Errorf("expected %q but got %q", expected, out.String())
+	}
+}
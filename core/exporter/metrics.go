@@ -0,0 +1,79 @@
+package exporter
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//metric is one family of the Prometheus text exposition format.
+type metric struct {
+	name    string
+	help    string
+	kind    string
+	samples []sample
+}
+
+//sample is one line of a family, labels holds name and value pairs.
+type sample struct {
+	labels []string
+	value  float64
+}
+
+func gauge(name, help string) *metric {
+	return &metric{name: name, help: help, kind: "gauge"}
+}
+
+func counter(name, help string) *metric {
+	return &metric{name: name, help: help, kind: "counter"}
+}
+
+func (m *metric) add(value float64, labels ...string) {
+	m.samples = append(m.samples, sample{labels: labels, value: value})
+}
+
+//write writes the families in text format version 0.0.4, leaving out the ones without samples.
+func write(w io.Writer, metrics []*metric) error {
+	out := bufio.NewWriter(w)
+	for _, m := range metrics {
+		if len(m.samples) == 0 {
+			continue
+		}
+		out.WriteString("# HELP " + m.name + " " + helpEscaper.Replace(m.help) + "\n")
+		out.WriteString("# TYPE " + m.name + " " + m.kind + "\n")
+		for _, s := range m.samples {
+			out.WriteString(m.name)
+			if len(s.labels) > 0 {
+				out.WriteByte('{')
+				for i := 0; i+1 < len(s.labels); i += 2 {
+					if i > 0 {
+						out.WriteByte(',')
+					}
+					out.WriteString(s.labels[i] + `="` + labelEscaper.Replace(s.labels[i+1]) + `"`)
+				}
+				out.WriteByte('}')
+			}
+			out.WriteString(" " + formatValue(s.value) + "\n")
+		}
+	}
+	return out.Flush()
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -17,6 +17,9 @@ func Decode(bytes []byte) (*Header, error) {
 	}
 
 	h := Header(bytes)
+	if size := int(h.Size()); size < HeaderLen || size > len(bytes) {
+		return nil, fmt.Errorf("header size %v does not fit the %v bytes found", size, len(bytes))
+	}
 	return &h, nil
 }
 
@@ -30,8 +33,17 @@ func New(sequence byte) *Header {
 	return &head
 }
 
+//at is the byte at i, reading past the end of a short header gives zero like an unset field. The setters
+//need a header of HeaderLen bytes, which New and Decode give.
+func (h Header) at(i int) byte {
+	if i < len(h) {
+		return h[i]
+	}
+	return 0
+}
+
 func (h Header) Size() uint16 {
-	return uint16(h[0]) + (uint16(h[1]))<<8
+	return uint16(h.at(0)) + (uint16(h.at(1)))<<8
 }
 
 func (h Header) SetSize(size uint16) {
@@ -40,7 +52,7 @@ func (h Header) SetSize(size uint16) {
 }
 
 func (h Header) Protocol() uint16 {
-	return uint16(h[2]) + (uint16(h[3])&0xf)<<8
+	return uint16(h.at(2)) + (uint16(h.at(3))&0xf)<<8
 }
 
 func (h Header) SetProtocol(protocol uint16) {
@@ -49,7 +61,7 @@ func (h Header) SetProtocol(protocol uint16) {
 }
 
 func (h Header) Addressable() bool {
-	return h[3]&(1<<4) > 0
+	return h.at(3)&(1<<4) > 0
 }
 
 func (h Header) SetAddressable(addressable bool) {
@@ -61,7 +73,7 @@ func (h Header) SetAddressable(addressable bool) {
 }
 
 func (h Header) Tagged() bool {
-	return h[3]&(1<<5) > 0
+	return h.at(3)&(1<<5) > 0
 }
 
 func (h Header) SetTagged(tagged bool) {
@@ -74,7 +86,7 @@ func (h Header) SetTagged(tagged bool) {
 
 func (h Header) Origin() byte {
 	//origin must aways be zero
-	return h[3] >> 6
+	return h.at(3) >> 6
 }
 
 func (h Header) SetOrigin(origin byte) {
@@ -82,7 +94,7 @@ func (h Header) SetOrigin(origin byte) {
 }
 
 func (h Header) Source() uint32 {
-	return uint32(h[4]) + uint32(h[5])<<8 + uint32(h[6])<<16 + uint32(h[7])<<24
+	return uint32(h.at(4)) + uint32(h.at(5))<<8 + uint32(h.at(6))<<16 + uint32(h.at(7))<<24
 }
 
 func (h Header) SetSource(source uint32) {
@@ -93,6 +105,9 @@ func (h Header) SetSource(source uint32) {
 }
 
 func (h Header) Target() []byte {
+	if len(h) < 15 {
+		return make([]byte, 7)
+	}
 	return h[8:15]
 }
 
@@ -108,8 +123,13 @@ func (h Header) FrameAddressReservedReset() {
 	copy(h[16:21], []byte{0, 0, 0, 0, 0, 0})
 }
 
+//SetFrameAddressReserved fills the reserved frame address bytes, devices answer with "LIFXV2" here.
+func (h Header) SetFrameAddressReserved(reserved []byte) {
+	copy(h[16:22], reserved)
+}
+
 func (h Header) ResponseRequired() bool {
-	return h[22]&0x01 > 0
+	return h.at(22)&0x01 > 0
 }
 
 func (h Header) SetResponseRequired(required bool) {
@@ -121,7 +141,7 @@ func (h Header) SetResponseRequired(required bool) {
 }
 
 func (h Header) AcknowledgementRequired() bool {
-	return h[22]&0b10 > 0
+	return h.at(22)&0b10 > 0
 }
 
 func (h Header) SetAcknowledgementRequired(required bool) {
@@ -133,7 +153,7 @@ func (h Header) SetAcknowledgementRequired(required bool) {
 }
 
 func (h Header) Sequence() byte {
-	return h[23]
+	return h.at(23)
 }
 
 func (h Header) SetSequence(seq byte) {
@@ -141,7 +161,7 @@ func (h Header) SetSequence(seq byte) {
 }
 
 func (h Header) Type() uint16 {
-	return uint16(h[32]) + uint16(h[33])<<8
+	return uint16(h.at(32)) + uint16(h.at(33))<<8
 }
 
 func (h Header) SetType(packetType uint16) {
@@ -151,6 +171,9 @@ func (h Header) SetType(packetType uint16) {
 
 func (h Header) Validate(filterSenders bool) bool {
 	//weak but better than nothing
+	if len(h) < HeaderLen {
+		return false
+	}
 	l := h[16:22]
 	zeros := reflect.DeepEqual([]byte{0, 0, 0, 0, 0, 0}, l)
 	s := string(l)
@@ -159,6 +182,9 @@ func (h Header) Validate(filterSenders bool) bool {
 }
 
 func (h Header) Data() []byte {
+	if len(h) < HeaderLen {
+		return nil
+	}
 	return h[HeaderLen:]
 }
 
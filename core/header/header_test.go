@@ -1,12 +1,13 @@
 package header
 
 import (
+	"encoding/hex"
 	"fmt"
 	"testing"
 )
 
 func TestHeader_SetOrigin(t *testing.T) {
-	h := Header{}
+	h := Header(make([]byte, HeaderLen))
 	if h.Origin() != 0 {
 		t.Errorf("expect 0 but got %v", h.Origin())
 	}
@@ -20,7 +21,7 @@ func TestHeader_SetOrigin(t *testing.T) {
 }
 
 func TestHeader_Tagged(t *testing.T) {
-	h := Header{}
+	h := Header(make([]byte, HeaderLen))
 	if h.Tagged() {
 		t.Errorf("expect false but got %v", h.Tagged())
 	}
@@ -35,7 +36,7 @@ func TestHeader_Tagged(t *testing.T) {
 }
 
 func TestHeader_Addressable(t *testing.T) {
-	h := Header{}
+	h := Header(make([]byte, HeaderLen))
 	if h.Addressable() {
 		t.Errorf("expect false but got %v", h.Addressable())
 	}
@@ -43,8 +44,72 @@ func TestHeader_Addressable(t *testing.T) {
 	if !h.Addressable() {
 		t.Errorf("expect true but got %v", h.Addressable())
 	}
-	if h[3] != 0x20 {
-		t.Errorf("expect 0x20 but got 0x%02x", h[3])
+	if h[3] != 0x10 {
+		t.Errorf("expect 0x10 but got 0x%02x", h[3])
 	}
 	fmt.Printf("%x", h)
 }
+
+func TestHeader_Short(t *testing.T) {
+	// the getters read a truncated header as zeros instead of panicking
+	for _, h := range []Header{{}, {0x24, 0x00, 0x00, 0x34}} {
+		if h.Origin() != 0 || h.Source() != 0 || h.Sequence() != 0 || h.Type() != 0 || h.Validate(false) || h.Data() != nil {
+			t.Errorf("expect zeros from %x but got %v", []byte(h), h)
+		}
+		if len(h.Target()) != 7 {
+			t.Errorf("expect a 7 byte target but got %x", h.Target())
+		}
+	}
+}
+
+//packets captured from a bulb (requests and the responses to them)
+var seedPackets = []string{
+	"2400003400000000000000000000000000000000000000000000000000000000020000000000",
+	"2400001478563412d073d512345600000000000000000007000000000000000002000000",
+	"2900001478563412d073d512345600004c49465856320007000000000000000003000000017cdd0000",
+	"4400001478563412d073d512345600004c494658563200070000000000000000190000004b69746368656e00000000000000000000000000000000000000000000000000",
+	"5800001478563412d073d512345600004c4946585632000700000000000000006b0000005555ffff0080ac0d0000ffff4b69746368656e000000000000000000000000000000000000000000000000000000000000000000",
+	"3100003400000000000000000000000000000000000000000000000000000000660000000055550000ffffffffac0d00040000",
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, s := range seedPackets {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h, err := Decode(data)
+		if err != nil {
+			return
+		}
+		if int(h.Size()) > len(data) {
+			t.Errorf("expect size %v to fit in %v bytes", h.Size(), len(data))
+		}
+		_ = h.String()
+		_ = h.TargetHex()
+		_ = h.Validate(true)
+		_ = h.Validate(false)
+		_ = h.Data()
+
+		// setters must round trip on anything Decode accepts
+		h.SetOrigin(0)
+		h.SetTagged(!h.Tagged())
+		h.SetAddressable(true)
+		if !h.Addressable() {
+			t.Errorf("expect addressable after SetAddressable(true)")
+		}
+		h.SetSequence(7)
+		if h.Sequence() != 7 {
+			t.Errorf("expect 7 but got %v", h.Sequence())
+		}
+		h.SetType(0x1234)
+		if h.Type() != 0x1234 {
+			t.Errorf("expect 0x1234 but got 0x%x", h.Type())
+		}
+	})
+}
@@ -65,10 +65,10 @@ func (g GetHostInfo) RequiredHeader(h *header.Header) {
 }
 
 type StateHostInfo struct {
-	Signal   float32
-	Tx       uint32
-	Rx       uint32
-	reserved int16
+	Signal float32
+	Tx     uint32
+	Rx     uint32
+	_      int16 // reserved
 }
 
 type GetHostFirmware [0]byte
@@ -80,7 +80,7 @@ func (g GetHostFirmware) RequiredHeader(h *header.Header) {
 
 type StateHostFirmware struct {
 	Build        uint64
-	reserved     uint64
+	_            uint64 // reserved
 	VersionMinor uint64
 	VersionMajor uint64
 }
@@ -102,10 +102,10 @@ const (
 )
 
 type StateWifiInfo struct {
-	Signal   float32
-	Tx       uint32
-	Rx       uint32
-	reserved int16
+	Signal float32
+	Tx     uint32
+	Rx     uint32
+	_      int16 // reserved
 }
 
 func (s StateWifiInfo) SignalInfo() WifiStrength {
@@ -282,106 +282,49 @@ type EchoRequest [64]byte
 
 type EchoResponse [64]byte
 
+//DecodeFromHeader decodes the payload of h into message, which must be a pointer to the type matching h.Type().
 func DecodeFromHeader(h header.Header, message interface{}) error {
+	var ok bool
 	switch h.Type() {
 	case StateServiceType:
-		if s, ok := message.(*StateService); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StateService)
 	case StateHostInfoType:
-		if s, ok := message.(*StateHostInfo); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StateHostInfo)
 	case StateHostFirmwareType:
-		if s, ok := message.(*StateHostFirmware); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StateHostFirmware)
 	case StateWifiInfoType:
-		if s, ok := message.(*StateWifiInfo); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StateWifiInfo)
 	case StateWifiFirmwareType:
-		if s, ok := message.(*StateWifiFirmware); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StateWifiFirmware)
+	case SetPowerType:
+		_, ok = message.(*SetPower)
 	case StatePowerType:
-		if s, ok := message.(*StatePower); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StatePower)
+	case SetLabelType:
+		_, ok = message.(*SetLabel)
 	case StateLabelType:
-		if s, ok := message.(*StateLabel); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StateLabel)
 	case StateVersionType:
-		if s, ok := message.(*StateVersion); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StateVersion)
 	case StateInfoType:
-		if s, ok := message.(*StateInfo); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StateInfo)
+	case SetLocationType:
+		_, ok = message.(*SetLocation)
 	case StateLocationType:
-		if s, ok := message.(*StateLocation); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StateLocation)
+	case SetGroupType:
+		_, ok = message.(*SetGroup)
 	case StateGroupType:
-		if s, ok := message.(*StateGroup); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StateGroup)
+	case EchoRequestType:
+		_, ok = message.(*EchoRequest)
 	case EchoResponseType:
-		if s, ok := message.(*EchoResponse); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*EchoResponse)
 	default:
 		return fmt.Errorf("not supported")
 	}
-	return nil
+	if !ok {
+		return fmt.Errorf("type %v can not be decoded into %T", h.Type(), message)
+	}
+	return binary.Read(bytes.NewBuffer(h.Data()), binary.LittleEndian, message)
 }
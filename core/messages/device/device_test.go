@@ -0,0 +1,102 @@
+package device
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"gopkg.in/yaml.v2"
+	"testing"
+	"time"
+)
+
+func FuzzDecodeFromHeader(f *testing.F) {
+	for _, s := range []string{
+		"2900001478563412d073d512345600004c49465856320007000000000000000003000000017cdd0000",
+		"2600001478563412d073d512345600004c49465856320007000000000000000016000000ffff",
+		"4400001478563412d073d512345600004c494658563200070000000000000000190000004b69746368656e00000000000000000000000000000000000000000000000000",
+		"3000001478563412d073d512345600004c49465856320007000000000000000021000000010000001b00000000000000",
+		"5c00001478563412d073d512345600004c494658563200070000000000000000350000000102030405060708090a0b0c0d0e0f10557073746169727300000000000000000000000000000000000000000000000000008ab9359ae515",
+		"3200001478563412d073d512345600004c49465856320007000000000000000011000000000070c200100000002000000000",
+	} {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h, err := header.Decode(data)
+		if err != nil {
+			return
+		}
+		messages := []interface{}{
+			&StateService{}, &StateHostInfo{}, &StateHostFirmware{}, &StateWifiInfo{}, &StateWifiFirmware{},
+			&SetPower{}, &StatePower{}, &SetLabel{}, &StateLabel{}, &StateVersion{}, &StateInfo{},
+			&SetLocation{}, &StateLocation{}, &SetGroup{}, &StateGroup{}, &EchoRequest{}, &EchoResponse{},
+		}
+		decoded := 0
+		for _, m := range messages {
+			if DecodeFromHeader(*h, m) == nil {
+				decoded++
+			}
+		}
+		if decoded > 1 {
+			t.Errorf("expect type %v to decode into at most one message but got %v", h.Type(), decoded)
+		}
+
+		var label StateLabel
+		if DecodeFromHeader(*h, &label) == nil {
+			_ = label.String()
+		}
+		var wifi StateWifiInfo
+		if DecodeFromHeader(*h, &wifi) == nil {
+			_ = wifi.SignalInfo()
+		}
+	})
+}
+
+func TestStateGroup_MarshalJSON(t *testing.T) {
+	s := StateGroup{
+		Group:     [16]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+		Label:     fields.ToLabel("Upstairs"),
+		UpdatedAt: 1577836800123456789,
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"group":"01020304-0506-0708-090a-0b0c0d0e0f10","label":"Upstairs","updated_at":"2020-01-01T00:00:00.123456789Z"}`
+	if string(b) != expected {
+		t.Errorf("expect %v but got %v", expected, string(b))
+	}
+
+	var back StateGroup
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back != s {
+		t.Errorf("expect %v but got %v", s, back)
+	}
+}
+
+func TestStateInfo_MarshalYAML(t *testing.T) {
+	s := StateInfo{Time: 1577836800000000000, Uptime: uint64(90 * time.Minute)}
+	b, err := yaml.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "time: 2020-01-01T00:00:00Z\nuptime: 1h30m0s\ndowntime: 0s\n"
+	if string(b) != expected {
+		t.Errorf("expect %q but got %q", expected, string(b))
+	}
+
+	var back StateInfo
+	if err := yaml.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back != s {
+		t.Errorf("expect %v but got %v", s, back)
+	}
+}
@@ -0,0 +1,379 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"time"
+)
+
+type stateServiceJSON struct {
+	Service byte   `json:"service" yaml:"service"`
+	Port    uint32 `json:"port" yaml:"port"`
+}
+
+func (s StateService) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stateServiceJSON(s))
+}
+
+func (s *StateService) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*stateServiceJSON)(s))
+}
+
+func (s StateService) MarshalYAML() (interface{}, error) {
+	return stateServiceJSON(s), nil
+}
+
+func (s *StateService) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return unmarshal((*stateServiceJSON)(s))
+}
+
+type stateHostInfoJSON struct {
+	Signal float32 `json:"signal" yaml:"signal"`
+	Tx     uint32  `json:"tx" yaml:"tx"`
+	Rx     uint32  `json:"rx" yaml:"rx"`
+}
+
+func (s StateHostInfo) toJSON() stateHostInfoJSON {
+	return stateHostInfoJSON{Signal: s.Signal, Tx: s.Tx, Rx: s.Rx}
+}
+
+func (s *StateHostInfo) fromJSON(j stateHostInfoJSON) {
+	s.Signal, s.Tx, s.Rx = j.Signal, j.Tx, j.Rx
+}
+
+func (s StateHostInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+func (s *StateHostInfo) UnmarshalJSON(data []byte) error {
+	var j stateHostInfoJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	s.fromJSON(j)
+	return nil
+}
+
+func (s StateHostInfo) MarshalYAML() (interface{}, error) {
+	return s.toJSON(), nil
+}
+
+func (s *StateHostInfo) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j stateHostInfoJSON
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	s.fromJSON(j)
+	return nil
+}
+
+//stateWifiInfoJSON also carries the signal classification so consumers don't have to redo SignalInfo
+type stateWifiInfoJSON struct {
+	Signal     float32      `json:"signal" yaml:"signal"`
+	SignalInfo WifiStrength `json:"signal_info" yaml:"signal_info"`
+	Tx         uint32       `json:"tx" yaml:"tx"`
+	Rx         uint32       `json:"rx" yaml:"rx"`
+}
+
+func (s StateWifiInfo) toJSON() stateWifiInfoJSON {
+	return stateWifiInfoJSON{Signal: s.Signal, SignalInfo: s.SignalInfo(), Tx: s.Tx, Rx: s.Rx}
+}
+
+func (s *StateWifiInfo) fromJSON(j stateWifiInfoJSON) {
+	s.Signal, s.Tx, s.Rx = j.Signal, j.Tx, j.Rx
+}
+
+func (s StateWifiInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+func (s *StateWifiInfo) UnmarshalJSON(data []byte) error {
+	var j stateWifiInfoJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	s.fromJSON(j)
+	return nil
+}
+
+func (s StateWifiInfo) MarshalYAML() (interface{}, error) {
+	return s.toJSON(), nil
+}
+
+func (s *StateWifiInfo) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j stateWifiInfoJSON
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	s.fromJSON(j)
+	return nil
+}
+
+type stateHostFirmwareJSON struct {
+	Build   time.Time `json:"build" yaml:"build"`
+	Version string    `json:"version" yaml:"version"`
+}
+
+func (s StateHostFirmware) toJSON() stateHostFirmwareJSON {
+	return stateHostFirmwareJSON{
+		Build:   fields.Time(s.Build),
+		Version: fmt.Sprintf("%v.%v", s.VersionMajor, s.VersionMinor),
+	}
+}
+
+func (s *StateHostFirmware) fromJSON(j stateHostFirmwareJSON) error {
+	s.Build = fields.Nanos(j.Build)
+	if _, err := fmt.Sscanf(j.Version, "%d.%d", &s.VersionMajor, &s.VersionMinor); err != nil {
+		return fmt.Errorf("parsing firmware version %q: %v", j.Version, err)
+	}
+	return nil
+}
+
+func (s StateHostFirmware) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+func (s *StateHostFirmware) UnmarshalJSON(data []byte) error {
+	var j stateHostFirmwareJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	return s.fromJSON(j)
+}
+
+func (s StateHostFirmware) MarshalYAML() (interface{}, error) {
+	return s.toJSON(), nil
+}
+
+func (s *StateHostFirmware) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j stateHostFirmwareJSON
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	return s.fromJSON(j)
+}
+
+type statePowerJSON struct {
+	Power string `json:"power" yaml:"power"`
+}
+
+func (s StatePower) MarshalJSON() ([]byte, error) {
+	return json.Marshal(statePowerJSON{Power: fields.Power(s.Level)})
+}
+
+func (s *StatePower) UnmarshalJSON(data []byte) (err error) {
+	var j statePowerJSON
+	if err = json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	s.Level, err = fields.ParsePower(j.Power)
+	return
+}
+
+func (s StatePower) MarshalYAML() (interface{}, error) {
+	return statePowerJSON{Power: fields.Power(s.Level)}, nil
+}
+
+func (s *StatePower) UnmarshalYAML(unmarshal func(interface{}) error) (err error) {
+	var j statePowerJSON
+	if err = unmarshal(&j); err != nil {
+		return err
+	}
+	s.Level, err = fields.ParsePower(j.Power)
+	return
+}
+
+type stateLabelJSON struct {
+	Label string `json:"label" yaml:"label"`
+}
+
+func (l StateLabel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stateLabelJSON{Label: fields.Label(l.Label)})
+}
+
+func (l *StateLabel) UnmarshalJSON(data []byte) error {
+	var j stateLabelJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	l.Label = fields.ToLabel(j.Label)
+	return nil
+}
+
+func (l StateLabel) MarshalYAML() (interface{}, error) {
+	return stateLabelJSON{Label: fields.Label(l.Label)}, nil
+}
+
+func (l *StateLabel) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j stateLabelJSON
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	l.Label = fields.ToLabel(j.Label)
+	return nil
+}
+
+type stateVersionJSON struct {
+	Vendor  uint32 `json:"vendor" yaml:"vendor"`
+	Product uint32 `json:"product" yaml:"product"`
+	Version uint32 `json:"version" yaml:"version"`
+}
+
+func (s StateVersion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stateVersionJSON(s))
+}
+
+func (s *StateVersion) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*stateVersionJSON)(s))
+}
+
+func (s StateVersion) MarshalYAML() (interface{}, error) {
+	return stateVersionJSON(s), nil
+}
+
+func (s *StateVersion) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return unmarshal((*stateVersionJSON)(s))
+}
+
+//stateInfoJSON shows uptime and downtime as durations ("1h2m3s")
+type stateInfoJSON struct {
+	Time     time.Time `json:"time" yaml:"time"`
+	Uptime   string    `json:"uptime" yaml:"uptime"`
+	Downtime string    `json:"downtime" yaml:"downtime"`
+}
+
+func (s StateInfo) toJSON() stateInfoJSON {
+	return stateInfoJSON{
+		Time:     fields.Time(s.Time),
+		Uptime:   time.Duration(s.Uptime).String(),
+		Downtime: time.Duration(s.Downtime).String(),
+	}
+}
+
+func (s *StateInfo) fromJSON(j stateInfoJSON) error {
+	uptime, err := time.ParseDuration(j.Uptime)
+	if err != nil {
+		return err
+	}
+	downtime, err := time.ParseDuration(j.Downtime)
+	if err != nil {
+		return err
+	}
+	s.Time, s.Uptime, s.Downtime = fields.Nanos(j.Time), uint64(uptime), uint64(downtime)
+	return nil
+}
+
+func (s StateInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+func (s *StateInfo) UnmarshalJSON(data []byte) error {
+	var j stateInfoJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	return s.fromJSON(j)
+}
+
+func (s StateInfo) MarshalYAML() (interface{}, error) {
+	return s.toJSON(), nil
+}
+
+func (s *StateInfo) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j stateInfoJSON
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	return s.fromJSON(j)
+}
+
+type stateLocationJSON struct {
+	Location  string    `json:"location" yaml:"location"`
+	Label     string    `json:"label" yaml:"label"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+func (s StateLocation) toJSON() stateLocationJSON {
+	return stateLocationJSON{
+		Location:  fields.UUID(s.Location),
+		Label:     fields.Label(s.Label),
+		UpdatedAt: fields.Time(s.UpdatedAt),
+	}
+}
+
+func (s *StateLocation) fromJSON(j stateLocationJSON) (err error) {
+	s.Location, err = fields.ParseUUID(j.Location)
+	s.Label = fields.ToLabel(j.Label)
+	s.UpdatedAt = fields.Nanos(j.UpdatedAt)
+	return
+}
+
+func (s StateLocation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+func (s *StateLocation) UnmarshalJSON(data []byte) error {
+	var j stateLocationJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	return s.fromJSON(j)
+}
+
+func (s StateLocation) MarshalYAML() (interface{}, error) {
+	return s.toJSON(), nil
+}
+
+func (s *StateLocation) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j stateLocationJSON
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	return s.fromJSON(j)
+}
+
+type stateGroupJSON struct {
+	Group     string    `json:"group" yaml:"group"`
+	Label     string    `json:"label" yaml:"label"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+func (s StateGroup) toJSON() stateGroupJSON {
+	return stateGroupJSON{
+		Group:     fields.UUID(s.Group),
+		Label:     fields.Label(s.Label),
+		UpdatedAt: fields.Time(s.UpdatedAt),
+	}
+}
+
+func (s *StateGroup) fromJSON(j stateGroupJSON) (err error) {
+	s.Group, err = fields.ParseUUID(j.Group)
+	s.Label = fields.ToLabel(j.Label)
+	s.UpdatedAt = fields.Nanos(j.UpdatedAt)
+	return
+}
+
+func (s StateGroup) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+func (s *StateGroup) UnmarshalJSON(data []byte) error {
+	var j stateGroupJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	return s.fromJSON(j)
+}
+
+func (s StateGroup) MarshalYAML() (interface{}, error) {
+	return s.toJSON(), nil
+}
+
+func (s *StateGroup) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j stateGroupJSON
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	return s.fromJSON(j)
+}
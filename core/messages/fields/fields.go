@@ -0,0 +1,76 @@
+package fields
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//Label converts a fixed size label field to a string, dropping the NUL padding.
+func Label(label [32]byte) string {
+	if end := bytes.IndexByte(label[:], 0); end >= 0 {
+		return string(label[:end])
+	}
+	return string(label[:])
+}
+
+//ToLabel converts a string into a fixed size label field, truncating anything over 32 bytes.
+func ToLabel(label string) (result [32]byte) {
+	copy(result[:], label)
+	return
+}
+
+//UUID formats a location or group id the canonical way (8-4-4-4-12 lowercase hex).
+func UUID(id [16]byte) string {
+	s := hex.EncodeToString(id[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+//ParseUUID parses a location or group id with or without dashes.
+func ParseUUID(s string) (id [16]byte, err error) {
+	raw := strings.Replace(s, "-", "", -1)
+	if len(raw) != 32 {
+		return id, fmt.Errorf("expected 32 hex digits in uuid %q", s)
+	}
+	b, err := hex.DecodeString(raw)
+	if err != nil {
+		return id, fmt.Errorf("parsing uuid %q: %v", s, err)
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+//Time converts a nanoseconds since epoch field to a UTC time.
+func Time(nanos uint64) time.Time {
+	return time.Unix(0, int64(nanos)).UTC()
+}
+
+//Nanos converts a time to a nanoseconds since epoch field.
+func Nanos(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+//Power shows a power level as "on" or "off" like the LIFX HTTP API does.
+func Power(level uint16) string {
+	if level == 0xffff {
+		return "on"
+	}
+	return "off"
+}
+
+//ParsePower is the inverse of Power.
+func ParsePower(power string) (uint16, error) {
+	switch power {
+	case "on":
+		return 0xffff, nil
+	case "off":
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("expected power on or off found %q", power)
+	}
+}
@@ -0,0 +1,132 @@
+package hsbk
+
+import "math"
+
+//Interpolator returns the color t of the way from a to b, t is in [0,1].
+type Interpolator func(a, b HSBK, t float64) HSBK
+
+//Lerp interpolates each field linearly, the hue going the short way around the color wheel. When one
+//end has no saturation or brightness its hue means nothing, so the other end's hue is used instead of
+//sweeping through the rainbow.
+func Lerp(a, b HSBK, t float64) HSBK {
+	if t <= 0 {
+		return a
+	}
+	if t >= 1 {
+		return b
+	}
+	ca, cb := a.Color(), b.Color()
+	if ca.Saturation == 0 || ca.Brightness == 0 {
+		ca.Hue = cb.Hue
+	} else if cb.Saturation == 0 || cb.Brightness == 0 {
+		cb.Hue = ca.Hue
+	}
+
+	return Color{
+		Hue:        lerpHue(ca.Hue, cb.Hue, t),
+		Saturation: lerp(ca.Saturation, cb.Saturation, t),
+		Brightness: lerp(ca.Brightness, cb.Brightness, t),
+		Kelvin:     float64(MixKelvin(a.Kelvin, b.Kelvin, t)),
+	}.HSBK()
+}
+
+//Blend interpolates in the OKLab color space where equal steps look like equal changes, so a fade between
+//opposite colors is muted in the middle instead of sweeping around the color wheel. The kelvin is mixed
+//with MixKelvin.
+func Blend(a, b HSBK, t float64) HSBK {
+	if t <= 0 {
+		return a
+	}
+	if t >= 1 {
+		return b
+	}
+	ca, cb := a.Color(), b.Color()
+	la, aa, ba := oklab(ca)
+	lb, ab, bb := oklab(cb)
+
+	c := fromOklab(lerp(la, lb, t), lerp(aa, ab, t), lerp(ba, bb, t))
+	if c.Saturation < 1e-6 {
+		// gray has no hue, keep the one the ends agree on
+		c.Hue = Degrees(Lerp(a, b, t).Hue)
+	}
+	c.Kelvin = float64(MixKelvin(a.Kelvin, b.Kelvin, t))
+	return c.HSBK()
+}
+
+//MixKelvin interpolates whites in mireds, which is closer to how different they look than kelvin. A zero
+//kelvin takes the other one.
+func MixKelvin(a, b uint16, t float64) uint16 {
+	if a == 0 || t >= 1 {
+		return b
+	}
+	if b == 0 || t <= 0 {
+		return a
+	}
+	mired := lerp(KelvinToMired(float64(a)), KelvinToMired(float64(b)), t)
+	return uint16(math.Round(MiredToKelvin(mired)))
+}
+
+//Gradient returns n colors evenly spread over the stops, the first and last being the first and last
+//stop. A nil interpolate uses Lerp.
+func Gradient(n int, interpolate Interpolator, stops ...HSBK) []HSBK {
+	if n <= 0 || len(stops) == 0 {
+		return []HSBK{}
+	}
+	if interpolate == nil {
+		interpolate = Lerp
+	}
+	colors := make([]HSBK, n)
+	if n == 1 || len(stops) == 1 {
+		for i := range colors {
+			colors[i] = stops[0]
+		}
+		if n > 1 {
+			colors[n-1] = stops[len(stops)-1]
+		}
+		return colors
+	}
+
+	segments := float64(len(stops) - 1)
+	for i := range colors {
+		position := float64(i) / float64(n-1) * segments
+		segment := int(math.Min(math.Floor(position), segments-1))
+		colors[i] = interpolate(stops[segment], stops[segment+1], position-float64(segment))
+	}
+	return colors
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+//lerpHue goes from a to b in degrees the short way around.
+func lerpHue(a, b, t float64) float64 {
+	delta := math.Mod(b-a+540, 360) - 180
+	return math.Mod(a+delta*t+360, 360)
+}
+
+//oklab ignores the kelvin, white is sRGB white, so that only hue, saturation and brightness are blended.
+func oklab(c Color) (L, a, b float64) {
+	r, g, bl := hueToRGB(c.Hue)
+	s, v := clamp01(c.Saturation), clamp01(c.Brightness)
+	r, g, bl = linear(v*(s*r+1-s)), linear(v*(s*g+1-s)), linear(v*(s*bl+1-s))
+
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*bl)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*bl)
+	sh := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*bl)
+	return 0.2104542553*l + 0.7936177850*m - 0.0040720468*sh,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*sh,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*sh
+}
+
+func fromOklab(L, a, b float64) Color {
+	l := L + 0.3963377774*a + 0.2158037573*b
+	m := L - 0.1055613458*a - 0.0638541728*b
+	s := L - 0.0894841775*a - 1.2914855480*b
+	l, m, s = l*l*l, m*m*m, s*s*s
+
+	r := 4.0767416621*l - 3.3077115913*m + 0.2309699292*s
+	g := -1.2684380046*l + 2.6097574011*m - 0.3413193965*s
+	bl := -0.0041960863*l - 0.7034186147*m + 1.7076147010*s
+	return fromRGB(gamma(clamp01(r)), gamma(clamp01(g)), gamma(clamp01(bl)))
+}
@@ -0,0 +1,88 @@
+package hsbk
+
+import (
+	"math"
+	"testing"
+)
+
+var (
+	red    = HSBK{Hue: 0, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}
+	blue   = HSBK{Hue: 0xaaaa, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}
+	yellow = HSBK{Hue: 0x2aaa, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}
+)
+
+func TestLerp(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     HSBK
+		t        float64
+		expected HSBK
+	}{
+		{"start", red, blue, 0, red},
+		{"end", red, blue, 1, blue},
+		// red to blue is shorter going down through magenta (300) than up through green
+		{"short arc", red, blue, 0.5, HSBK{Hue: 0xd555, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}},
+		{"brightness", HSBK{Brightness: 0, Kelvin: 3500}, HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}, 0.25, HSBK{Hue: 0x5555, Saturation: 0x4000, Brightness: 0x4000, Kelvin: 3500}},
+		{"white keeps hue", HSBK{Hue: 0x1234, Saturation: 0, Brightness: 0xffff, Kelvin: 3500}, blue, 0.5, HSBK{Hue: 0xaaaa, Saturation: 0x8000, Brightness: 0xffff, Kelvin: 3500}},
+		{"kelvin", HSBK{Brightness: 0xffff, Kelvin: 2500}, HSBK{Brightness: 0xffff, Kelvin: 9000}, 0.5, HSBK{Brightness: 0xffff, Kelvin: 3913}},
+	}
+	for _, test := range tests {
+		if actual := Lerp(test.a, test.b, test.t); actual != test.expected {
+			t.Errorf("%v: expected %#v but got %#v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestBlend(t *testing.T) {
+	if Blend(red, blue, 0) != red || Blend(red, blue, 1) != blue {
+		t.Errorf("expected the ends to be exact")
+	}
+
+	// blue and yellow are opposite, the perceptual middle is muted instead of another saturated hue
+	middle := Blend(blue, yellow, 0.5).Color()
+	if middle.Saturation > 0.5 {
+		t.Errorf("expected a muted color between blue and yellow but got %v", middle)
+	}
+	if straight := Lerp(blue, yellow, 0.5).Color(); straight.Saturation != 1 {
+		t.Errorf("expected Lerp to stay saturated but got %v", straight)
+	}
+
+	// the brightness of a fade should change steadily
+	previous := math.Inf(-1)
+	for i := 0; i <= 10; i++ {
+		L, _, _ := oklab(Blend(HSBK{Kelvin: 3500}, red, float64(i)/10).Color())
+		if L < previous {
+			t.Errorf("expected the lightness to rise at step %v", i)
+		}
+		previous = L
+	}
+}
+
+func TestMixKelvin(t *testing.T) {
+	if k := MixKelvin(2500, 9000, 0.5); k != 3913 {
+		t.Errorf("expected 3913 but got %v", k)
+	}
+	if MixKelvin(0, 4000, 0.3) != 4000 || MixKelvin(4000, 0, 0.3) != 4000 {
+		t.Errorf("expected a zero kelvin to take the other")
+	}
+}
+
+func TestGradient(t *testing.T) {
+	colors := Gradient(5, nil, red, yellow, blue)
+	if len(colors) != 5 || colors[0] != red || colors[2] != yellow || colors[4] != blue {
+		t.Fatalf("expected red, yellow and blue at the stops but got %v", colors)
+	}
+	if colors[1] != Lerp(red, yellow, 0.5) || colors[3] != Lerp(yellow, blue, 0.5) {
+		t.Errorf("expected the steps halfway between the stops but got %v", colors)
+	}
+
+	if len(Gradient(0, nil, red)) != 0 || len(Gradient(3, nil)) != 0 {
+		t.Errorf("expected nothing to interpolate")
+	}
+	if one := Gradient(1, Blend, red, blue); len(one) != 1 || one[0] != red {
+		t.Errorf("expected just the first stop but got %v", one)
+	}
+	if same := Gradient(3, nil, blue); same[0] != blue || same[2] != blue {
+		t.Errorf("expected a single stop to repeat but got %v", same)
+	}
+}
@@ -0,0 +1,222 @@
+package hsbk
+
+import (
+	"fmt"
+	"math"
+)
+
+//Color is HSBK in natural units: hue in degrees [0,360), saturation and brightness as fractions [0,1]
+//and kelvin. Converting an HSBK to a Color and back gives the same HSBK.
+type Color struct {
+	Hue        float64
+	Saturation float64
+	Brightness float64
+	Kelvin     float64
+}
+
+//Color converts the raw fields to natural units.
+func (hsbk HSBK) Color() Color {
+	return Color{
+		Hue:        Degrees(hsbk.Hue),
+		Saturation: Fraction(hsbk.Saturation),
+		Brightness: Fraction(hsbk.Brightness),
+		Kelvin:     float64(hsbk.Kelvin),
+	}
+}
+
+//HSBK converts to the raw fields, wrapping the hue and clamping the rest into range.
+func (c Color) HSBK() HSBK {
+	return HSBK{
+		Hue:        FromDegrees(c.Hue),
+		Saturation: FromFraction(c.Saturation),
+		Brightness: FromFraction(c.Brightness),
+		Kelvin:     uint16(math.Round(math.Max(0, math.Min(0xffff, c.Kelvin)))),
+	}
+}
+
+func (c Color) String() string {
+	return fmt.Sprintf("Hue:%.2f Sat:%.2f%% Bright:%.2f%% Kelvin:%.0f", c.Hue, c.Saturation*100, c.Brightness*100, c.Kelvin)
+}
+
+//Degrees scales a raw hue to [0,360].
+func Degrees(hue uint16) float64 {
+	return float64(hue) / 0xffff * 360
+}
+
+//FromDegrees scales degrees to a raw hue, angles outside [0,360] are wrapped into it first.
+func FromDegrees(degrees float64) uint16 {
+	if math.IsNaN(degrees) || math.IsInf(degrees, 0) {
+		return 0
+	}
+	if degrees < 0 || degrees > 360 {
+		degrees = math.Mod(degrees, 360)
+		if degrees < 0 {
+			degrees += 360
+		}
+	}
+	return uint16(math.Round(degrees / 360 * 0xffff))
+}
+
+//Fraction scales a raw saturation or brightness to [0,1].
+func Fraction(value uint16) float64 {
+	return float64(value) / 0xffff
+}
+
+//FromFraction scales [0,1] to a raw saturation or brightness, clamping values out of range.
+func FromFraction(f float64) uint16 {
+	if math.IsNaN(f) {
+		return 0
+	}
+	return uint16(math.Round(clamp01(f) * 0xffff))
+}
+
+//Mired is the color temperature in micro reciprocal degrees, the unit Hue and Home Assistant use.
+func (c Color) Mired() float64 {
+	return KelvinToMired(c.Kelvin)
+}
+
+//KelvinToMired converts a color temperature, 0 stays 0.
+func KelvinToMired(kelvin float64) float64 {
+	if kelvin <= 0 {
+		return 0
+	}
+	return 1e6 / kelvin
+}
+
+//MiredToKelvin converts a color temperature, 0 stays 0.
+func MiredToKelvin(mired float64) float64 {
+	if mired <= 0 {
+		return 0
+	}
+	return 1e6 / mired
+}
+
+//RGB is the color the bulb shows with each component in [0,1], see HSBK.ToRGB.
+func (c Color) RGB() (r, g, b float64) {
+	s := clamp01(c.Saturation)
+	v := clamp01(c.Brightness)
+	hr, hg, hb := hueToRGB(math.Mod(math.Mod(c.Hue, 360)+360, 360))
+	wr, wg, wb := kelvinToRGB(c.Kelvin)
+	return v * (s*hr + (1-s)*wr), v * (s*hg + (1-s)*wg), v * (s*hb + (1-s)*wb)
+}
+
+//XYZ is the CIE 1931 tristimulus value of the color taken as sRGB (D65 white), Y is the luminance in [0,1].
+func (c Color) XYZ() (x, y, z float64) {
+	r, g, b := c.RGB()
+	r, g, b = linear(r), linear(g), linear(b)
+	x = 0.4124564*r + 0.3575761*g + 0.1804375*b
+	y = 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z = 0.0193339*r + 0.1191920*g + 0.9503041*b
+	return
+}
+
+//XY is the CIE 1931 chromaticity of the color and its luminance. Black has no chromaticity, it gets the
+//D65 white point.
+func (c Color) XY() (x, y, luminance float64) {
+	X, Y, Z := c.XYZ()
+	sum := X + Y + Z
+	if sum == 0 {
+		return 0.31271, 0.32902, 0
+	}
+	return X / sum, Y / sum, Y
+}
+
+//FromXYZ converts a CIE 1931 tristimulus value to a Color. Colors outside the sRGB gamut are brought
+//inside keeping their hue, the kelvin is NeutralKelvin.
+func FromXYZ(x, y, z float64) Color {
+	r := 3.2404542*x - 1.5371385*y - 0.4985314*z
+	g := -0.9692660*x + 1.8760108*y + 0.0415560*z
+	b := 0.0556434*x - 0.2040259*y + 1.0572252*z
+
+	// out of gamut: drop the negative components then scale down so the brightest component fits
+	r, g, b = math.Max(0, r), math.Max(0, g), math.Max(0, b)
+	if max := math.Max(r, math.Max(g, b)); max > 1 {
+		r, g, b = r/max, g/max, b/max
+	}
+	c := fromRGB(gamma(r), gamma(g), gamma(b))
+	c.Kelvin = NeutralKelvin
+	return c
+}
+
+//FromXY converts a CIE 1931 chromaticity at the given brightness [0,1] to a Color. Like the Hue API
+//the brightness is applied after finding the brightest color with that chromaticity.
+func FromXY(x, y, brightness float64) Color {
+	if y <= 0 {
+		return Color{Kelvin: NeutralKelvin}
+	}
+	c := FromXYZ(x/y, 1, (1-x-y)/y)
+	c.Brightness = clamp01(brightness)
+	return c
+}
+
+//KelvinToXY is the chromaticity of a black body at kelvin, using Kim et al.'s cubic spline of the
+//Planckian locus which is valid from 1667K to 25000K.
+func KelvinToXY(kelvin float64) (x, y float64) {
+	t := math.Max(1667, math.Min(25000, kelvin))
+	if t <= 4000 {
+		x = -0.2661239e9/(t*t*t) - 0.2343589e6/(t*t) + 0.8776956e3/t + 0.179910
+	} else {
+		x = -3.0258469e9/(t*t*t) + 2.1070379e6/(t*t) + 0.2226347e3/t + 0.240390
+	}
+	switch {
+	case t <= 2222:
+		y = -1.1063814*x*x*x - 1.34811020*x*x + 2.18555832*x - 0.20219683
+	case t <= 4000:
+		y = -0.9549476*x*x*x - 1.37418593*x*x + 2.09137015*x - 0.16748867
+	default:
+		y = 3.0817580*x*x*x - 5.87338670*x*x + 3.75112997*x - 0.37001483
+	}
+	return
+}
+
+//XYToKelvin is the correlated color temperature of a chromaticity using McCamy's approximation, which is
+//good for whites between about 2000K and 12500K.
+func XYToKelvin(x, y float64) float64 {
+	n := (x - 0.3320) / (0.1858 - y)
+	return 449*n*n*n + 3525*n*n + 6823.3*n + 5520.33
+}
+
+//fromRGB is HSV with components in [0,1].
+func fromRGB(r, g, b float64) Color {
+	r, g, b = clamp01(r), clamp01(g), clamp01(b)
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	delta := max - min
+
+	var c Color
+	c.Brightness = max
+	if max > 0 {
+		c.Saturation = delta / max
+	}
+	if delta > 0 {
+		switch max {
+		case r:
+			c.Hue = math.Mod((g-b)/delta, 6)
+		case g:
+			c.Hue = (b-r)/delta + 2
+		default:
+			c.Hue = (r-g)/delta + 4
+		}
+		c.Hue *= 60
+		if c.Hue < 0 {
+			c.Hue += 360
+		}
+	}
+	return c
+}
+
+//linear removes the sRGB gamma.
+func linear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+//gamma applies the sRGB gamma.
+func gamma(v float64) float64 {
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
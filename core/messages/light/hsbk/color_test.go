@@ -0,0 +1,102 @@
+package hsbk
+
+import (
+	"math"
+	"testing"
+	"testing/quick"
+)
+
+func TestColor_RoundTrip(t *testing.T) {
+	roundTrip := func(h HSBK) bool {
+		return h.Color().HSBK() == h
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+	for _, h := range []HSBK{{}, {Hue: 0xffff, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 9000}, {Hue: 1, Saturation: 1, Brightness: 1, Kelvin: 1}} {
+		if !roundTrip(h) {
+			t.Errorf("%v did not round trip", h)
+		}
+	}
+}
+
+func TestColor_HSBK(t *testing.T) {
+	tests := []struct {
+		in       Color
+		expected HSBK
+	}{
+		{Color{Hue: 120, Saturation: 1, Brightness: 0.5, Kelvin: 3500}, HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500}},
+		{Color{Hue: -120, Saturation: 2, Brightness: -1, Kelvin: 2700.4}, HSBK{Hue: 0xaaaa, Saturation: 0xffff, Brightness: 0, Kelvin: 2700}},
+		{Color{Hue: 720}, HSBK{}},
+	}
+	for _, test := range tests {
+		if actual := test.in.HSBK(); actual != test.expected {
+			t.Errorf("%v: expected %#v but got %#v", test.in, test.expected, actual)
+		}
+	}
+}
+
+func TestMired(t *testing.T) {
+	if m := (Color{Kelvin: 2500}).Mired(); m != 400 {
+		t.Errorf("expected 400 mired but got %v", m)
+	}
+	if k := MiredToKelvin(153); math.Abs(k-6535.9) > 0.1 {
+		t.Errorf("expected 6535.9K but got %v", k)
+	}
+	if KelvinToMired(0) != 0 || MiredToKelvin(0) != 0 {
+		t.Errorf("expected 0 to stay 0")
+	}
+}
+
+func TestColor_XY(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        Color
+		x, y, lum float64
+	}{
+		{"red", Color{Hue: 0, Saturation: 1, Brightness: 1}, 0.6400, 0.3300, 0.2126},
+		{"green", Color{Hue: 120, Saturation: 1, Brightness: 1}, 0.3000, 0.6000, 0.7152},
+		{"blue", Color{Hue: 240, Saturation: 1, Brightness: 1}, 0.1500, 0.0600, 0.0722},
+		{"black", Color{Kelvin: 3500}, 0.3127, 0.3290, 0},
+	}
+	for _, test := range tests {
+		x, y, lum := test.in.XY()
+		if math.Abs(x-test.x) > 0.001 || math.Abs(y-test.y) > 0.001 || math.Abs(lum-test.lum) > 0.001 {
+			t.Errorf("%v: expected %.4f,%.4f,%.4f but got %.4f,%.4f,%.4f", test.name, test.x, test.y, test.lum, x, y, lum)
+		}
+
+		if test.lum == 0 {
+			continue
+		}
+		back := FromXY(x, y, test.in.Brightness)
+		if math.Abs(back.Hue-test.in.Hue) > 0.1 || math.Abs(back.Saturation-test.in.Saturation) > 0.001 || back.Brightness != test.in.Brightness {
+			t.Errorf("%v: expected %v from xy but got %v", test.name, test.in, back)
+		}
+	}
+
+	// out of gamut for sRGB, the hue should still be green-ish and fully saturated
+	c := FromXY(0.17, 0.70, 1)
+	if c.Hue < 90 || c.Hue > 150 || c.Saturation < 0.99 {
+		t.Errorf("expected a saturated green but got %v", c)
+	}
+}
+
+func TestKelvinToXY(t *testing.T) {
+	tests := []struct {
+		kelvin float64
+		x, y   float64
+	}{
+		{2700, 0.4599, 0.4106},
+		{4000, 0.3805, 0.3768},
+		{6500, 0.3135, 0.3236},
+	}
+	for _, test := range tests {
+		x, y := KelvinToXY(test.kelvin)
+		if math.Abs(x-test.x) > 0.001 || math.Abs(y-test.y) > 0.001 {
+			t.Errorf("%vK: expected %.4f,%.4f but got %.4f,%.4f", test.kelvin, test.x, test.y, x, y)
+		}
+		if k := XYToKelvin(x, y); math.Abs(k-test.kelvin) > 50 {
+			t.Errorf("%vK: expected the kelvin back but got %.0f", test.kelvin, k)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package hsbk
+
+import (
+	"encoding/json"
+	"math"
+)
+
+//hsbkJSON is the human friendly form of HSBK: hue in degrees, saturation and brightness in percent.
+type hsbkJSON struct {
+	Hue        float64 `json:"hue" yaml:"hue"`
+	Saturation float64 `json:"saturation" yaml:"saturation"`
+	Brightness float64 `json:"brightness" yaml:"brightness"`
+	Kelvin     uint16  `json:"kelvin" yaml:"kelvin"`
+}
+
+func (hsbk HSBK) toJSON() hsbkJSON {
+	return hsbkJSON{
+		Hue:        toScale(hsbk.Hue, 360),
+		Saturation: toScale(hsbk.Saturation, 100),
+		Brightness: toScale(hsbk.Brightness, 100),
+		Kelvin:     hsbk.Kelvin,
+	}
+}
+
+func (hsbk *HSBK) fromJSON(j hsbkJSON) {
+	hsbk.Hue = fromScale(j.Hue, 360)
+	hsbk.Saturation = fromScale(j.Saturation, 100)
+	hsbk.Brightness = fromScale(j.Brightness, 100)
+	hsbk.Kelvin = j.Kelvin
+}
+
+func (hsbk HSBK) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hsbk.toJSON())
+}
+
+func (hsbk *HSBK) UnmarshalJSON(data []byte) error {
+	var j hsbkJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	hsbk.fromJSON(j)
+	return nil
+}
+
+func (hsbk HSBK) MarshalYAML() (interface{}, error) {
+	return hsbk.toJSON(), nil
+}
+
+func (hsbk *HSBK) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j hsbkJSON
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	hsbk.fromJSON(j)
+	return nil
+}
+
+//toScale maps a 0-65535 field onto [0,max], rounded to 4 decimals which is still enough to get the
+//exact field value back.
+func toScale(value uint16, max float64) float64 {
+	return math.Round(float64(value)/0xffff*max*10000) / 10000
+}
+
+func fromScale(value, max float64) uint16 {
+	return uint16(math.Round(math.Max(0, math.Min(max, value)) / max * 0xffff))
+}
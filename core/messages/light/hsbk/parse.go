@@ -0,0 +1,262 @@
+package hsbk
+
+import (
+	"fmt"
+	"golang.org/x/image/colornames"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//Fields says which parts of an HSBK a Spec sets.
+type Fields uint8
+
+const (
+	HueField Fields = 1 << iota
+	SaturationField
+	BrightnessField
+	KelvinField
+
+	AllFields = HueField | SaturationField | BrightnessField | KelvinField
+)
+
+//Spec is a parsed color string. Only the fields in Set are meaningful, the rest come from the color it is
+//applied to, which is usually the light's current color.
+type Spec struct {
+	HSBK
+	Set Fields
+}
+
+//Whites are the named white temperatures, they set the kelvin and drop the saturation.
+var Whites = map[string]uint16{
+	"warm white":    2700,
+	"soft white":    3000,
+	"neutral white": 4000,
+	"cool white":    5000,
+	"daylight":      6500,
+}
+
+//Apply returns base with the fields of the spec replaced.
+func (s Spec) Apply(base HSBK) HSBK {
+	if s.Set&HueField != 0 {
+		base.Hue = s.Hue
+	}
+	if s.Set&SaturationField != 0 {
+		base.Saturation = s.Saturation
+	}
+	if s.Set&BrightnessField != 0 {
+		base.Brightness = s.Brightness
+	}
+	if s.Set&KelvinField != 0 {
+		base.Kelvin = s.Kelvin
+	}
+	return base
+}
+
+//Complete reports if the spec sets every field, so there is no need to know the current color.
+func (s Spec) Complete() bool {
+	return s.Set == AllFields
+}
+
+//Parse reads a color string made of space separated parts, later parts override earlier ones:
+//
+//	red, tomato, ...     CSS/X11 color names set hue, saturation and brightness
+//	white                sets saturation to 0
+//	warm white, ...      the Whites set saturation to 0 and the kelvin
+//	#ff0000, #f00        hex RGB sets hue, saturation and brightness
+//	rgb(255,0,0)         as hex, rgb:255,0,0 works too
+//	hsb(120,100%,50%)    hue in degrees, saturation and brightness as percent or fractions
+//	hue:120 saturation:50% brightness:0.3 kelvin:2700
+//
+//so "warm white brightness:30%" is a dim warm white at whatever hue the light has.
+func Parse(s string) (Spec, error) {
+	tokens := tokenize(strings.ToLower(s))
+	if len(tokens) == 0 {
+		return Spec{}, fmt.Errorf("empty color")
+	}
+
+	var spec Spec
+	for i := 0; i < len(tokens); i++ {
+		if i+1 < len(tokens) {
+			if kelvin, has := Whites[tokens[i]+" "+tokens[i+1]]; has {
+				spec.white(kelvin)
+				i++
+				continue
+			}
+		}
+		if err := spec.parseToken(tokens[i]); err != nil {
+			return Spec{}, fmt.Errorf("bad color %q: %v", s, err)
+		}
+	}
+	return spec, nil
+}
+
+//MustParse is Parse for colors known to be good, it panics otherwise.
+func MustParse(s string) Spec {
+	spec, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return spec
+}
+
+func (s *Spec) white(kelvin uint16) {
+	s.Saturation = 0
+	s.Kelvin = kelvin
+	s.Set |= SaturationField | KelvinField
+}
+
+func (s *Spec) rgb(c HSBK) {
+	s.Hue, s.Saturation, s.Brightness = c.Hue, c.Saturation, c.Brightness
+	s.Set |= HueField | SaturationField | BrightnessField
+}
+
+func (s *Spec) parseToken(token string) error {
+	if kelvin, has := Whites[token]; has {
+		s.white(kelvin)
+		return nil
+	}
+	if token == "white" {
+		s.Saturation = 0
+		s.Set |= SaturationField
+		return nil
+	}
+	if c, has := colornames.Map[token]; has {
+		s.rgb(FromRGB8(c.R, c.G, c.B))
+		return nil
+	}
+	if strings.HasPrefix(token, "#") {
+		c, err := FromHex(token)
+		if err != nil {
+			return err
+		}
+		s.rgb(c)
+		return nil
+	}
+	if strings.HasPrefix(token, "rgb(") || strings.HasPrefix(token, "hsb(") {
+		return s.parseFunction(token)
+	}
+	if i := strings.Index(token, ":"); i > 0 {
+		return s.parseField(token[:i], token[i+1:])
+	}
+	return fmt.Errorf("unknown color %q", token)
+}
+
+func (s *Spec) parseFunction(token string) error {
+	if !strings.HasSuffix(token, ")") {
+		return fmt.Errorf("missing ) in %q", token)
+	}
+	args := strings.Split(token[4:len(token)-1], ",")
+	if len(args) != 3 {
+		return fmt.Errorf("expected 3 values in %q", token)
+	}
+
+	if strings.HasPrefix(token, "rgb(") {
+		rgb := make([]uint8, 3)
+		for i, arg := range args {
+			v, err := strconv.ParseUint(strings.TrimSpace(arg), 10, 8)
+			if err != nil {
+				return fmt.Errorf("bad value in %q: expected 0-255", token)
+			}
+			rgb[i] = uint8(v)
+		}
+		s.rgb(FromRGB8(rgb[0], rgb[1], rgb[2]))
+		return nil
+	}
+
+	hue, err := parseHue(args[0])
+	if err != nil {
+		return err
+	}
+	saturation, err := parseFraction(args[1])
+	if err != nil {
+		return err
+	}
+	brightness, err := parseFraction(args[2])
+	if err != nil {
+		return err
+	}
+	s.Hue, s.Saturation, s.Brightness = hue, saturation, brightness
+	s.Set |= HueField | SaturationField | BrightnessField
+	return nil
+}
+
+func (s *Spec) parseField(name, value string) (err error) {
+	switch name {
+	case "hue", "h":
+		s.Hue, err = parseHue(value)
+		s.Set |= HueField
+	case "saturation", "sat", "s":
+		s.Saturation, err = parseFraction(value)
+		s.Set |= SaturationField
+	case "brightness", "bright", "b":
+		s.Brightness, err = parseFraction(value)
+		s.Set |= BrightnessField
+	case "kelvin", "k":
+		var k uint64
+		k, err = strconv.ParseUint(strings.TrimSuffix(value, "k"), 10, 16)
+		if err == nil && (k < 1500 || k > 9000) {
+			err = fmt.Errorf("kelvin %v out of range [1500,9000]", k)
+		}
+		s.Kelvin = uint16(k)
+		s.Set |= KelvinField
+	case "rgb":
+		// the LIFX HTTP API's spelling of rgb()
+		err = s.parseFunction("rgb(" + value + ")")
+	default:
+		err = fmt.Errorf("unknown field %q", name)
+	}
+	return
+}
+
+//parseHue reads degrees, wrapping angles outside [0,360].
+func parseHue(value string) (uint16, error) {
+	degrees, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "deg"), 64)
+	if err != nil || math.IsNaN(degrees) || math.IsInf(degrees, 0) {
+		return 0, fmt.Errorf("bad hue %q: expected degrees", value)
+	}
+	return FromDegrees(degrees), nil
+}
+
+//parseFraction reads "50%" or "0.5".
+func parseFraction(value string) (uint16, error) {
+	value = strings.TrimSpace(value)
+	scale := 1.0
+	if strings.HasSuffix(value, "%") {
+		value = strings.TrimSuffix(value, "%")
+		scale = 100
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 || f > scale {
+		return 0, fmt.Errorf("bad value %q: expected 0-100%% or 0-1", value)
+	}
+	return FromFraction(f / scale), nil
+}
+
+//tokenize splits on spaces that are not inside parentheses.
+func tokenize(s string) []string {
+	tokens := make([]string, 0)
+	depth := 0
+	start := -1
+	for i, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case (r == ' ' || r == '\t') && depth == 0:
+			if start >= 0 {
+				tokens = append(tokens, s[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
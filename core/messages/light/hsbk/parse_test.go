@@ -0,0 +1,46 @@
+package hsbk
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	current := HSBK{Hue: 0x1111, Saturation: 0x2222, Brightness: 0x3333, Kelvin: 4444}
+	tests := []struct {
+		in       string
+		expected HSBK
+		complete bool
+	}{
+		{"red", HSBK{Hue: 0, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 4444}, false},
+		{"Tomato", HSBK{Hue: 0x067e, Saturation: 0xb8b8, Brightness: 0xffff, Kelvin: 4444}, false},
+		{"#00ff00", HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 4444}, false},
+		{"rgb(0, 0, 255)", HSBK{Hue: 0xaaaa, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 4444}, false},
+		{"rgb:0,0,255", HSBK{Hue: 0xaaaa, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 4444}, false},
+		{"hsb(120,100%,50%)", HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 4444}, false},
+		{"hsb(-90, 0.5, 1)", HSBK{Hue: 0xbfff, Saturation: 0x8000, Brightness: 0xffff, Kelvin: 4444}, false},
+		{"kelvin:2700", HSBK{Hue: 0x1111, Saturation: 0x2222, Brightness: 0x3333, Kelvin: 2700}, false},
+		{"white", HSBK{Hue: 0x1111, Saturation: 0, Brightness: 0x3333, Kelvin: 4444}, false},
+		{"warm white brightness:30%", HSBK{Hue: 0x1111, Saturation: 0, Brightness: 0x4ccd, Kelvin: 2700}, false},
+		{"daylight", HSBK{Hue: 0x1111, Saturation: 0, Brightness: 0x3333, Kelvin: 6500}, false},
+		{"blue kelvin:3500", HSBK{Hue: 0xaaaa, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}, true},
+		{"hue:360 saturation:1 brightness:0 kelvin:9000", HSBK{Hue: 0xffff, Saturation: 0xffff, Brightness: 0, Kelvin: 9000}, true},
+		{"red brightness:50%", HSBK{Hue: 0, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 4444}, false},
+	}
+	for _, test := range tests {
+		spec, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if actual := spec.Apply(current); actual != test.expected {
+			t.Errorf("%q: expected %#v but got %#v", test.in, test.expected, actual)
+		}
+		if spec.Complete() != test.complete {
+			t.Errorf("%q: expected complete to be %v", test.in, test.complete)
+		}
+	}
+
+	for _, bad := range []string{"", "reddish", "#12", "rgb(256,0,0)", "rgb(1,2)", "hsb(0,200%,0)", "kelvin:100", "brightness:2", "hue:x", "warm"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("expected %q to fail", bad)
+		}
+	}
+}
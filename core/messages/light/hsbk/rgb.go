@@ -0,0 +1,111 @@
+package hsbk
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+)
+
+//NeutralKelvin is the kelvin FromRGB uses, it is the white point the closest to RGB white.
+const NeutralKelvin = 6500
+
+//ToRGB returns the color the bulb shows with each component in [0,1]. Hue, saturation and brightness
+//are HSV, but instead of pure white a desaturated bulb shows its kelvin white so the lower the
+//saturation the more the result is tinted by the kelvin.
+func (hsbk HSBK) ToRGB() (r, g, b float32) {
+	fr, fg, fb := hsbk.Color().RGB()
+	return float32(fr), float32(fg), float32(fb)
+}
+
+//ToRGB8 is ToRGB scaled to [0,255].
+func (hsbk HSBK) ToRGB8() (r, g, b uint8) {
+	fr, fg, fb := hsbk.ToRGB()
+	return to8(fr), to8(fg), to8(fb)
+}
+
+//Hex is the color as "#rrggbb".
+func (hsbk HSBK) Hex() string {
+	r, g, b := hsbk.ToRGB8()
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+//FromRGB converts an RGB color with components in [0,1] to HSBK using NeutralKelvin as the white point.
+func FromRGB(r, g, b float32) HSBK {
+	c := fromRGB(float64(r), float64(g), float64(b))
+	c.Kelvin = NeutralKelvin
+	return c.HSBK()
+}
+
+//FromRGB8 converts an RGB color with components in [0,255] to HSBK.
+func FromRGB8(r, g, b uint8) HSBK {
+	return FromRGB(float32(r)/0xff, float32(g)/0xff, float32(b)/0xff)
+}
+
+//FromHex parses "#ff8800", "ff8800" or the short "#f80".
+func FromHex(s string) (HSBK, error) {
+	digits := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	if len(digits) != 6 {
+		return HSBK{}, fmt.Errorf("bad hex color %q: expected #rrggbb or #rgb", s)
+	}
+	rgb, err := hex.DecodeString(digits)
+	if err != nil {
+		return HSBK{}, fmt.Errorf("bad hex color %q: %v", s, err)
+	}
+	return FromRGB8(rgb[0], rgb[1], rgb[2]), nil
+}
+
+//KelvinToRGB is the color of white light at the given temperature with components in [0,1], the brightest
+//component is always 1.
+func KelvinToRGB(kelvin uint16) (r, g, b float32) {
+	fr, fg, fb := kelvinToRGB(float64(kelvin))
+	return float32(fr), float32(fg), float32(fb)
+}
+
+//kelvinToRGB uses Tanner Helland's fit of the black body colors, normalized to a brightest component of 1.
+func kelvinToRGB(kelvin float64) (r, g, b float64) {
+	if kelvin <= 0 {
+		kelvin = NeutralKelvin
+	}
+	t := math.Max(1000, math.Min(40000, kelvin)) / 100
+
+	if t <= 66 {
+		r = 255
+		g = 99.4708025861*math.Log(t) - 161.1195681661
+	} else {
+		r = 329.698727446 * math.Pow(t-60, -0.1332047592)
+		g = 288.1221695283 * math.Pow(t-60, -0.0755148492)
+	}
+	switch {
+	case t >= 66:
+		b = 255
+	case t <= 19:
+		b = 0
+	default:
+		b = 138.5177312231*math.Log(t-10) - 305.0447927307
+	}
+
+	r, g, b = clamp01(r/255), clamp01(g/255), clamp01(b/255)
+	max := math.Max(r, math.Max(g, b))
+	return r / max, g / max, b / max
+}
+
+//hueToRGB is the fully saturated, full brightness color at hue degrees.
+func hueToRGB(h float64) (r, g, b float64) {
+	component := func(n float64) float64 {
+		k := math.Mod(n+h/60, 6)
+		return 1 - math.Max(0, math.Min(k, math.Min(4-k, 1)))
+	}
+	return component(5), component(3), component(1)
+}
+
+func clamp01(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
+
+func to8(x float32) uint8 {
+	return uint8(math.Round(clamp01(float64(x)) * 0xff))
+}
@@ -0,0 +1,101 @@
+package hsbk
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+func TestHSBK_ToRGB(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      HSBK
+		r, g, b uint8
+	}{
+		{"red", HSBK{Hue: 0, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}, 255, 0, 0},
+		{"green", HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}, 0, 255, 0},
+		{"blue", HSBK{Hue: 0xaaaa, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}, 0, 0, 255},
+		{"yellow", HSBK{Hue: 0x2aaa, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}, 255, 255, 0},
+		{"half red", HSBK{Hue: 0, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500}, 128, 0, 0},
+		{"off", HSBK{Hue: 0x1234, Saturation: 0x8000, Brightness: 0, Kelvin: 3500}, 0, 0, 0},
+		{"daylight white", HSBK{Saturation: 0, Brightness: 0xffff, Kelvin: 6500}, 255, 254, 250},
+		{"warm white", HSBK{Saturation: 0, Brightness: 0xffff, Kelvin: 2500}, 255, 159, 70},
+		{"cool white", HSBK{Saturation: 0, Brightness: 0xffff, Kelvin: 9000}, 210, 223, 255},
+		{"pastel red", HSBK{Hue: 0, Saturation: 0x8000, Brightness: 0xffff, Kelvin: 6500}, 255, 127, 125},
+	}
+	for _, test := range tests {
+		r, g, b := test.in.ToRGB8()
+		if !near(r, test.r) || !near(g, test.g) || !near(b, test.b) {
+			t.Errorf("%v: expected %v,%v,%v but got %v,%v,%v", test.name, test.r, test.g, test.b, r, g, b)
+		}
+	}
+}
+
+func TestFromHex(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected HSBK
+	}{
+		{"#ff0000", HSBK{Hue: 0, Saturation: 0xffff, Brightness: 0xffff, Kelvin: NeutralKelvin}},
+		{"00ff00", HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0xffff, Kelvin: NeutralKelvin}},
+		{"#00F", HSBK{Hue: 0xaaaa, Saturation: 0xffff, Brightness: 0xffff, Kelvin: NeutralKelvin}},
+		{"#ff8800", HSBK{Hue: 0x16c1, Saturation: 0xffff, Brightness: 0xffff, Kelvin: NeutralKelvin}},
+		{"#ffffff", HSBK{Hue: 0, Saturation: 0, Brightness: 0xffff, Kelvin: NeutralKelvin}},
+		{"#808080", HSBK{Hue: 0, Saturation: 0, Brightness: 0x8080, Kelvin: NeutralKelvin}},
+		{"#000000", HSBK{Kelvin: NeutralKelvin}},
+		{"#ff00ff", HSBK{Hue: 0xd555, Saturation: 0xffff, Brightness: 0xffff, Kelvin: NeutralKelvin}},
+	}
+	for _, test := range tests {
+		actual, err := FromHex(test.in)
+		if err != nil {
+			t.Errorf("%v: %v", test.in, err)
+			continue
+		}
+		if actual != test.expected {
+			t.Errorf("%v: expected %v but got %v", test.in, test.expected, actual)
+		}
+	}
+
+	for _, bad := range []string{"", "#ff00", "#gg0000", "red"} {
+		if _, err := FromHex(bad); err == nil {
+			t.Errorf("expected %q to fail", bad)
+		}
+	}
+}
+
+func TestRGB_RoundTrip(t *testing.T) {
+	for _, in := range []string{"#ff8800", "#123456", "#00ffee", "#7f7f00", "#ff0000", "#010203"} {
+		c, err := FromHex(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := hex.DecodeString(in[1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, g, b := c.ToRGB8()
+		// white is the kelvin white so gray channels may drift a little
+		if !near(r, expected[0]) || !near(g, expected[1]) || !near(b, expected[2]) {
+			t.Errorf("%v: round tripped to %v", in, c.Hex())
+		}
+	}
+}
+
+func TestKelvinToRGB(t *testing.T) {
+	previous := float32(-1)
+	for k := uint16(2500); k <= 9000; k += 500 {
+		r, g, b := KelvinToRGB(k)
+		if math.Max(float64(r), math.Max(float64(g), float64(b))) != 1 {
+			t.Errorf("%v: expected the brightest component to be 1 but got %v,%v,%v", k, r, g, b)
+		}
+		// blue rises as the light gets cooler
+		if b/r < previous {
+			t.Errorf("%v: expected %v,%v,%v to be bluer than the warmer kelvin", k, r, g, b)
+		}
+		previous = b / r
+	}
+}
+
+func near(a, b uint8) bool {
+	return math.Abs(float64(a)-float64(b)) <= 2
+}
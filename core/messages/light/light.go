@@ -40,7 +40,7 @@ func (g SetColor) RequiredHeader(h *header.Header, responseRequired bool) {
 	h.SetType(SetColorType)
 	// if true a response with state will be sent
 	h.SetResponseRequired(responseRequired)
-	h.SetSize(header.HeaderLen)
+	h.SetSize(header.HeaderLen + 13)
 }
 
 type State struct {
@@ -83,7 +83,7 @@ func (SetPower) RequiredHeader(h *header.Header, responseRequired bool) {
 	h.SetType(SetPowerType)
 	// if true a response with state will be sent
 	h.SetResponseRequired(responseRequired)
-	h.SetSize(header.HeaderLen)
+	h.SetSize(header.HeaderLen + 6)
 }
 
 func (sp SetPower) GetLevel() bool {
@@ -135,41 +135,34 @@ type SetInfrared struct {
 func (g SetInfrared) RequiredHeader(h *header.Header, responseRequired bool) {
 	h.SetType(SetInfraredType)
 	h.SetResponseRequired(responseRequired)
-	h.SetSize(header.HeaderLen)
+	h.SetSize(header.HeaderLen + 2)
 }
 
 type StateInfrared struct {
 	Brightness uint16
 }
 
+//DecodeFromHeader decodes the payload of h into message, which must be a pointer to the type matching h.Type().
 func DecodeFromHeader(h header.Header, message interface{}) error {
+	var ok bool
 	switch h.Type() {
+	case SetColorType:
+		_, ok = message.(*SetColor)
 	case StateType:
-		if s, ok := message.(*State); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*State)
+	case SetPowerType:
+		_, ok = message.(*SetPower)
 	case StatePowerType:
-		if s, ok := message.(*StatePower); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StatePower)
+	case SetInfraredType:
+		_, ok = message.(*SetInfrared)
 	case StateInfraredType:
-		if s, ok := message.(*StateInfrared); ok {
-			buffer := bytes.NewBuffer(h.Data())
-			err := binary.Read(buffer, binary.LittleEndian, s)
-			if err != nil {
-				return err
-			}
-		}
+		_, ok = message.(*StateInfrared)
 	default:
 		return fmt.Errorf("not supported")
 	}
-	return nil
+	if !ok {
+		return fmt.Errorf("type %v can not be decoded into %T", h.Type(), message)
+	}
+	return binary.Read(bytes.NewBuffer(h.Data()), binary.LittleEndian, message)
 }
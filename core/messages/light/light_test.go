@@ -0,0 +1,102 @@
+package light
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"testing"
+)
+
+func FuzzDecodeFromHeader(f *testing.F) {
+	for _, s := range []string{
+		"5800001478563412d073d512345600004c4946585632000700000000000000006b0000005555ffff0080ac0d0000ffff4b69746368656e000000000000000000000000000000000000000000000000000000000000000000",
+		"2600001478563412d073d512345600004c49465856320007000000000000000076000000ffff",
+		"3100003400000000000000000000000000000000000000000000000000000000660000000055550000ffffffffac0d00040000",
+		"2a00001400000000d073d512345600000000000000000001000000000000000075000000ffffe8030000",
+	} {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h, err := header.Decode(data)
+		if err != nil {
+			return
+		}
+		messages := []interface{}{
+			&SetColor{}, &State{}, &SetPower{}, &StatePower{}, &SetInfrared{}, &StateInfrared{},
+		}
+		decoded := 0
+		for _, m := range messages {
+			if DecodeFromHeader(*h, m) == nil {
+				decoded++
+			}
+		}
+		if decoded > 1 {
+			t.Errorf("expect type %v to decode into at most one message but got %v", h.Type(), decoded)
+		}
+
+		var state State
+		if DecodeFromHeader(*h, &state) == nil {
+			_ = state.String()
+			_, _, _ = state.Color.ToRGB()
+		}
+		var power StatePower
+		if DecodeFromHeader(*h, &power) == nil {
+			_ = power.String()
+		}
+	})
+}
+
+func TestRequiredHeader_Size(t *testing.T) {
+	// devices ignore a Set whose size leaves out the payload
+	for _, test := range []struct {
+		message interface {
+			RequiredHeader(h *header.Header, responseRequired bool)
+		}
+		size int
+	}{
+		{SetColor{}, 13},
+		{SetPower{}, 6},
+		{SetInfrared{}, 2},
+	} {
+		h := header.New(0)
+		test.message.RequiredHeader(h, false)
+		if binary.Size(test.message) != test.size {
+			t.Errorf("expect a %v byte payload for %T but got %v", test.size, test.message, binary.Size(test.message))
+		}
+		if int(h.Size()) != header.HeaderLen+test.size {
+			t.Errorf("expect size %v for %T but got %v", header.HeaderLen+test.size, test.message, h.Size())
+		}
+	}
+}
+
+func TestState_MarshalJSON(t *testing.T) {
+	s := State{
+		Color: hsbk.HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500},
+		Power: 0xffff,
+		Label: fields.ToLabel("Kitchen"),
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"label":"Kitchen","power":"on","color":{"hue":120,"saturation":100,"brightness":50.0008,"kelvin":3500}}`
+	if string(b) != expected {
+		t.Errorf("expect %v but got %v", expected, string(b))
+	}
+
+	var back State
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back != s {
+		t.Errorf("expect %v but got %v", s, back)
+	}
+}
@@ -0,0 +1,122 @@
+package light
+
+import (
+	"encoding/json"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"math"
+)
+
+type stateJSON struct {
+	Label string    `json:"label" yaml:"label"`
+	Power string    `json:"power" yaml:"power"`
+	Color hsbk.HSBK `json:"color" yaml:"color"`
+}
+
+func (s State) toJSON() stateJSON {
+	return stateJSON{
+		Label: fields.Label(s.Label),
+		Power: fields.Power(s.Power),
+		Color: s.Color,
+	}
+}
+
+func (s *State) fromJSON(j stateJSON) (err error) {
+	s.Power, err = fields.ParsePower(j.Power)
+	s.Label = fields.ToLabel(j.Label)
+	s.Color = j.Color
+	return
+}
+
+func (s State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+func (s *State) UnmarshalJSON(data []byte) error {
+	var j stateJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	return s.fromJSON(j)
+}
+
+func (s State) MarshalYAML() (interface{}, error) {
+	return s.toJSON(), nil
+}
+
+func (s *State) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j stateJSON
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	return s.fromJSON(j)
+}
+
+type statePowerJSON struct {
+	Power string `json:"power" yaml:"power"`
+}
+
+func (sp StatePower) MarshalJSON() ([]byte, error) {
+	return json.Marshal(statePowerJSON{Power: fields.Power(sp.Level)})
+}
+
+func (sp *StatePower) UnmarshalJSON(data []byte) (err error) {
+	var j statePowerJSON
+	if err = json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	sp.Level, err = fields.ParsePower(j.Power)
+	return
+}
+
+func (sp StatePower) MarshalYAML() (interface{}, error) {
+	return statePowerJSON{Power: fields.Power(sp.Level)}, nil
+}
+
+func (sp *StatePower) UnmarshalYAML(unmarshal func(interface{}) error) (err error) {
+	var j statePowerJSON
+	if err = unmarshal(&j); err != nil {
+		return err
+	}
+	sp.Level, err = fields.ParsePower(j.Power)
+	return
+}
+
+//stateInfraredJSON holds the infrared brightness in percent
+type stateInfraredJSON struct {
+	Brightness float64 `json:"brightness" yaml:"brightness"`
+}
+
+func (s StateInfrared) toJSON() stateInfraredJSON {
+	return stateInfraredJSON{Brightness: math.Round(float64(s.Brightness)/0xffff*100*10000) / 10000}
+}
+
+func (s *StateInfrared) fromJSON(j stateInfraredJSON) {
+	s.Brightness = uint16(math.Round(math.Max(0, math.Min(100, j.Brightness)) / 100 * 0xffff))
+}
+
+func (s StateInfrared) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+func (s *StateInfrared) UnmarshalJSON(data []byte) error {
+	var j stateInfraredJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	s.fromJSON(j)
+	return nil
+}
+
+func (s StateInfrared) MarshalYAML() (interface{}, error) {
+	return s.toJSON(), nil
+}
+
+func (s *StateInfrared) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var j stateInfraredJSON
+	if err := unmarshal(&j); err != nil {
+		return err
+	}
+	s.fromJSON(j)
+	return nil
+}
@@ -0,0 +1,247 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net"
+	"sync"
+	"time"
+)
+
+//Broker is a small in-process MQTT 3.1.1 broker delivering at QoS 0 with retained messages and wills,
+//enough to test clients against. Credentials are not checked.
+type Broker struct {
+	//Log gets clients connecting and leaving, nil uses logrus' standard logger
+	Log logrus.FieldLogger
+
+	mutex    sync.Mutex
+	sessions map[*session]bool
+	retained map[string]Message
+}
+
+type session struct {
+	conn       net.Conn
+	writeMutex sync.Mutex
+	id         string
+	filters    map[string]bool
+	will       *Message
+}
+
+//Serve accepts connections until l is closed.
+func (b *Broker) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go b.serve(conn)
+	}
+}
+
+func (b *Broker) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	s := &session{conn: conn, filters: make(map[string]bool)}
+	keepAlive, err := s.connect(reader)
+	if err != nil {
+		b.log().WithField("client", conn.RemoteAddr()).WithError(err).Warn("bad CONNECT")
+		return
+	}
+	log := b.log().WithField("client", s.id)
+	log.Debug("connected")
+
+	b.mutex.Lock()
+	if b.sessions == nil {
+		b.sessions = make(map[*session]bool)
+	}
+	b.sessions[s] = true
+	b.mutex.Unlock()
+	defer func() {
+		b.mutex.Lock()
+		delete(b.sessions, s)
+		b.mutex.Unlock()
+		if s.will != nil {
+			b.route(*s.will)
+		}
+		log.Debug("left")
+	}()
+
+	for {
+		if keepAlive > 0 {
+			conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+		}
+		p, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		switch p.kind {
+		case publish:
+			m, qos, id, err := parsePublish(p)
+			if err != nil || !ValidTopic(m.Topic) {
+				return
+			}
+			if qos == 1 {
+				s.write(packet{kind: puback, body: []byte{byte(id >> 8), byte(id)}})
+			}
+			b.route(m)
+		case subscribe:
+			if err := b.subscribe(s, p); err != nil {
+				log.WithError(err).Warn("bad SUBSCRIBE")
+				return
+			}
+		case unsubscribe:
+			if len(p.body) < 2 {
+				return
+			}
+			b.mutex.Lock()
+			for rest := p.body[2:]; len(rest) > 0; {
+				var filter string
+				if filter, rest, err = readString(rest); err != nil {
+					break
+				}
+				delete(s.filters, filter)
+			}
+			b.mutex.Unlock()
+			s.write(packet{kind: unsuback, body: p.body[:2]})
+		case pingreq:
+			s.write(packet{kind: pingresp})
+		case disconnect:
+			s.will = nil
+			return
+		}
+	}
+}
+
+//connect reads the CONNECT, which has to come first, and accepts it.
+func (s *session) connect(reader *bufio.Reader) (time.Duration, error) {
+	s.conn.SetReadDeadline(time.Now().Add(connectTimeout))
+	defer s.conn.SetReadDeadline(time.Time{})
+	p, err := readPacket(reader)
+	if err != nil {
+		return 0, err
+	}
+	if p.kind != connect {
+		return 0, fmt.Errorf("expected CONNECT but got packet type %v", p.kind)
+	}
+	protocol, rest, err := readString(p.body)
+	if err != nil {
+		return 0, err
+	}
+	if (protocol != "MQTT" && protocol != "MQIsdp") || len(rest) < 4 {
+		s.write(packet{kind: connack, body: []byte{0, 1}})
+		return 0, fmt.Errorf("unsupported protocol %q", protocol)
+	}
+	flags := rest[1]
+	keepAlive := time.Duration(binary.BigEndian.Uint16(rest[2:])) * time.Second
+	if s.id, rest, err = readString(rest[4:]); err != nil {
+		return 0, err
+	}
+	if s.id == "" {
+		s.id = s.conn.RemoteAddr().String()
+	}
+	if flags&0x04 != 0 {
+		will := &Message{Retain: flags&0x20 != 0}
+		var payload string
+		if will.Topic, rest, err = readString(rest); err != nil {
+			return 0, err
+		}
+		if payload, _, err = readString(rest); err != nil {
+			return 0, err
+		}
+		will.Payload = []byte(payload)
+		s.will = will
+	}
+	return keepAlive, s.write(packet{kind: connack, body: []byte{0, 0}})
+}
+
+func (b *Broker) subscribe(s *session, p packet) error {
+	if len(p.body) < 2 {
+		return fmt.Errorf("truncated packet id")
+	}
+	codes := append([]byte{}, p.body[:2]...)
+	var filters []string
+	for rest := p.body[2:]; len(rest) > 0; {
+		filter, after, err := readString(rest)
+		if err != nil || len(after) < 1 {
+			return fmt.Errorf("truncated filter")
+		}
+		rest = after[1:]
+		filters = append(filters, filter)
+		codes = append(codes, 0)
+	}
+
+	b.mutex.Lock()
+	for _, f := range filters {
+		s.filters[f] = true
+	}
+	var retained []Message
+	for _, m := range b.retained {
+		for _, f := range filters {
+			if Match(f, m.Topic) {
+				retained = append(retained, m)
+				break
+			}
+		}
+	}
+	b.mutex.Unlock()
+
+	if err := s.write(packet{kind: suback, body: codes}); err != nil {
+		return err
+	}
+	for _, m := range retained {
+		s.write(publishPacket(m))
+	}
+	return nil
+}
+
+//route keeps the message when it is retained and delivers it to every matching subscription.
+func (b *Broker) route(m Message) {
+	b.mutex.Lock()
+	if m.Retain {
+		if b.retained == nil {
+			b.retained = make(map[string]Message)
+		}
+		// an empty retained message clears the topic
+		if len(m.Payload) == 0 {
+			delete(b.retained, m.Topic)
+		} else {
+			b.retained[m.Topic] = m
+		}
+	}
+	var to []*session
+	for s := range b.sessions {
+		for f := range s.filters {
+			if Match(f, m.Topic) {
+				to = append(to, s)
+				break
+			}
+		}
+	}
+	b.mutex.Unlock()
+
+	// the retain flag only goes out to new subscribers
+	m.Retain = false
+	for _, s := range to {
+		s.write(publishPacket(m))
+	}
+}
+
+func (s *session) write(p packet) error {
+	data, err := p.encode()
+	if err != nil {
+		return err
+	}
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	_, err = s.conn.Write(data)
+	return err
+}
+
+func (b *Broker) log() logrus.FieldLogger {
+	if b.Log == nil {
+		return logrus.StandardLogger()
+	}
+	return b.Log
+}
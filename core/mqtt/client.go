@@ -0,0 +1,382 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultKeepAlive = 30 * time.Second
+	DefaultPort      = "1883"
+	//connectTimeout bounds connecting when ctx has no deadline
+	connectTimeout = 10 * time.Second
+)
+
+//connackErrors are the reasons a broker gives for refusing a connection.
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+//Options are the CONNECT settings.
+type Options struct {
+	//ClientID names the session, empty lets the broker pick one
+	ClientID string
+	Username string
+	Password string
+	//KeepAlive is how often the connection is checked, 0 is DefaultKeepAlive
+	KeepAlive time.Duration
+	//Will is published by the broker when the connection is lost without Close
+	Will *Message
+}
+
+//Client is an MQTT 3.1.1 client that publishes and subscribes at QoS 0, which is all a bridge for state
+//that is republished on every change needs.
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMutex sync.Mutex
+	mutex      sync.Mutex
+	handlers   []handler
+	nextID     uint16
+	acks       map[uint16]chan packet
+	lastRead   time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+type handler struct {
+	filter string
+	handle func(Message)
+}
+
+//Dial connects to broker, an address like tcp://host:1883 or ssl://host:8883 where the scheme and port may
+//be left out, and waits for the broker to accept the connection.
+func Dial(ctx context.Context, broker string, o Options) (*Client, error) {
+	address, secure, err := parseBroker(broker)
+	if err != nil {
+		return nil, err
+	}
+	if _, has := ctx.Deadline(); !has {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, connectTimeout)
+		defer cancel()
+	}
+	var conn net.Conn
+	if secure {
+		host, _, _ := net.SplitHostPort(address)
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: host}}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keepAlive := o.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultKeepAlive
+	}
+	c := &Client{
+		conn:     conn,
+		reader:   bufio.NewReader(conn),
+		acks:     make(map[uint16]chan packet),
+		lastRead: time.Now(),
+		done:     make(chan struct{}),
+	}
+	if err := c.connect(ctx, o, keepAlive); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go c.read()
+	go c.keepAlive(keepAlive)
+	return c, nil
+}
+
+func parseBroker(broker string) (address string, secure bool, err error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Host == "" {
+		// host:port without a scheme parses as an opaque URL
+		u, err = url.Parse("tcp://" + broker)
+		if err != nil {
+			return "", false, fmt.Errorf("bad broker %q: %v", broker, err)
+		}
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		secure = true
+	default:
+		return "", false, fmt.Errorf("bad broker %q: unknown scheme %v", broker, u.Scheme)
+	}
+	port := u.Port()
+	if port == "" {
+		port = DefaultPort
+		if secure {
+			port = "8883"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), secure, nil
+}
+
+func (c *Client) connect(ctx context.Context, o Options, keepAlive time.Duration) error {
+	var flags byte = 0x02 // clean session
+	if o.Will != nil {
+		flags |= 0x04
+		if o.Will.Retain {
+			flags |= 0x20
+		}
+	}
+	if o.Username != "" {
+		flags |= 0x80
+	}
+	if o.Password != "" {
+		flags |= 0x40
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	seconds := int((keepAlive + time.Second - 1) / time.Second)
+	if seconds > 0xffff {
+		seconds = 0xffff
+	}
+	body = append(body, byte(seconds>>8), byte(seconds))
+	body = appendString(body, o.ClientID)
+	if o.Will != nil {
+		body = appendString(body, o.Will.Topic)
+		body = appendString(body, string(o.Will.Payload))
+	}
+	if o.Username != "" {
+		body = appendString(body, o.Username)
+	}
+	if o.Password != "" {
+		body = appendString(body, o.Password)
+	}
+
+	deadline, _ := ctx.Deadline()
+	c.conn.SetDeadline(deadline)
+	defer c.conn.SetDeadline(time.Time{})
+	if err := c.write(packet{kind: connect, body: body}); err != nil {
+		return err
+	}
+	p, err := readPacket(c.reader)
+	if err != nil {
+		return fmt.Errorf("no CONNACK: %v", err)
+	}
+	if p.kind != connack || len(p.body) != 2 {
+		return fmt.Errorf("expected CONNACK but got packet type %v", p.kind)
+	}
+	if code := p.body[1]; code != 0 {
+		reason, has := connackErrors[code]
+		if !has {
+			reason = fmt.Sprintf("return code %v", code)
+		}
+		return fmt.Errorf("connection refused: %v", reason)
+	}
+	return nil
+}
+
+func (c *Client) write(p packet) error {
+	data, err := p.encode()
+	if err != nil {
+		return err
+	}
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_, err = c.conn.Write(data)
+	return err
+}
+
+//Publish sends the message at QoS 0.
+func (c *Client) Publish(m Message) error {
+	if !ValidTopic(m.Topic) {
+		return fmt.Errorf("can not publish to %q", m.Topic)
+	}
+	select {
+	case <-c.done:
+		return c.Err()
+	default:
+	}
+	return c.write(publishPacket(m))
+}
+
+//Subscribe calls handle with every message whose topic matches filter until the connection ends. handle
+//runs on the goroutine reading the connection, so it should hand slow work off and must not call
+//Subscribe or Unsubscribe itself.
+func (c *Client) Subscribe(ctx context.Context, filter string, handle func(Message)) error {
+	c.mutex.Lock()
+	// added first as the broker sends the retained messages right after SUBACK
+	c.handlers = append(c.handlers, handler{filter: filter, handle: handle})
+	c.mutex.Unlock()
+
+	ack, err := c.request(ctx, subscribe, append(appendString(nil, filter), 0))
+	if err == nil && (len(ack) != 1 || ack[0] == 0x80) {
+		err = fmt.Errorf("subscribing to %q was refused", filter)
+	}
+	if err != nil {
+		c.removeHandlers(filter)
+		return err
+	}
+	return nil
+}
+
+//Unsubscribe stops the handlers of filter.
+func (c *Client) Unsubscribe(ctx context.Context, filter string) error {
+	c.removeHandlers(filter)
+	_, err := c.request(ctx, unsubscribe, appendString(nil, filter))
+	return err
+}
+
+func (c *Client) removeHandlers(filter string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	kept := c.handlers[:0]
+	for _, h := range c.handlers {
+		if h.filter != filter {
+			kept = append(kept, h)
+		}
+	}
+	c.handlers = kept
+}
+
+//request sends a SUBSCRIBE or UNSUBSCRIBE and returns the body of its acknowledgement after the id.
+func (c *Client) request(ctx context.Context, kind byte, payload []byte) ([]byte, error) {
+	c.mutex.Lock()
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID++
+	}
+	id := c.nextID
+	ack := make(chan packet, 1)
+	c.acks[id] = ack
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		delete(c.acks, id)
+	}()
+
+	body := append([]byte{byte(id >> 8), byte(id)}, payload...)
+	// SUBSCRIBE and UNSUBSCRIBE have the reserved flags 0010
+	if err := c.write(packet{kind: kind, flags: 2, body: body}); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, c.Err()
+	case p := <-ack:
+		return p.body[2:], nil
+	}
+}
+
+func (c *Client) read() {
+	for {
+		p, err := readPacket(c.reader)
+		if err != nil {
+			c.close(err)
+			return
+		}
+		c.mutex.Lock()
+		c.lastRead = time.Now()
+		c.mutex.Unlock()
+
+		switch p.kind {
+		case publish:
+			m, qos, id, err := parsePublish(p)
+			if err != nil {
+				c.close(err)
+				return
+			}
+			if qos == 1 {
+				c.write(packet{kind: puback, body: []byte{byte(id >> 8), byte(id)}})
+			}
+			c.mutex.Lock()
+			handlers := append([]handler{}, c.handlers...)
+			c.mutex.Unlock()
+			for _, h := range handlers {
+				if Match(h.filter, m.Topic) {
+					h.handle(m)
+				}
+			}
+		case suback, unsuback:
+			if len(p.body) < 2 {
+				continue
+			}
+			c.mutex.Lock()
+			ack, has := c.acks[binary.BigEndian.Uint16(p.body)]
+			c.mutex.Unlock()
+			if has {
+				ack <- p
+			}
+		}
+	}
+}
+
+//keepAlive pings the broker and gives up on it when it stops answering.
+func (c *Client) keepAlive(keepAlive time.Duration) {
+	ticker := time.NewTicker(keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+		c.mutex.Lock()
+		silent := time.Since(c.lastRead)
+		c.mutex.Unlock()
+		if silent > keepAlive*3/2 {
+			c.close(fmt.Errorf("broker stopped answering"))
+			return
+		}
+		if err := c.write(packet{kind: pingreq}); err != nil {
+			c.close(err)
+			return
+		}
+	}
+}
+
+func (c *Client) close(err error) {
+	c.closeOnce.Do(func() {
+		c.mutex.Lock()
+		c.err = err
+		c.mutex.Unlock()
+		c.conn.Close()
+		close(c.done)
+	})
+}
+
+//Close disconnects cleanly, so the broker doesn't publish the will.
+func (c *Client) Close() error {
+	err := c.write(packet{kind: disconnect})
+	c.close(fmt.Errorf("closed"))
+	return err
+}
+
+//Done is closed when the connection ends.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+//Err is why the connection ended, nil while it is up.
+func (c *Client) Err() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.err
+}
@@ -0,0 +1,136 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	for _, c := range []struct {
+		filter, topic string
+		match         bool
+	}{
+		{"lifx/+/set", "lifx/d073d500000100/set", true},
+		{"lifx/+/set", "lifx/d073d500000100/power/set", false},
+		{"lifx/#", "lifx", true},
+		{"lifx/#", "lifx/a/b/c", true},
+		{"#", "$SYS/uptime", false},
+		{"+/uptime", "$SYS/uptime", false},
+		{"lifx/bridge", "lifx/bridge/status", false},
+		{"lifx/+", "lifx/", true},
+	} {
+		if got := Match(c.filter, c.topic); got != c.match {
+			t.Errorf("Match(%q, %q) = %v", c.filter, c.topic, got)
+		}
+	}
+}
+
+func startBroker(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go (&Broker{}).Serve(l)
+	return "tcp://" + l.Addr().String()
+}
+
+//receive returns the next message or fails the test if none comes.
+func receive(t *testing.T, messages chan Message) Message {
+	t.Helper()
+	select {
+	case m := <-messages:
+		return m
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected a message")
+		return Message{}
+	}
+}
+
+func TestClientBroker(t *testing.T) {
+	broker := startBroker(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	publisher, err := Dial(ctx, broker, Options{ClientID: "publisher", Will: &Message{Topic: "test/status", Payload: []byte("offline"), Retain: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.Publish(Message{Topic: "test/status", Payload: []byte("online"), Retain: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.Publish(Message{Topic: "test/+", Payload: []byte("x")}); err == nil {
+		t.Errorf("expected publishing to a wildcard to fail")
+	}
+
+	subscriber, err := Dial(ctx, broker, Options{ClientID: "subscriber", KeepAlive: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscriber.Close()
+	messages := make(chan Message, 10)
+	// the publisher's messages on one connection are in order so the retained one comes first
+	time.Sleep(50 * time.Millisecond)
+	if err := subscriber.Subscribe(ctx, "test/#", func(m Message) { messages <- m }); err != nil {
+		t.Fatal(err)
+	}
+	if m := receive(t, messages); m.Topic != "test/status" || string(m.Payload) != "online" || !m.Retain {
+		t.Errorf("expected the retained status but got %v", m)
+	}
+
+	publisher.Publish(Message{Topic: "test/light/state", Payload: []byte(`{"state":"ON"}`)})
+	if m := receive(t, messages); m.Topic != "test/light/state" || m.Retain {
+		t.Errorf("unexpected %v", m)
+	}
+
+	// dropping the connection without DISCONNECT publishes the will
+	publisher.conn.Close()
+	if m := receive(t, messages); m.Topic != "test/status" || string(m.Payload) != "offline" {
+		t.Errorf("expected the will but got %v", m)
+	}
+
+	// keep alive pings keep an idle connection up
+	time.Sleep(2 * time.Second)
+	if err := subscriber.Err(); err != nil {
+		t.Errorf("expected the connection up but got %v", err)
+	}
+	if err := subscriber.Unsubscribe(ctx, "test/#"); err != nil {
+		t.Fatal(err)
+	}
+	subscriber.Publish(Message{Topic: "test/status", Payload: []byte("again")})
+	select {
+	case m := <-messages:
+		t.Errorf("expected nothing after unsubscribing but got %v", m)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestParseBroker(t *testing.T) {
+	if _, _, err := parseBroker("http://example.com"); err == nil {
+		t.Errorf("expected an unknown scheme to fail")
+	}
+	if address, secure, err := parseBroker("ssl://broker.local"); err != nil || !secure || address != "broker.local:8883" {
+		t.Errorf("unexpected %v %v %v", address, secure, err)
+	}
+	if address, _, err := parseBroker("localhost:1884"); err != nil || address != "localhost:1884" {
+		t.Errorf("unexpected %v %v", address, err)
+	}
+}
+
+func TestReadPacket_TooLarge(t *testing.T) {
+	// a PUBLISH claiming 2MB, refused before anything is allocated for it
+	if _, err := readPacket(bufio.NewReader(bytes.NewReader([]byte{0x30, 0x80, 0x80, 0x80, 0x01}))); err == nil {
+		t.Errorf("expected a packet over the limit to fail")
+	}
+	if _, err := (packet{kind: publish, body: make([]byte, maxPacket+1)}).encode(); err == nil {
+		t.Errorf("expected encoding a packet over the limit to fail")
+	}
+	p, err := readPacket(bufio.NewReader(bytes.NewReader([]byte{0xd0, 0x00})))
+	if err != nil || p.kind != pingresp {
+		t.Errorf("unexpected %+v %v", p, err)
+	}
+}
@@ -0,0 +1,165 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//The control packet types of MQTT 3.1.1, the ones a QoS 0 client and broker need.
+const (
+	connect     = 1
+	connack     = 2
+	publish     = 3
+	puback      = 4
+	subscribe   = 8
+	suback      = 9
+	unsubscribe = 10
+	unsuback    = 11
+	pingreq     = 12
+	pingresp    = 13
+	disconnect  = 14
+)
+
+//maxPacket is the largest remaining length read or sent. The protocol allows 256MB, which a broker could
+//make the reader allocate, while a bridge's messages are a few hundred bytes.
+const maxPacket = 1 << 20
+
+//packet is one control packet, body is everything after the fixed header.
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, fmt.Errorf("malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if length > maxPacket {
+		return packet{}, fmt.Errorf("packet of %v bytes is larger than the %v allowed", length, maxPacket)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{kind: first >> 4, flags: first & 0x0f, body: body}, nil
+}
+
+func (p packet) encode() ([]byte, error) {
+	length := len(p.body)
+	if length > maxPacket {
+		return nil, fmt.Errorf("packet of %v bytes is too large", length)
+	}
+	data := []byte{p.kind<<4 | p.flags}
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		data = append(data, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(data, p.body...), nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
+
+//readString returns the length prefixed string at the start of b and what follows it.
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, fmt.Errorf("truncated string")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, fmt.Errorf("truncated string")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+//Message is a published payload.
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+func (m Message) String() string {
+	return fmt.Sprintf("Message{ %v %q Retain:%v }", m.Topic, m.Payload, m.Retain)
+}
+
+//publishPacket builds a QoS 0 PUBLISH.
+func publishPacket(m Message) packet {
+	p := packet{kind: publish, body: append(appendString(nil, m.Topic), m.Payload...)}
+	if m.Retain {
+		p.flags = 1
+	}
+	return p
+}
+
+//parsePublish reads a PUBLISH of any QoS, id is 0 for QoS 0.
+func parsePublish(p packet) (m Message, qos byte, id uint16, err error) {
+	qos = (p.flags >> 1) & 3
+	m.Retain = p.flags&1 != 0
+	var rest []byte
+	if m.Topic, rest, err = readString(p.body); err != nil {
+		return
+	}
+	if qos > 0 {
+		if len(rest) < 2 {
+			err = fmt.Errorf("truncated packet id")
+			return
+		}
+		id, rest = binary.BigEndian.Uint16(rest), rest[2:]
+	}
+	m.Payload = rest
+	return
+}
+
+//ValidTopic reports if topic can be published to, it needs to be non-empty and without wildcards.
+func ValidTopic(topic string) bool {
+	return topic != "" && !strings.ContainsAny(topic, "+#")
+}
+
+//Match reports if the topic matches the filter, where + matches one level and a trailing # any number
+//of levels including none.
+func Match(filter, topic string) bool {
+	filters, topics := strings.Split(filter, "/"), strings.Split(topic, "/")
+	// wildcards at the start don't match the topics starting with $ like $SYS
+	if strings.HasPrefix(topic, "$") && (filters[0] == "+" || filters[0] == "#") {
+		return false
+	}
+	for i, f := range filters {
+		if f == "#" {
+			return i == len(filters)-1
+		}
+		if i >= len(topics) || (f != "+" && f != topics[i]) {
+			return false
+		}
+	}
+	return len(filters) == len(topics)
+}
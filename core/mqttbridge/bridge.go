@@ -0,0 +1,319 @@
+package mqttbridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/effect"
+	"github.com/nathanhack/lifx/core/events"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/mqtt"
+	"github.com/nathanhack/lifx/core/products"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPrefix          = "lifx"
+	DefaultDiscoveryPrefix = "homeassistant"
+	//retryInterval is how often lights that could not be added are tried again
+	retryInterval = 30 * time.Second
+)
+
+//requestTimeout bounds the LAN requests made for one event or command, a variable for the tests
+var requestTimeout = 10 * time.Second
+
+//Bridge publishes the lights' state to MQTT and changes them on commands. For a light with TARGET_HEXSTR
+//id under Prefix:
+//
+//	PREFIX/bridge/status          online, or offline when the bridge stops (set it as the will too)
+//	PREFIX/id/state               JSON state in the form of Home Assistant's JSON schema, retained
+//	PREFIX/id/availability        online or offline, retained
+//	PREFIX/id/set                 JSON command in the form of Home Assistant's JSON schema
+//	PREFIX/id/power/set           on or off
+//	PREFIX/id/color/set           a color as the light commands take it, e.g. "blue brightness:50%"
+//	PREFIX/id/brightness/set      percent
+//	PREFIX/id/effect/set          one of effect.Names(), or none to stop the effect
+//
+//Unless NoDiscovery is set, a Home Assistant discovery config describing what the product can do is
+//published for every light under DiscoveryPrefix.
+type Bridge struct {
+	MQTT *mqtt.Client
+	//Watcher finds the lights and reports their changes, the bridge runs it
+	Watcher *events.Watcher
+	//Prefix starts every topic of the bridge, empty is DefaultPrefix
+	Prefix string
+	//DiscoveryPrefix is where Home Assistant looks for discovery configs, empty is DefaultDiscoveryPrefix
+	DiscoveryPrefix string
+	NoDiscovery     bool
+	//Log gets the commands and what failed, nil uses logrus' standard logger
+	Log logrus.FieldLogger
+
+	mutex  sync.Mutex
+	lights map[string]*bridged
+	//pending are the lights whose version request failed, they are added on their next event or retry
+	pending map[string]bool
+}
+
+//bridged is what the bridge knows of one light.
+type bridged struct {
+	light   *client.Light
+	product products.Product
+	label   string
+	power   bool
+	color   hsbk.HSBK
+	effect  string
+	running *effect.Running
+}
+
+//StatusTopic is where the bridge says if it is online, the topic for the connection's will.
+func (b *Bridge) StatusTopic() string {
+	return b.prefix() + "/bridge/status"
+}
+
+//Run bridges until ctx is done or the MQTT connection is lost.
+func (b *Bridge) Run(ctx context.Context) error {
+	subscribed, unsubscribe := b.Watcher.Subscribe()
+	defer unsubscribe()
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	defer stopWatching()
+	go b.Watcher.Run(watchCtx)
+
+	for _, filter := range []string{b.prefix() + "/+/set", b.prefix() + "/+/+/set"} {
+		if err := b.MQTT.Subscribe(ctx, filter, b.command); err != nil {
+			return err
+		}
+	}
+	if err := b.publish(b.StatusTopic(), "online"); err != nil {
+		return err
+	}
+	defer func() {
+		b.mutex.Lock()
+		for _, l := range b.lights {
+			if l.running != nil {
+				l.running.Stop()
+			}
+		}
+		b.mutex.Unlock()
+		b.publish(b.StatusTopic(), "offline")
+	}()
+
+	retry := time.NewTicker(retryInterval)
+	defer retry.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-retry.C:
+			b.mutex.Lock()
+			pending := make([]string, 0, len(b.pending))
+			for id := range b.pending {
+				pending = append(pending, id)
+			}
+			b.mutex.Unlock()
+			for _, id := range pending {
+				b.retry(ctx, id)
+			}
+		case <-b.MQTT.Done():
+			return fmt.Errorf("lost the MQTT connection: %v", b.MQTT.Err())
+		case e, open := <-subscribed:
+			if !open {
+				return nil
+			}
+			b.event(ctx, e)
+		}
+	}
+}
+
+//event publishes what the event changed.
+func (b *Bridge) event(ctx context.Context, e events.Event) {
+	log := b.log().WithField("light", e.ID)
+	b.mutex.Lock()
+	l, known := b.lights[e.ID]
+	b.mutex.Unlock()
+
+	switch e.Type {
+	case events.Added:
+		if !known {
+			b.addAndPublish(ctx, e)
+			return
+		}
+		b.update(l, e)
+		b.publishAll(log, e.ID, l)
+	case events.Reachable:
+		if known {
+			b.update(l, e)
+			b.publishAll(log, e.ID, l)
+		} else {
+			b.retry(ctx, e.ID)
+		}
+	case events.Unreachable, events.Removed:
+		if known {
+			if err := b.publish(b.topic(e.ID, "availability"), "offline"); err != nil {
+				log.WithError(err).Warn("could not publish availability")
+			}
+		}
+		if e.Type == events.Removed {
+			b.mutex.Lock()
+			delete(b.pending, e.ID)
+			b.mutex.Unlock()
+		}
+	default:
+		if !known {
+			b.retry(ctx, e.ID)
+			return
+		}
+		b.update(l, e)
+		if e.Type == events.Label && !b.NoDiscovery {
+			// the name in Home Assistant follows the label
+			b.publishDiscovery(log, e.ID, l)
+		}
+		b.publishState(log, e.ID, l)
+	}
+}
+
+//addAndPublish starts bridging the light of an Added event. When its version request fails, a routine
+//UDP loss, the light is kept pending and tried again.
+func (b *Bridge) addAndPublish(ctx context.Context, e events.Event) {
+	log := b.log().WithField("light", e.ID)
+	l, err := b.add(ctx, e.ID)
+	b.mutex.Lock()
+	if b.pending == nil {
+		b.pending = make(map[string]bool)
+	}
+	if err != nil {
+		b.pending[e.ID] = true
+	} else {
+		delete(b.pending, e.ID)
+	}
+	b.mutex.Unlock()
+	if err != nil {
+		log.WithError(err).Warn("could not add light, will try again")
+		return
+	}
+	if l == nil {
+		// a switch
+		return
+	}
+	b.update(l, e)
+	b.publishAll(log, e.ID, l)
+}
+
+//retry adds a pending light with the state the watcher has for it, if it is reachable.
+func (b *Bridge) retry(ctx context.Context, id string) {
+	b.mutex.Lock()
+	pending := b.pending[id]
+	b.mutex.Unlock()
+	if !pending {
+		return
+	}
+	for _, e := range b.Watcher.Snapshot() {
+		if e.ID == id {
+			b.addAndPublish(ctx, e)
+			return
+		}
+	}
+}
+
+//add looks up what the light can do and starts bridging it, switches are left out and give nil.
+func (b *Bridge) add(ctx context.Context, id string) (*bridged, error) {
+	l, has := b.Watcher.Light(id)
+	if !has {
+		return nil, fmt.Errorf("the light is gone")
+	}
+	versionCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	version, err := l.Version(versionCtx)
+	if err != nil {
+		return nil, err
+	}
+	product, _ := products.Lookup(version.Vendor, version.Product)
+	if !product.IsLight() {
+		return nil, nil
+	}
+
+	added := &bridged{light: l, product: product}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.lights == nil {
+		b.lights = make(map[string]*bridged)
+	}
+	b.lights[id] = added
+	return added, nil
+}
+
+//update folds the event's fields into what the bridge knows of the light.
+func (b *Bridge) update(l *bridged, e events.Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if e.Label != "" || e.Type == events.Label {
+		l.label = e.Label
+	}
+	if e.Power != "" {
+		l.power = e.Power == "on"
+	}
+	if e.Color != nil {
+		l.color = *e.Color
+	}
+}
+
+func (b *Bridge) publishAll(log logrus.FieldLogger, id string, l *bridged) {
+	if !b.NoDiscovery {
+		b.publishDiscovery(log, id, l)
+	}
+	if err := b.publish(b.topic(id, "availability"), "online"); err != nil {
+		log.WithError(err).Warn("could not publish availability")
+	}
+	b.publishState(log, id, l)
+}
+
+func (b *Bridge) publishState(log logrus.FieldLogger, id string, l *bridged) {
+	b.mutex.Lock()
+	s := newState(l)
+	b.mutex.Unlock()
+	if err := b.publishJSON(b.topic(id, "state"), s); err != nil {
+		log.WithError(err).Warn("could not publish state")
+	}
+}
+
+func (b *Bridge) publishDiscovery(log logrus.FieldLogger, id string, l *bridged) {
+	b.mutex.Lock()
+	config := b.discovery(id, l)
+	b.mutex.Unlock()
+	if err := b.publishJSON(b.discoveryTopic(id), config); err != nil {
+		log.WithError(err).Warn("could not publish discovery config")
+	}
+}
+
+func (b *Bridge) publish(topic, payload string) error {
+	return b.MQTT.Publish(mqtt.Message{Topic: topic, Payload: []byte(payload), Retain: true})
+}
+
+func (b *Bridge) publishJSON(topic string, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.publish(topic, string(payload))
+}
+
+func (b *Bridge) topic(id string, parts ...string) string {
+	return strings.Join(append([]string{b.prefix(), id}, parts...), "/")
+}
+
+func (b *Bridge) prefix() string {
+	if b.Prefix == "" {
+		return DefaultPrefix
+	}
+	return b.Prefix
+}
+
+func (b *Bridge) log() logrus.FieldLogger {
+	if b.Log == nil {
+		return logrus.StandardLogger()
+	}
+	return b.Log
+}
@@ -0,0 +1,207 @@
+package mqttbridge
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/events"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/mqtt"
+	"github.com/nathanhack/lifx/core/server"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//retained keeps the last message of every topic like a broker's retained messages.
+type retained struct {
+	mutex    sync.Mutex
+	messages map[string]string
+}
+
+func (r *retained) handle(m mqtt.Message) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.messages[m.Topic] = string(m.Payload)
+}
+
+//waitFor returns the topic's payload once check accepts it, failing the test if that takes too long.
+func (r *retained) waitFor(t *testing.T, topic string, check func(string) bool) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mutex.Lock()
+		payload, has := r.messages[topic]
+		r.mutex.Unlock()
+		if has && check(payload) {
+			return payload
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	t.Fatalf("%v: expected a different payload than %q", topic, r.messages[topic])
+	return ""
+}
+
+func TestBridge(t *testing.T) {
+	color := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Label: "Kitchen", Vendor: 1, Product: 27,
+		Color: hsbk.HSBK{Brightness: 0xffff, Kelvin: 3500}}
+	white := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 2, 0}, Label: "Hall", Vendor: 1, Product: 50,
+		Color: hsbk.HSBK{Brightness: 0x8000, Kelvin: 2700}}
+	button := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 3, 0}, Label: "Switch", Vendor: 1, Product: 70}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, color, white, button)
+	c := client.New(ctx, out, in)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go (&mqtt.Broker{}).Serve(l)
+	broker := "tcp://" + l.Addr().String()
+
+	observer, err := mqtt.Dial(ctx, broker, mqtt.Options{ClientID: "observer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer observer.Close()
+	seen := &retained{messages: make(map[string]string)}
+	if err := observer.Subscribe(ctx, "#", seen.handle); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Bridge{Watcher: &events.Watcher{Client: c, Interval: 200 * time.Millisecond, Wait: 200 * time.Millisecond}}
+	b.MQTT, err = mqtt.Dial(ctx, broker, mqtt.Options{ClientID: "bridge", Will: &mqtt.Message{Topic: b.StatusTopic(), Payload: []byte("offline"), Retain: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bridgeCtx, stop := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- b.Run(bridgeCtx) }()
+
+	seen.waitFor(t, "lifx/bridge/status", func(p string) bool { return p == "online" })
+	var d Discovery
+	json.Unmarshal([]byte(seen.waitFor(t, "homeassistant/light/lifx_d073d500000100/config", func(string) bool { return true })), &d)
+	if d.Name != "Kitchen" || d.Schema != "json" || d.CommandTopic != "lifx/d073d500000100/set" || len(d.SupportedColorModes) != 2 ||
+		!d.Effect || d.Device.Model != "LIFX A19" || d.MinKelvin != 2500 {
+		t.Errorf("unexpected %+v", d)
+	}
+	d = Discovery{}
+	json.Unmarshal([]byte(seen.waitFor(t, "homeassistant/light/lifx_d073d500000200/config", func(string) bool { return true })), &d)
+	if d.Name != "Hall" || len(d.SupportedColorModes) != 1 || d.SupportedColorModes[0] != "color_temp" || d.Effect || d.MaxKelvin != 4000 {
+		t.Errorf("unexpected %+v", d)
+	}
+	seen.waitFor(t, "lifx/d073d500000100/availability", func(p string) bool { return p == "online" })
+	seen.waitFor(t, "lifx/d073d500000200/state", func(p string) bool {
+		return strings.Contains(p, `"state":"OFF"`) && strings.Contains(p, `"color_temp":2700`) && strings.Contains(p, `"brightness":128`)
+	})
+
+	// the simple topics and Home Assistant's JSON ones
+	b.MQTT.Publish(mqtt.Message{Topic: "lifx/d073d500000200/power/set", Payload: []byte("on")})
+	seen.waitFor(t, "lifx/d073d500000200/state", func(p string) bool { return strings.Contains(p, `"state":"ON"`) })
+	b.MQTT.Publish(mqtt.Message{Topic: "lifx/d073d500000100/set", Payload: []byte(`{"state":"ON","color":{"h":240,"s":100},"brightness":255}`)})
+	seen.waitFor(t, "lifx/d073d500000100/state", func(p string) bool {
+		return strings.Contains(p, `"state":"ON"`) && strings.Contains(p, `"color_mode":"hs"`) && strings.Contains(p, `"h":240`)
+	})
+	b.MQTT.Publish(mqtt.Message{Topic: "lifx/d073d500000100/color/set", Payload: []byte("kelvin:4000 saturation:0")})
+	seen.waitFor(t, "lifx/d073d500000100/state", func(p string) bool { return strings.Contains(p, `"color_temp":4000`) })
+	b.MQTT.Publish(mqtt.Message{Topic: "lifx/d073d500000100/brightness/set", Payload: []byte("50")})
+	seen.waitFor(t, "lifx/d073d500000100/state", func(p string) bool { return strings.Contains(p, `"brightness":128`) })
+
+	b.MQTT.Publish(mqtt.Message{Topic: "lifx/d073d500000100/effect/set", Payload: []byte("candle")})
+	seen.waitFor(t, "lifx/d073d500000100/state", func(p string) bool { return strings.Contains(p, `"effect":"candle"`) })
+	b.MQTT.Publish(mqtt.Message{Topic: "lifx/d073d500000100/effect/set", Payload: []byte("none")})
+	seen.waitFor(t, "lifx/d073d500000100/state", func(p string) bool { return strings.Contains(p, `"effect":"none"`) })
+
+	seen.mutex.Lock()
+	for topic := range seen.messages {
+		if strings.Contains(topic, "d073d500000300") {
+			t.Errorf("expected the switch left out but got %v", topic)
+		}
+	}
+	seen.mutex.Unlock()
+
+	stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	seen.waitFor(t, "lifx/bridge/status", func(p string) bool { return p == "offline" })
+}
+
+func TestBridge_VersionLost(t *testing.T) {
+	defer func(timeout time.Duration) { requestTimeout = timeout }(requestTimeout)
+	requestTimeout = 300 * time.Millisecond
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Label: "Kitchen", Vendor: 1, Product: 27,
+		Color: hsbk.HSBK{Brightness: 0xffff, Kelvin: 3500}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, bulb)
+	// loses every GetVersion until told to stop
+	var losing int32 = 1
+	lossy := make(chan *server.OutBoundPayload, 10)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case p := <-lossy:
+				if h, err := header.Decode(p.Data); err == nil && h.Type() == device.GetVersionType && atomic.LoadInt32(&losing) == 1 {
+					p.Done()
+					continue
+				}
+				out <- p
+			}
+		}
+	}()
+	c := client.New(ctx, lossy, in)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go (&mqtt.Broker{}).Serve(l)
+	broker := "tcp://" + l.Addr().String()
+	observer, err := mqtt.Dial(ctx, broker, mqtt.Options{ClientID: "observer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer observer.Close()
+	seen := &retained{messages: make(map[string]string)}
+	if err := observer.Subscribe(ctx, "#", seen.handle); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Bridge{Watcher: &events.Watcher{Client: c, Interval: 200 * time.Millisecond, Wait: 200 * time.Millisecond}}
+	if b.MQTT, err = mqtt.Dial(ctx, broker, mqtt.Options{ClientID: "bridge"}); err != nil {
+		t.Fatal(err)
+	}
+	go b.Run(ctx)
+
+	for pending := false; !pending; time.Sleep(20 * time.Millisecond) {
+		if ctx.Err() != nil {
+			t.Fatal("expected the light pending after its version was lost")
+		}
+		b.mutex.Lock()
+		pending = b.pending["d073d500000100"]
+		b.mutex.Unlock()
+	}
+	atomic.StoreInt32(&losing, 0)
+	// the next event for the light adds it
+	if err := c.Light(bulb.Target, bulb.Address).SetPower(ctx, true, 0); err != nil {
+		t.Fatal(err)
+	}
+	seen.waitFor(t, "lifx/d073d500000100/availability", func(p string) bool { return p == "online" })
+	seen.waitFor(t, "lifx/d073d500000100/state", func(p string) bool {
+		return strings.Contains(p, `"state":"ON"`) && strings.Contains(p, `"label":"Kitchen"`)
+	})
+}
@@ -0,0 +1,158 @@
+package mqttbridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/effect"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/mqtt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//command handles a message on a command topic. It runs on the MQTT client's reading goroutine so the
+//LAN requests are made on their own.
+func (b *Bridge) command(m mqtt.Message) {
+	parts := strings.Split(strings.TrimPrefix(m.Topic, b.prefix()+"/"), "/")
+	if len(parts) < 2 || parts[len(parts)-1] != "set" {
+		return
+	}
+	id, kind := parts[0], strings.Join(parts[1:len(parts)-1], "/")
+	log := b.log().WithField("light", id)
+	b.mutex.Lock()
+	l, has := b.lights[id]
+	b.mutex.Unlock()
+	if !has {
+		log.Warnf("command for an unknown light on %v", m.Topic)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		if err := b.execute(ctx, l, kind, string(m.Payload)); err != nil {
+			log.WithError(err).Warnf("%v %q failed", m.Topic, m.Payload)
+			return
+		}
+		log.Infof("%v %q", m.Topic, m.Payload)
+
+		// answers right away instead of at the next poll
+		state, err := l.light.State(ctx)
+		if err != nil {
+			return
+		}
+		b.mutex.Lock()
+		l.label, l.power, l.color = fields.Label(state.Label), state.Power != 0, state.Color
+		b.mutex.Unlock()
+		b.publishState(log, id, l)
+	}()
+}
+
+//execute carries out the command, kind is empty for the JSON command and the name of the field otherwise.
+func (b *Bridge) execute(ctx context.Context, l *bridged, kind, payload string) error {
+	var c Command
+	switch kind {
+	case "":
+		if err := json.Unmarshal([]byte(payload), &c); err != nil {
+			return fmt.Errorf("bad command: %v", err)
+		}
+	case "power":
+		c.State = strings.ToUpper(payload)
+		if c.State != "ON" && c.State != "OFF" {
+			return fmt.Errorf("expected on or off")
+		}
+	case "brightness":
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(payload, "%"), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return fmt.Errorf("expected a brightness from 0 to 100")
+		}
+		brightness := percent * 255 / 100
+		c.Brightness = &brightness
+	case "effect":
+		c.Effect = payload
+	case "color":
+		spec, err := hsbk.Parse(payload)
+		if err != nil {
+			return err
+		}
+		return b.setColor(ctx, l, spec, 0)
+	default:
+		return fmt.Errorf("unknown command %v", kind)
+	}
+
+	transition := time.Duration(c.Transition * float64(time.Second))
+	if c.Effect != "" {
+		if err := b.setEffect(l, c.Effect); err != nil {
+			return err
+		}
+	}
+	if spec := c.spec(); spec.Set != 0 {
+		if err := b.setColor(ctx, l, spec, transition); err != nil {
+			return err
+		}
+	}
+	switch c.State {
+	case "ON":
+		return l.light.SetPower(ctx, true, transition)
+	case "OFF":
+		// an effect would turn the light back on
+		b.setEffect(l, noEffect)
+		return l.light.SetPower(ctx, false, transition)
+	}
+	return nil
+}
+
+//setColor applies the spec to the light's current color.
+func (b *Bridge) setColor(ctx context.Context, l *bridged, spec hsbk.Spec, transition time.Duration) error {
+	color := spec.HSBK
+	if !spec.Complete() {
+		state, err := l.light.State(ctx)
+		if err != nil {
+			return err
+		}
+		color = spec.Apply(state.Color)
+	}
+	return l.light.SetColor(ctx, color, transition)
+}
+
+//setEffect stops the running effect and starts the named one, none only stops. Stopping restores the
+//light to how it was before the effect.
+func (b *Bridge) setEffect(l *bridged, name string) error {
+	var e effect.Effect
+	if name != noEffect {
+		var err error
+		if e, err = effect.Named(name, effect.Params{}); err != nil {
+			return err
+		}
+	}
+
+	b.mutex.Lock()
+	running := l.running
+	l.running, l.effect = nil, ""
+	b.mutex.Unlock()
+	if running != nil {
+		running.Stop()
+	}
+	if e == nil {
+		return nil
+	}
+
+	// the effect outlives the command
+	running = effect.Start(context.Background(), e, []*client.Light{l.light}, effect.Options{Restore: true})
+	b.mutex.Lock()
+	l.running, l.effect = running, name
+	b.mutex.Unlock()
+	go func() {
+		<-running.Done()
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		if l.running == running {
+			l.running, l.effect = nil, ""
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,173 @@
+package mqttbridge
+
+import (
+	"github.com/nathanhack/lifx/core/effect"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"math"
+)
+
+//noEffect is the effect Home Assistant shows and sends when none is running.
+const noEffect = "none"
+
+//State is the payload of PREFIX/id/state, Home Assistant's JSON schema for lights. Brightness is 0 to 255,
+//the hue is in degrees and the saturation in percent.
+type State struct {
+	State      string `json:"state"`
+	Brightness int    `json:"brightness"`
+	ColorMode  string `json:"color_mode"`
+	Color      *HS    `json:"color,omitempty"`
+	//ColorTemp is in kelvin
+	ColorTemp uint16 `json:"color_temp,omitempty"`
+	Effect    string `json:"effect,omitempty"`
+	Label     string `json:"label"`
+}
+
+//HS is a color as Home Assistant's hs color mode has it.
+type HS struct {
+	H float64 `json:"h"`
+	S float64 `json:"s"`
+}
+
+func newState(l *bridged) State {
+	c := l.color.Color()
+	s := State{
+		State:      "OFF",
+		Brightness: int(math.Round(c.Brightness * 255)),
+		ColorMode:  colorModes(l)[0],
+		Label:      l.label,
+	}
+	if l.power {
+		s.State = "ON"
+	}
+	if l.product.Color && l.color.Saturation > 0 {
+		s.ColorMode = "hs"
+		s.Color = &HS{H: round(c.Hue), S: round(c.Saturation * 100)}
+	} else if l.product.VariableKelvin() {
+		s.ColorTemp = l.color.Kelvin
+	}
+	if l.product.Color {
+		s.Effect = noEffect
+		if l.effect != "" {
+			s.Effect = l.effect
+		}
+	}
+	return s
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+//colorModes are the color modes of the product, the one for whites first.
+func colorModes(l *bridged) []string {
+	switch {
+	case l.product.VariableKelvin() && l.product.Color:
+		return []string{"color_temp", "hs"}
+	case l.product.VariableKelvin():
+		return []string{"color_temp"}
+	case l.product.Color:
+		return []string{"hs"}
+	}
+	return []string{"brightness"}
+}
+
+//Discovery is a Home Assistant MQTT discovery config for one light.
+type Discovery struct {
+	Name                string         `json:"name"`
+	UniqueID            string         `json:"unique_id"`
+	Schema              string         `json:"schema"`
+	CommandTopic        string         `json:"command_topic"`
+	StateTopic          string         `json:"state_topic"`
+	Availability        []Availability `json:"availability"`
+	AvailabilityMode    string         `json:"availability_mode"`
+	Brightness          bool           `json:"brightness"`
+	SupportedColorModes []string       `json:"supported_color_modes"`
+	ColorTempKelvin     bool           `json:"color_temp_kelvin,omitempty"`
+	MinKelvin           uint16         `json:"min_kelvin,omitempty"`
+	MaxKelvin           uint16         `json:"max_kelvin,omitempty"`
+	Effect              bool           `json:"effect,omitempty"`
+	EffectList          []string       `json:"effect_list,omitempty"`
+	Device              Device         `json:"device"`
+}
+
+//Availability is one topic saying if the light can be used.
+type Availability struct {
+	Topic string `json:"topic"`
+}
+
+//Device groups the light's entities in Home Assistant.
+type Device struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+func (b *Bridge) discoveryTopic(id string) string {
+	prefix := b.DiscoveryPrefix
+	if prefix == "" {
+		prefix = DefaultDiscoveryPrefix
+	}
+	return prefix + "/light/lifx_" + id + "/config"
+}
+
+func (b *Bridge) discovery(id string, l *bridged) Discovery {
+	name := l.label
+	if name == "" {
+		name = id
+	}
+	d := Discovery{
+		Name:                name,
+		UniqueID:            "lifx_" + id,
+		Schema:              "json",
+		CommandTopic:        b.topic(id, "set"),
+		StateTopic:          b.topic(id, "state"),
+		Availability:        []Availability{{Topic: b.StatusTopic()}, {Topic: b.topic(id, "availability")}},
+		AvailabilityMode:    "all",
+		Brightness:          true,
+		SupportedColorModes: colorModes(l),
+		Device: Device{
+			Identifiers:  []string{"lifx_" + id},
+			Name:         name,
+			Manufacturer: "LIFX",
+			Model:        l.product.Name,
+		},
+	}
+	if l.product.VariableKelvin() {
+		d.ColorTempKelvin, d.MinKelvin, d.MaxKelvin = true, l.product.MinKelvin, l.product.MaxKelvin
+	}
+	if l.product.Color {
+		d.Effect, d.EffectList = true, append([]string{noEffect}, effect.Names()...)
+	}
+	return d
+}
+
+//Command is the payload of PREFIX/id/set, Home Assistant's JSON schema for light commands. Brightness is
+//0 to 255, the saturation is in percent, ColorTemp in kelvin and Transition in seconds.
+type Command struct {
+	State      string   `json:"state"`
+	Brightness *float64 `json:"brightness"`
+	Color      *HS      `json:"color"`
+	ColorTemp  *uint16  `json:"color_temp"`
+	Transition float64  `json:"transition"`
+	Effect     string   `json:"effect"`
+}
+
+//spec is the color part of the command.
+func (c Command) spec() hsbk.Spec {
+	var spec hsbk.Spec
+	if c.ColorTemp != nil {
+		spec.Kelvin, spec.Saturation = *c.ColorTemp, 0
+		spec.Set |= hsbk.KelvinField | hsbk.SaturationField
+	}
+	if c.Color != nil {
+		spec.Hue = hsbk.FromDegrees(c.Color.H)
+		spec.Saturation = hsbk.FromFraction(c.Color.S / 100)
+		spec.Set |= hsbk.HueField | hsbk.SaturationField
+	}
+	if c.Brightness != nil {
+		spec.Brightness = hsbk.FromFraction(*c.Brightness / 255)
+		spec.Set |= hsbk.BrightnessField
+	}
+	return spec
+}
@@ -0,0 +1,128 @@
+package presence
+
+import (
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/schedule"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Window is part of the day lights are on, each end a clock time or a sun event as schedule.ParseSolar
+//reads it.
+type Window struct {
+	On, Off string
+}
+
+//ParseWindow reads ON..OFF, like 18:30..23:00 or sunset-30m..23:15.
+func ParseWindow(s string) (Window, error) {
+	parts := strings.Split(s, "..")
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return Window{}, fmt.Errorf("window %q is not ON..OFF", s)
+	}
+	return Window{On: strings.TrimSpace(parts[0]), Off: strings.TrimSpace(parts[1])}, nil
+}
+
+func (w Window) String() string {
+	return w.On + ".." + w.Off
+}
+
+//Simulation makes a home look lived in by turning each light on and off in every window, every light and
+//every day at its own random times.
+type Simulation struct {
+	Windows []Window
+	//Jitter is how far either way of the window's ends each light's times may be, under half the window
+	Jitter time.Duration
+	//Seed picks the random times, the same seed gives the same times
+	Seed int64
+	//Fade is how long the lights take to turn on and off
+	Fade time.Duration
+	//Latitude and longitude are only needed by windows at sun events
+	Latitude, Longitude *float64
+}
+
+//File returns the schedule of the simulation, an on and an off job for each light in every window. The
+//lights are taken in target order so the seed gives each the same times whatever order they were found in.
+func (s *Simulation) File(lights []*client.Light) (*schedule.File, error) {
+	if len(s.Windows) == 0 {
+		return nil, fmt.Errorf("no windows")
+	}
+	sorted := append([]*client.Light{}, lights...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].TargetHex() < sorted[j].TargetHex()
+	})
+
+	f := &schedule.File{Latitude: s.Latitude, Longitude: s.Longitude}
+	// the triggers of each window's on and off ends
+	triggers := make([][2]schedule.Trigger, len(s.Windows))
+	for i, w := range s.Windows {
+		for j, at := range []string{w.On, w.Off} {
+			trigger, err := s.daily(f, at)
+			if err != nil {
+				return nil, err
+			}
+			triggers[i][j] = trigger
+		}
+		// the times are moved independently, so more jitter could turn a light off before it is on
+		if shortest := shortest(triggers[i][0], triggers[i][1], time.Now()); shortest > 0 && s.Jitter*2 >= shortest {
+			return nil, fmt.Errorf("jitter %v must be under half of window %v, which can be as short as %v", s.Jitter, w, shortest)
+		}
+	}
+
+	seed := s.Seed
+	for _, l := range sorted {
+		for i, w := range s.Windows {
+			for j, on := range []bool{true, false} {
+				seed++
+				on := on
+				name := fmt.Sprintf("%v off %v", l.TargetHex(), w)
+				if on {
+					name = fmt.Sprintf("%v on %v", l.TargetHex(), w)
+				}
+				err := f.Add(&schedule.Job{
+					Name:     name,
+					Lights:   "id:" + l.TargetHex(),
+					Power:    &on,
+					Duration: s.Fade,
+					Trigger:  &schedule.Jittered{Trigger: triggers[i][j], Jitter: s.Jitter, Seed: seed},
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return f, nil
+}
+
+//shortest is the shortest the window from on to off gets in the year after from, sun events move it
+//from day to day.
+func shortest(on, off schedule.Trigger, from time.Time) time.Duration {
+	var min time.Duration
+	for day := 0; day < 366; day++ {
+		start := on.Next(from.AddDate(0, 0, day))
+		if start.IsZero() {
+			continue
+		}
+		end := off.Next(start)
+		if end.IsZero() {
+			continue
+		}
+		if length := end.Sub(start); min == 0 || length < min {
+			min = length
+		}
+	}
+	return min
+}
+
+//daily reads a clock time as a daily cron expression and anything else as a sun event.
+func (s *Simulation) daily(f *schedule.File, at string) (schedule.Trigger, error) {
+	if clock, err := time.Parse("15:04", at); err == nil {
+		return schedule.ParseCron(fmt.Sprintf("%v %v * * *", clock.Minute(), clock.Hour()))
+	}
+	if _, err := schedule.ParseSolar(at, 0, 0); err != nil {
+		return nil, fmt.Errorf("%q is not a clock time or a sun event", at)
+	}
+	return f.ParseTrigger(at)
+}
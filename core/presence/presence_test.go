@@ -0,0 +1,109 @@
+package presence
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/schedule"
+	"testing"
+	"time"
+)
+
+func TestParseWindow(t *testing.T) {
+	w, err := ParseWindow("sunset-30m..23:15")
+	if err != nil || w.On != "sunset-30m" || w.Off != "23:15" {
+		t.Errorf("unexpected %v, %v", w, err)
+	}
+	for _, bad := range []string{"18:00", "18:00..", "..23:00", "18:00..20:00..23:00"} {
+		if _, err := ParseWindow(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestSimulation_File(t *testing.T) {
+	c := client.New(context.Background(), nil, nil)
+	lights := []*client.Light{
+		c.Light([]byte{0xd0, 0x73, 0xd5, 0, 0, 2, 0}, nil),
+		c.Light([]byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, nil),
+	}
+	s := &Simulation{Windows: []Window{{"18:30", "23:00"}}, Jitter: 20 * time.Minute, Seed: 42}
+	f, err := s.File(lights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Jobs) != 4 {
+		t.Fatalf("expected on and off jobs for both lights but got %v", f.Jobs)
+	}
+
+	from := time.Date(2024, 5, 15, 12, 0, 0, 0, time.Local)
+	upcoming := f.Upcoming(from, 8)
+	ons := map[string]bool{}
+	for _, u := range upcoming[:4] {
+		if u.At.Day() != 15 {
+			t.Errorf("expected the first day's runs first but got %v", upcoming)
+		}
+		ons[u.At.Format("15:04:05")] = true
+	}
+	if len(ons) != 4 {
+		t.Errorf("expected every light to get its own times but got %v", upcoming)
+	}
+	for _, j := range f.Jobs {
+		next := j.Trigger.Next(from)
+		base := time.Date(2024, 5, 15, 18, 30, 0, 0, time.Local)
+		if !*j.Power {
+			base = time.Date(2024, 5, 15, 23, 0, 0, 0, time.Local)
+		}
+		if diff := next.Sub(base); diff < -20*time.Minute || diff > 20*time.Minute {
+			t.Errorf("%v: expected within 20 minutes of %v but got %v", j, base, next)
+		}
+	}
+
+	// the same seed gives the same plan whatever order the lights were found in
+	lights[0], lights[1] = lights[1], lights[0]
+	again, _ := s.File(lights)
+	for i, u := range again.Upcoming(from, 8) {
+		if u != upcoming[i] {
+			t.Fatalf("expected the same plan but got %v and %v", u, upcoming[i])
+		}
+	}
+
+	if _, err := (&Simulation{Windows: []Window{{"sunset", "23:00"}}}).File(lights); err == nil {
+		t.Errorf("expected a sun event without a location to fail")
+	}
+	if _, err := (&Simulation{Windows: []Window{{"evening", "23:00"}}}).File(lights); err == nil {
+		t.Errorf("expected an unknown time to fail")
+	}
+	// 15m either way could turn a light off before it is on in a 30m window
+	if _, err := (&Simulation{Windows: []Window{{"18:30", "23:00"}, {"06:30", "07:00"}}, Jitter: 15 * time.Minute}).File(lights); err == nil {
+		t.Errorf("expected jitter of half the shortest window to fail")
+	}
+	if _, err := (&Simulation{Windows: []Window{{"23:45", "00:15"}}, Jitter: 14 * time.Minute}).File(lights); err != nil {
+		t.Errorf("expected a window past midnight to fit the jitter: %v", err)
+	}
+}
+
+func TestSimulation_Run(t *testing.T) {
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, bulb)
+	c := client.New(ctx, out, in)
+	l := c.Light(bulb.Target, bulb.Address)
+
+	f, err := (&Simulation{Windows: []Window{{"18:30", "23:00"}}}).File([]*client.Light{l})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetPower(ctx, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	// runs the on job as the scheduler would when it comes due
+	s := &schedule.Scheduler{Client: c, File: f, Wait: 200 * time.Millisecond}
+	if err := s.Execute(ctx, f.Jobs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if on, _ := l.Power(ctx); !on {
+		t.Errorf("expected the light turned on")
+	}
+}
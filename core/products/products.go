@@ -0,0 +1,118 @@
+package products
+
+import "fmt"
+
+//Vendor is LIFX's vendor id in device.StateVersion, the only vendor there is.
+const Vendor = 1
+
+//Product is what a model of LIFX device can do.
+type Product struct {
+	Name string `json:"name" yaml:"name"`
+	//Color is false for whites, which only change kelvin and brightness
+	Color     bool `json:"color" yaml:"color"`
+	Infrared  bool `json:"infrared,omitempty" yaml:"infrared,omitempty"`
+	Multizone bool `json:"multizone,omitempty" yaml:"multizone,omitempty"`
+	Matrix    bool `json:"matrix,omitempty" yaml:"matrix,omitempty"`
+	Chain     bool `json:"chain,omitempty" yaml:"chain,omitempty"`
+	HEV       bool `json:"hev,omitempty" yaml:"hev,omitempty"`
+	//Relays is true for switches, which are not lights at all
+	Relays bool `json:"relays,omitempty" yaml:"relays,omitempty"`
+	//MinKelvin and MaxKelvin are the same for lights with a fixed white
+	MinKelvin uint16 `json:"min_kelvin" yaml:"min_kelvin"`
+	MaxKelvin uint16 `json:"max_kelvin" yaml:"max_kelvin"`
+}
+
+//IsLight reports if the device is a light and not a switch.
+func (p Product) IsLight() bool {
+	return !p.Relays
+}
+
+//VariableKelvin reports if the white temperature can be changed.
+func (p Product) VariableKelvin() bool {
+	return p.MinKelvin < p.MaxKelvin
+}
+
+var (
+	color       = Product{Color: true, MinKelvin: 2500, MaxKelvin: 9000}
+	color1500   = Product{Color: true, MinKelvin: 1500, MaxKelvin: 9000}
+	nightVision = Product{Color: true, Infrared: true, MinKelvin: 2500, MaxKelvin: 9000}
+	multizone   = Product{Color: true, Multizone: true, MinKelvin: 2500, MaxKelvin: 9000}
+	matrix      = Product{Color: true, Matrix: true, MinKelvin: 1500, MaxKelvin: 9000}
+	white       = Product{MinKelvin: 2700, MaxKelvin: 6500}
+	whiteToWarm = Product{MinKelvin: 1500, MaxKelvin: 4000}
+	fixedWhite  = Product{MinKelvin: 2700, MaxKelvin: 2700}
+	switches    = Product{Relays: true}
+)
+
+func named(name string, p Product) Product {
+	p.Name = name
+	return p
+}
+
+//table is LIFX's products by product id, from the list LIFX publishes for the LAN protocol.
+var table = map[uint32]Product{
+	1:   named("Original 1000", color),
+	3:   named("Color 650", color),
+	10:  named("White 800 (Low Voltage)", white),
+	11:  named("White 800 (High Voltage)", white),
+	15:  named("Color 1000", color),
+	18:  named("White 900 BR30 (Low Voltage)", white),
+	19:  named("White 900 BR30 (High Voltage)", white),
+	20:  named("Color 1000 BR30", color),
+	22:  named("Color 1000", color),
+	27:  named("LIFX A19", color),
+	28:  named("LIFX BR30", color),
+	29:  named("LIFX A19 Night Vision", nightVision),
+	30:  named("LIFX BR30 Night Vision", nightVision),
+	31:  named("LIFX Z", multizone),
+	32:  named("LIFX Z", multizone),
+	36:  named("LIFX Downlight", color),
+	37:  named("LIFX Downlight", color),
+	38:  named("LIFX Beam", multizone),
+	43:  named("LIFX A19", color),
+	44:  named("LIFX BR30", color),
+	45:  named("LIFX A19 Night Vision", nightVision),
+	46:  named("LIFX BR30 Night Vision", nightVision),
+	49:  named("LIFX Mini Color", color1500),
+	50:  named("LIFX Mini White to Warm", whiteToWarm),
+	51:  named("LIFX Mini White", fixedWhite),
+	52:  named("LIFX GU10", color1500),
+	55:  named("LIFX Tile", Product{Color: true, Matrix: true, Chain: true, MinKelvin: 2500, MaxKelvin: 9000}),
+	57:  named("LIFX Candle", matrix),
+	59:  named("LIFX Mini Color", color1500),
+	60:  named("LIFX Mini White to Warm", whiteToWarm),
+	61:  named("LIFX Mini White", fixedWhite),
+	62:  named("LIFX A19", color1500),
+	63:  named("LIFX BR30", color1500),
+	64:  named("LIFX A19 Night Vision", nightVision),
+	65:  named("LIFX BR30 Night Vision", nightVision),
+	66:  named("LIFX Mini White", fixedWhite),
+	68:  named("LIFX Candle", matrix),
+	70:  named("LIFX Switch", switches),
+	71:  named("LIFX Switch", switches),
+	89:  named("LIFX Switch", switches),
+	90:  named("LIFX Clean", Product{Color: true, HEV: true, MinKelvin: 1500, MaxKelvin: 9000}),
+	91:  named("LIFX Color", color1500),
+	92:  named("LIFX Color", color1500),
+	117: named("LIFX Z", multizone),
+	118: named("LIFX Z", multizone),
+	119: named("LIFX Beam", multizone),
+	120: named("LIFX Beam", multizone),
+	137: named("LIFX Candle Color", matrix),
+	138: named("LIFX Candle Color", matrix),
+	141: named("LIFX Neon", multizone),
+	142: named("LIFX Neon", multizone),
+	143: named("LIFX String", multizone),
+	144: named("LIFX String", multizone),
+}
+
+//Lookup returns what the product can do. Products missing from the table, newer ones or other vendors,
+//are taken to be color lights and the second result is false.
+func Lookup(vendor, product uint32) (Product, bool) {
+	if vendor == Vendor {
+		if p, has := table[product]; has {
+			return p, true
+		}
+	}
+	return named(fmt.Sprintf("Unknown product %v:%v", vendor, product), color), false
+}
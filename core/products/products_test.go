@@ -0,0 +1,23 @@
+package products
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	p, known := Lookup(Vendor, 50)
+	if !known || p.Name != "LIFX Mini White to Warm" || p.Color || !p.VariableKelvin() || !p.IsLight() {
+		t.Errorf("unexpected %+v", p)
+	}
+	if p, _ := Lookup(Vendor, 51); p.VariableKelvin() {
+		t.Errorf("expected a fixed white but got %+v", p)
+	}
+	if p, _ := Lookup(Vendor, 70); p.IsLight() {
+		t.Errorf("expected a switch but got %+v", p)
+	}
+	if p, _ := Lookup(Vendor, 31); !p.Multizone || !p.Color {
+		t.Errorf("unexpected %+v", p)
+	}
+	p, known = Lookup(2, 27)
+	if known || !p.Color || p.MinKelvin != 2500 || p.MaxKelvin != 9000 {
+		t.Errorf("expected another vendor to be a generic color light but got %+v", p)
+	}
+}
@@ -0,0 +1,81 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/nathanhack/lifx/core/capture"
+)
+
+//Difference is a response the replay didn't give back as recorded. Problem is "missing" when the emulated
+//devices never sent it, "unexpected" when they sent one that wasn't recorded and "payload" when both were
+//sent but differ. The payloads are hex, empty when the side didn't send the response.
+type Difference struct {
+	Problem  string `json:"problem" yaml:"problem"`
+	Type     uint16 `json:"type" yaml:"type"`
+	Target   string `json:"target" yaml:"target"`
+	Recorded string `json:"recorded" yaml:"recorded"`
+	Live     string `json:"live" yaml:"live"`
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%v type:%v target:%v recorded:%q live:%q", d.Problem, d.Type, d.Target, d.Recorded, d.Live)
+}
+
+//responseKey pairs a live response with the recorded one, the replayed requests carry the recorded source
+//and sequence so the answers to them do too.
+type responseKey struct {
+	source     uint32
+	sequence   byte
+	packetType uint16
+	target     string
+}
+
+func keyOf(p *capture.Packet) responseKey {
+	return responseKey{p.Header.Source(), p.Header.Sequence(), p.Header.Type(), p.Header.TargetHex()}
+}
+
+//Compare matches each live response to the recorded response to the same request with the same type and
+//target, in the order they came, and returns where they differ. Recorded responses to requests that aren't
+//in packets can't be replayed and are left out.
+func Compare(packets []*capture.Packet, live []*capture.Packet) []Difference {
+	type correlation struct {
+		source   uint32
+		sequence byte
+	}
+	requested := make(map[correlation]bool)
+	for _, p := range Requests(packets) {
+		requested[correlation{p.Header.Source(), p.Header.Sequence()}] = true
+	}
+
+	unmatched := make(map[responseKey][]*capture.Packet)
+	for _, p := range live {
+		unmatched[keyOf(p)] = append(unmatched[keyOf(p)], p)
+	}
+
+	differences := make([]Difference, 0)
+	for _, p := range packets {
+		if !IsResponse(p.Header.Type()) || !requested[correlation{p.Header.Source(), p.Header.Sequence()}] {
+			continue
+		}
+		key := keyOf(p)
+		recorded := hex.EncodeToString(p.Header.Data())
+		if len(unmatched[key]) == 0 {
+			differences = append(differences, Difference{Problem: "missing", Type: key.packetType, Target: key.target, Recorded: recorded})
+			continue
+		}
+		l := unmatched[key][0]
+		unmatched[key] = unmatched[key][1:]
+		if !bytes.Equal(p.Header.Data(), l.Header.Data()) {
+			differences = append(differences, Difference{Problem: "payload", Type: key.packetType, Target: key.target, Recorded: recorded, Live: hex.EncodeToString(l.Header.Data())})
+		}
+	}
+	for _, p := range live {
+		key := keyOf(p)
+		if len(unmatched[key]) > 0 && unmatched[key][0] == p {
+			unmatched[key] = unmatched[key][1:]
+			differences = append(differences, Difference{Problem: "unexpected", Type: key.packetType, Target: key.target, Live: hex.EncodeToString(p.Header.Data())})
+		}
+	}
+	return differences
+}
@@ -0,0 +1,135 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/capture"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/server"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+type recordedResponse struct {
+	delay  time.Duration
+	from   *capture.Packet
+	header header.Header
+}
+
+//exchange is one recorded request and everything devices answered to it.
+type exchange struct {
+	responses []recordedResponse
+}
+
+//StartUp is a stand-in for server.StartUp that plays back the devices recorded in packets. When a request
+//arrives that matches a recorded one (same type and target) the responses recorded for it are sent back
+//after the recorded delay (scaled by speed), rewritten to carry the live request's source and sequence.
+//Matching requests are answered with successive recordings, the last one repeating once exhausted.
+func StartUp(ctx context.Context, packets []*capture.Packet, speed float64) (outBound chan *server.OutBoundPayload, inbound chan *server.InboundPayload) {
+	outBound = make(chan *server.OutBoundPayload, 10)
+	inbound = make(chan *server.InboundPayload, 100)
+
+	exchanges := recordExchanges(packets)
+	next := make(map[string]int)
+	var wg sync.WaitGroup
+
+	go func() {
+		defer wg.Wait()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case payload := <-outBound:
+				if payload.Done != nil {
+					payload.Done()
+				}
+				h, err := header.Decode(payload.Data)
+				if err != nil {
+					continue
+				}
+
+				key := exchangeKey(*h)
+				recorded := exchanges[key]
+				if len(recorded) == 0 {
+					logrus.Debugf("playback has no recording for %v", key)
+					continue
+				}
+				i := next[key]
+				if i < len(recorded)-1 {
+					next[key] = i + 1
+				}
+
+				for _, r := range recorded[i].responses {
+					response := header.Header(append([]byte{}, r.header...))
+					response.SetSource(h.Source())
+					response.SetSequence(h.Sequence())
+					delay := r.delay
+					if speed > 0 {
+						delay = time.Duration(float64(delay) / speed)
+					} else {
+						delay = 0
+					}
+
+					wg.Add(1)
+					go func(from *capture.Packet, response header.Header) {
+						defer wg.Done()
+						select {
+						case <-ctx.Done():
+							return
+						case <-time.After(delay):
+						}
+						select {
+						case <-ctx.Done():
+						case inbound <- &server.InboundPayload{Data: response, Conn: from.Src}:
+						}
+					}(r.from, response)
+				}
+			}
+		}
+	}()
+
+	return outBound, inbound
+}
+
+func exchangeKey(h header.Header) string {
+	if h.Tagged() {
+		return fmt.Sprintf("%v/*", h.Type())
+	}
+	return fmt.Sprintf("%v/%v", h.Type(), h.TargetHex())
+}
+
+//recordExchanges pairs each recorded request with the responses that carry its source and sequence.
+func recordExchanges(packets []*capture.Packet) map[string][]*exchange {
+	exchanges := make(map[string][]*exchange)
+	type correlation struct {
+		source   uint32
+		sequence byte
+	}
+	pending := make(map[correlation]*capture.Packet)
+	byRequest := make(map[*capture.Packet]*exchange)
+
+	for _, p := range packets {
+		c := correlation{p.Header.Source(), p.Header.Sequence()}
+		if !IsResponse(p.Header.Type()) {
+			e := &exchange{}
+			key := exchangeKey(p.Header)
+			exchanges[key] = append(exchanges[key], e)
+			pending[c] = p
+			byRequest[p] = e
+			continue
+		}
+
+		request, has := pending[c]
+		if !has {
+			continue
+		}
+		e := byRequest[request]
+		e.responses = append(e.responses, recordedResponse{
+			delay:  p.Time.Sub(request.Time),
+			from:   p,
+			header: p.Header,
+		})
+	}
+	return exchanges
+}
@@ -0,0 +1,139 @@
+package replay
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/capture"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/server"
+	"time"
+)
+
+var responseTypes = map[uint16]bool{
+	device.StateServiceType:      true,
+	device.StateHostInfoType:     true,
+	device.StateHostFirmwareType: true,
+	device.StateWifiInfoType:     true,
+	device.StateWifiFirmwareType: true,
+	device.StatePowerType:        true,
+	device.StateLabelType:        true,
+	device.StateVersionType:      true,
+	device.StateInfoType:         true,
+	device.AcknowledgementType:   true,
+	device.StateLocationType:     true,
+	device.StateGroupType:        true,
+	device.EchoResponseType:      true,
+	light.StateType:              true,
+	light.StatePowerType:         true,
+	light.StateInfraredType:      true,
+}
+
+//IsResponse reports if packetType is a message devices send (as opposed to clients).
+func IsResponse(packetType uint16) bool {
+	return responseTypes[packetType]
+}
+
+//Requests returns the packets that were sent by clients.
+func Requests(packets []*capture.Packet) []*capture.Packet {
+	requests := make([]*capture.Packet, 0)
+	for _, p := range packets {
+		if !IsResponse(p.Header.Type()) {
+			requests = append(requests, p)
+		}
+	}
+	return requests
+}
+
+//Play sends every request in packets to out, addressed as recorded, keeping the recorded gaps
+//between them. A speed of 2 plays twice as fast, a speed of 0 or less sends without waiting.
+func Play(ctx context.Context, packets []*capture.Packet, speed float64, out chan *server.OutBoundPayload) error {
+	requests := Requests(packets)
+	start := time.Now()
+	for _, p := range requests {
+		if speed > 0 {
+			offset := time.Duration(float64(p.Time.Sub(requests[0].Time)) / speed)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Until(start.Add(offset))):
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- &server.OutBoundPayload{Data: append([]byte{}, p.Header...), Address: p.Dst}:
+		}
+	}
+	return nil
+}
+
+//Devices builds emulated devices for every device that answered in the capture. Each device keeps its
+//recorded address and target and starts with the last state it reported.
+func Devices(packets []*capture.Packet) []*emulator.Device {
+	devices := make([]*emulator.Device, 0)
+	byTarget := make(map[string]*emulator.Device)
+	for _, p := range packets {
+		if !IsResponse(p.Header.Type()) {
+			continue
+		}
+		d, has := byTarget[p.Header.TargetHex()]
+		if !has {
+			d = &emulator.Device{
+				Target:  append([]byte{}, p.Header.Target()...),
+				Address: p.Src,
+			}
+			byTarget[p.Header.TargetHex()] = d
+			devices = append(devices, d)
+		}
+		applyState(d, p)
+	}
+	return devices
+}
+
+func applyState(d *emulator.Device, p *capture.Packet) {
+	switch p.Header.Type() {
+	case device.StatePowerType:
+		var s device.StatePower
+		if device.DecodeFromHeader(p.Header, &s) == nil {
+			d.Power = s.Level
+		}
+	case device.StateLabelType:
+		var s device.StateLabel
+		if device.DecodeFromHeader(p.Header, &s) == nil {
+			d.Label = fields.Label(s.Label)
+		}
+	case device.StateVersionType:
+		var s device.StateVersion
+		if device.DecodeFromHeader(p.Header, &s) == nil {
+			d.Vendor, d.Product, d.Version = s.Vendor, s.Product, s.Version
+		}
+	case device.StateLocationType:
+		var s device.StateLocation
+		if device.DecodeFromHeader(p.Header, &s) == nil {
+			d.Location, d.LocationLabel, d.LocationUpdatedAt = s.Location, fields.Label(s.Label), s.UpdatedAt
+		}
+	case device.StateGroupType:
+		var s device.StateGroup
+		if device.DecodeFromHeader(p.Header, &s) == nil {
+			d.Group, d.GroupLabel, d.GroupUpdatedAt = s.Group, fields.Label(s.Label), s.UpdatedAt
+		}
+	case light.StateType:
+		var s light.State
+		if light.DecodeFromHeader(p.Header, &s) == nil {
+			d.Color, d.Power, d.Label = s.Color, s.Power, fields.Label(s.Label)
+		}
+	case light.StatePowerType:
+		var s light.StatePower
+		if light.DecodeFromHeader(p.Header, &s) == nil {
+			d.Power = s.Level
+		}
+	case light.StateInfraredType:
+		var s light.StateInfrared
+		if light.DecodeFromHeader(p.Header, &s) == nil {
+			d.Infrared = s.Brightness
+		}
+	}
+}
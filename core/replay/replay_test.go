@@ -0,0 +1,187 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"github.com/nathanhack/lifx/core/capture"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/header"
+	"github.com/nathanhack/lifx/core/messages/device"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/server"
+	"net"
+	"testing"
+	"time"
+)
+
+var (
+	client = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 56700}
+	bulb   = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 100), Port: 56700}
+	target = []byte{0xd0, 0x73, 0xd5, 0x01, 0x02, 0x03, 0x00}
+)
+
+//recordSession builds the capture a client would see asking a bulb for its service and state.
+func recordSession(t *testing.T) []*capture.Packet {
+	d := &emulator.Device{
+		Target: target,
+		Label:  "Kitchen",
+		Power:  0xffff,
+		Color:  hsbk.HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500},
+	}
+
+	service := header.New(1)
+	device.GetService{}.RequiredHeader(service)
+	get := header.New(2)
+	get.SetTarget(target)
+	light.Get{}.RequiredHeader(get)
+
+	start := time.Unix(1577836800, 0)
+	packets := make([]*capture.Packet, 0)
+	for i, request := range []*header.Header{service, get} {
+		sent := start.Add(time.Duration(i) * time.Second)
+		packets = append(packets, &capture.Packet{Time: sent, Src: client, Dst: bulb, Header: *request})
+
+		responses, err := d.Handle(*request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, response := range responses {
+			packets = append(packets, &capture.Packet{Time: sent.Add(20 * time.Millisecond), Src: bulb, Dst: client, Header: response})
+		}
+	}
+	return packets
+}
+
+func TestDevices(t *testing.T) {
+	devices := Devices(recordSession(t))
+	if len(devices) != 1 {
+		t.Fatalf("expect 1 device but got %v", len(devices))
+	}
+	d := devices[0]
+	if !bytes.Equal(d.Target, target) {
+		t.Errorf("expect %x but got %x", target, d.Target)
+	}
+	if d.Address.String() != bulb.String() {
+		t.Errorf("expect %v but got %v", bulb, d.Address)
+	}
+	if d.Label != "Kitchen" || d.Power != 0xffff || d.Color.Kelvin != 3500 {
+		t.Errorf("expect recorded state but got %v %v %v", d.Label, d.Power, d.Color)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	packets := recordSession(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out, in := emulator.StartUp(ctx, Devices(packets)...)
+	if err := Play(ctx, packets, 0, out); err != nil {
+		t.Fatal(err)
+	}
+
+	// the emulator should answer exactly like the recorded bulb did
+	expected := make([]uint16, 0)
+	for _, p := range packets {
+		if IsResponse(p.Header.Type()) {
+			expected = append(expected, p.Header.Type())
+		}
+	}
+	for _, packetType := range expected {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for type %v", packetType)
+		case payload := <-in:
+			h, err := header.Decode(payload.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Type() != packetType {
+				t.Errorf("expect type %v but got %v", packetType, h.Type())
+			}
+		}
+	}
+}
+
+func TestStartUp_Playback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, in := StartUp(ctx, recordSession(t), 1)
+
+	request := header.New(42)
+	request.SetTarget(target)
+	light.Get{}.RequiredHeader(request)
+	buffer := bytes.NewBuffer([]byte{})
+	if err := binary.Write(buffer, binary.LittleEndian, request); err != nil {
+		t.Fatal(err)
+	}
+	sent := time.Now()
+	out <- &server.OutBoundPayload{Data: buffer.Bytes(), Address: bulb}
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for playback")
+	case payload := <-in:
+		if time.Since(sent) < 20*time.Millisecond {
+			t.Errorf("expect recorded delay to be kept but got %v", time.Since(sent))
+		}
+		h, err := header.Decode(payload.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !h.Validate(true) {
+			t.Errorf("expect a valid device header but got %v", h)
+		}
+		if h.Source() != request.Source() || h.Sequence() != 42 {
+			t.Errorf("expect source %v sequence 42 but got %v %v", request.Source(), h.Source(), h.Sequence())
+		}
+		var state light.State
+		if err := light.DecodeFromHeader(*h, &state); err != nil {
+			t.Fatal(err)
+		}
+		if fields.Label(state.Label) != "Kitchen" {
+			t.Errorf("expect Kitchen but got %q", fields.Label(state.Label))
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	packets := recordSession(t)
+	d := Devices(packets)[0]
+	replayed := func() []*capture.Packet {
+		live := make([]*capture.Packet, 0)
+		for _, request := range Requests(packets) {
+			responses, err := d.Handle(request.Header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, response := range responses {
+				live = append(live, &capture.Packet{Src: bulb, Dst: client, Header: response})
+			}
+		}
+		return live
+	}
+
+	live := replayed()
+	if differences := Compare(packets, live); len(differences) != 0 {
+		t.Errorf("expect the same answers as recorded but got %v", differences)
+	}
+
+	d.Label = "Hall"
+	differences := Compare(packets, replayed())
+	if len(differences) != 1 || differences[0].Problem != "payload" || differences[0].Type != light.StateType || differences[0].Target != hex.EncodeToString(target) {
+		t.Errorf("expect the state payload to differ but got %v", differences)
+	}
+
+	differences = Compare(packets, live[1:])
+	if len(differences) != 1 || differences[0].Problem != "missing" || differences[0].Type != device.StateServiceType {
+		t.Errorf("expect the service answer missing but got %v", differences)
+	}
+	differences = Compare(packets, append(live, live[0]))
+	if len(differences) != 1 || differences[0].Problem != "unexpected" || differences[0].Type != device.StateServiceType {
+		t.Errorf("expect an unexpected service answer but got %v", differences)
+	}
+}
@@ -0,0 +1,158 @@
+package scene
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+//Scene is the power and color of a set of lights at one point in time.
+type Scene struct {
+	Name   string    `json:"name" yaml:"name"`
+	Saved  time.Time `json:"saved" yaml:"saved"`
+	Lights []Entry   `json:"lights" yaml:"lights"`
+}
+
+//Entry is one light of a scene, State is what the light answered to light.Get.
+type Entry struct {
+	Target string      `json:"target" yaml:"target"`
+	State  light.State `json:"state" yaml:"state"`
+}
+
+func (s *Scene) String() string {
+	return fmt.Sprintf("Scene{ %v Saved:%v Lights:%v }", s.Name, s.Saved.Format(time.RFC3339), len(s.Lights))
+}
+
+//Capture asks every light for its state. Lights that don't answer before ctx is done are left out of
+//the scene and counted in the error.
+func Capture(ctx context.Context, name string, lights []*client.Light) (*Scene, error) {
+	states := make([]*light.State, len(lights))
+	err := client.Each(lights, func(i int, l *client.Light) (err error) {
+		states[i], err = l.State(ctx)
+		return
+	})
+
+	scene := &Scene{Name: name, Saved: time.Now(), Lights: make([]Entry, 0, len(lights))}
+	for i, l := range lights {
+		if states[i] != nil {
+			scene.Lights = append(scene.Lights, Entry{Target: l.TargetHex(), State: *states[i]})
+		}
+	}
+	sort.Slice(scene.Lights, func(i, j int) bool {
+		return scene.Lights[i].Target < scene.Lights[j].Target
+	})
+	return scene, err
+}
+
+//Targets returns the targets of the scene's lights, ready for client.Find.
+func (s *Scene) Targets() ([][]byte, error) {
+	targets := make([][]byte, len(s.Lights))
+	for i, e := range s.Lights {
+		target, err := hex.DecodeString(e.Target)
+		if err != nil {
+			return nil, fmt.Errorf("scene %v: bad target %q: %v", s.Name, e.Target, err)
+		}
+		targets[i] = target
+	}
+	return targets, nil
+}
+
+//Apply sets every light of the scene found in lights back to its recorded color and power, all at the
+//same time, transitioning over duration. Lights of the scene missing from lights are counted in the error.
+func (s *Scene) Apply(ctx context.Context, lights []*client.Light, duration time.Duration) error {
+	byTarget := make(map[string]*client.Light)
+	for _, l := range lights {
+		byTarget[l.TargetHex()] = l
+	}
+	// in the scene's order, nil for the lights that weren't found
+	ordered := make([]*client.Light, len(s.Lights))
+	for i, e := range s.Lights {
+		ordered[i] = byTarget[e.Target]
+	}
+
+	return client.Each(ordered, func(i int, l *client.Light) error {
+		e := s.Lights[i]
+		if l == nil {
+			return fmt.Errorf("%v not found", e.Target)
+		}
+		return e.Apply(ctx, l, duration)
+	})
+}
+
+//Apply sets l back to the entry's recorded color and power, transitioning over duration.
+func (e Entry) Apply(ctx context.Context, l *client.Light, duration time.Duration) error {
+	if err := l.SetColor(ctx, e.State.Color, duration); err != nil {
+		return err
+	}
+	return l.SetPower(ctx, e.State.Power != 0, duration)
+}
+
+//Scenes is the content of a scene file, keyed by scene name.
+type Scenes map[string]*Scene
+
+//DefaultFile is where scenes are kept unless told otherwise, lifx/scenes.json in the user's config directory.
+func DefaultFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "scenes.json"
+	}
+	return filepath.Join(dir, "lifx", "scenes.json")
+}
+
+//ReadFile loads a scene file, a file that does not exist yet holds no scenes.
+func ReadFile(path string) (Scenes, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Scenes{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	scenes := Scenes{}
+	if err := json.Unmarshal(b, &scenes); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return scenes, nil
+}
+
+//WriteFile saves the scenes, replacing the file in one step so a crash can't leave half a file behind.
+func WriteFile(path string, scenes Scenes) error {
+	b, err := json.MarshalIndent(scenes, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+//Names returns the scene names sorted.
+func (s Scenes) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
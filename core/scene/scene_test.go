@@ -0,0 +1,80 @@
+package scene
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCaptureApply(t *testing.T) {
+	red := hsbk.HSBK{Hue: 0, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}
+	blue := hsbk.HSBK{Hue: 0xaaaa, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500}
+	devices := []*emulator.Device{
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Label: "Kitchen", Power: 0xffff, Color: red},
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 2, 0}, Label: "Porch", Color: blue},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, devices...)
+	c := client.New(ctx, out, in)
+	lights := []*client.Light{c.Light(devices[0].Target, devices[0].Address), c.Light(devices[1].Target, devices[1].Address)}
+
+	s, err := Capture(ctx, "evening", lights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Lights) != 2 || s.Lights[0].Target != "d073d500000100" || s.Lights[0].State.Color != red || s.Lights[1].State.Power != 0 {
+		t.Fatalf("unexpected scene %+v", s.Lights)
+	}
+
+	path := filepath.Join(t.TempDir(), "lifx", "scenes.json")
+	if err := WriteFile(path, Scenes{s.Name: s}); err != nil {
+		t.Fatal(err)
+	}
+	scenes, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := scenes["evening"]
+	if loaded == nil || !reflect.DeepEqual(loaded.Lights, s.Lights) || !loaded.Saved.Equal(s.Saved) {
+		t.Fatalf("expected %+v but read %+v", s, loaded)
+	}
+
+	// change everything then put it back
+	for _, l := range lights {
+		if err := l.SetColor(ctx, hsbk.HSBK{Kelvin: 9000}, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.SetPower(ctx, true, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := loaded.Apply(ctx, lights, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range lights {
+		state, err := l.State(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.Color != loaded.Lights[i].State.Color || state.Power != loaded.Lights[i].State.Power {
+			t.Errorf("%v: expected %v but got %v", l, loaded.Lights[i].State, state)
+		}
+	}
+
+	if err := loaded.Apply(ctx, lights[:1], 0); err == nil {
+		t.Errorf("expected an error when a light of the scene is missing")
+	}
+}
+
+func TestReadFile_Missing(t *testing.T) {
+	scenes, err := ReadFile(filepath.Join(t.TempDir(), "none.json"))
+	if err != nil || len(scenes) != 0 {
+		t.Errorf("expected no scenes but got %v (%v)", scenes, err)
+	}
+}
@@ -0,0 +1,44 @@
+package schedule
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+//Jittered moves every time of Trigger by a random amount of up to Jitter either way. The amount only
+//depends on Seed and the time moved, so the same seed always gives the same times.
+type Jittered struct {
+	Trigger Trigger
+	Jitter  time.Duration
+	Seed    int64
+}
+
+func (j *Jittered) String() string {
+	return fmt.Sprintf("%v±%v", j.Trigger, j.Jitter)
+}
+
+//Next returns the first moved time after t.
+func (j *Jittered) Next(t time.Time) time.Time {
+	// a time moved later than t may come from before it
+	from := t.Add(-j.Jitter)
+	for i := 0; i < 1000; i++ {
+		base := j.Trigger.Next(from)
+		if base.IsZero() {
+			return base
+		}
+		if at := base.Add(j.offset(base)); at.After(t) {
+			return at
+		}
+		from = base
+	}
+	return time.Time{}
+}
+
+func (j *Jittered) offset(base time.Time) time.Duration {
+	if j.Jitter <= 0 {
+		return 0
+	}
+	r := rand.New(rand.NewSource(j.Seed ^ base.Unix()))
+	return time.Duration((2*r.Float64() - 1) * float64(j.Jitter)).Truncate(time.Second)
+}
@@ -0,0 +1,206 @@
+package schedule
+
+import (
+	"fmt"
+	"github.com/nathanhack/lifx/core/effect"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/selector"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+//Trigger says when a job runs next.
+type Trigger interface {
+	//Next returns the first time after t, the zero time for never
+	Next(t time.Time) time.Time
+	String() string
+}
+
+//File is a schedule file:
+//
+//	latitude: 51.48
+//	longitude: -0.01
+//	jobs:
+//	  - name: wake up
+//	    at: "30 6 * * mon-fri"
+//	    lights: group:Bedroom
+//	    color: daylight brightness:100%
+//	    power: on
+//	    duration: 20m
+//	  - at: sunset-15m
+//	    scene: evening
+//
+//Latitude and longitude are only needed by jobs at sun events.
+type File struct {
+	Latitude  *float64 `yaml:"latitude,omitempty"`
+	Longitude *float64 `yaml:"longitude,omitempty"`
+	Jobs      []*Job   `yaml:"jobs"`
+}
+
+//Job is one scheduled action. A job sets power, color or both, applies a scene or runs an effect.
+type Job struct {
+	//Name is used in the log, it defaults to the job's place in the file
+	Name string `yaml:"name,omitempty"`
+	//At is a cron expression or a sun event with an optional offset, see ParseCron and ParseSolar. It is
+	//parsed into Trigger unless a Trigger is given.
+	At string `yaml:"at"`
+	//Lights is a selector, scenes always use their own lights
+	Lights string `yaml:"lights,omitempty"`
+
+	Power *bool `yaml:"power,omitempty"`
+	//Color is a color string as hsbk.Parse reads it, anything it leaves out is kept from each light. It
+	//also colors the effects that take one.
+	Color string `yaml:"color,omitempty"`
+	Scene string `yaml:"scene,omitempty"`
+	//Effect is the name of one of the effect.Effects
+	Effect string `yaml:"effect,omitempty"`
+	//Period is the effect's cycle length, 0 for its default
+	Period time.Duration `yaml:"period,omitempty"`
+
+	//Duration is how long power, color and scene changes fade for and how long an effect runs
+	Duration time.Duration `yaml:"duration,omitempty"`
+
+	Trigger  Trigger `yaml:"-"`
+	selector selector.Selector
+	color    hsbk.Spec
+}
+
+func (j *Job) String() string {
+	return j.Name
+}
+
+//ReadFile loads a schedule file and checks every job in it.
+func ReadFile(path string) (*File, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+//Parse reads the content of a schedule file and checks every job in it.
+func Parse(b []byte) (*File, error) {
+	var f File
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, err
+	}
+	jobs := f.Jobs
+	f.Jobs = nil
+	for _, j := range jobs {
+		if err := f.Add(j); err != nil {
+			return nil, err
+		}
+	}
+	return &f, nil
+}
+
+//Add checks the job and appends it. A job given a Trigger doesn't need At.
+func (f *File) Add(j *Job) error {
+	if j.Name == "" {
+		j.Name = fmt.Sprintf("job %v", len(f.Jobs)+1)
+	}
+	if err := f.compile(j); err != nil {
+		return fmt.Errorf("%v: %v", j.Name, err)
+	}
+	f.Jobs = append(f.Jobs, j)
+	return nil
+}
+
+func (f *File) compile(j *Job) error {
+	var err error
+	if j.Trigger == nil {
+		if j.Trigger, err = f.ParseTrigger(j.At); err != nil {
+			return err
+		}
+	}
+
+	actions := 0
+	if j.Power != nil || j.Color != "" && j.Effect == "" {
+		actions++
+	}
+	if j.Scene != "" {
+		actions++
+	}
+	if j.Effect != "" {
+		actions++
+	}
+	if actions != 1 {
+		return fmt.Errorf("expected one of power and color, scene or effect")
+	}
+
+	switch {
+	case j.Scene != "" && j.Lights != "":
+		return fmt.Errorf("scenes use their own lights")
+	case j.Scene != "":
+	case j.Lights == "":
+		return fmt.Errorf("no lights")
+	default:
+		if j.selector, err = selector.Parse(j.Lights); err != nil {
+			return err
+		}
+	}
+	if j.Color != "" {
+		if j.color, err = hsbk.Parse(j.Color); err != nil {
+			return err
+		}
+	}
+	if j.Effect != "" {
+		if _, has := effect.Effects[j.Effect]; !has {
+			return fmt.Errorf("unknown effect %q (expected one of %v)", j.Effect, effect.Names())
+		}
+		if j.Duration <= 0 {
+			return fmt.Errorf("effect %v needs a duration", j.Effect)
+		}
+	}
+	return nil
+}
+
+//ParseTrigger reads a job's at, a sun event when it starts with one of their names and otherwise a cron
+//expression.
+func (f *File) ParseTrigger(at string) (Trigger, error) {
+	name := strings.ToLower(strings.TrimSpace(at))
+	for alias := range eventAliases {
+		if strings.HasPrefix(name, alias) {
+			if f.Latitude == nil || f.Longitude == nil {
+				return nil, fmt.Errorf("%q needs the latitude and longitude in the file", at)
+			}
+			return ParseSolar(at, *f.Latitude, *f.Longitude)
+		}
+	}
+	return ParseCron(at)
+}
+
+//Upcoming is a job's next run.
+type Upcoming struct {
+	At  time.Time `json:"at" yaml:"at"`
+	Job string    `json:"job" yaml:"job"`
+}
+
+func (u Upcoming) String() string {
+	return fmt.Sprintf("%v %v", u.At.Format("2006-01-02 15:04:05 MST"), u.Job)
+}
+
+//Upcoming returns the next n runs of all the jobs after t in time order.
+func (f *File) Upcoming(t time.Time, n int) []Upcoming {
+	next := make([]time.Time, len(f.Jobs))
+	for i, j := range f.Jobs {
+		next[i] = j.Trigger.Next(t)
+	}
+	upcoming := make([]Upcoming, 0, n)
+	for len(upcoming) < n {
+		first := -1
+		for i := range next {
+			if !next[i].IsZero() && (first < 0 || next[i].Before(next[first])) {
+				first = i
+			}
+		}
+		if first < 0 {
+			break
+		}
+		upcoming = append(upcoming, Upcoming{At: next[first], Job: f.Jobs[first].Name})
+		next[first] = f.Jobs[first].Trigger.Next(next[first])
+	}
+	return upcoming
+}
@@ -0,0 +1,141 @@
+package schedule
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/messages/light"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/scene"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testFile = `
+latitude: 51.48
+longitude: 0
+jobs:
+  - name: wake up
+    at: "30 6 * * mon-fri"
+    lights: label:Bed*
+    color: daylight brightness:100%
+    power: on
+    duration: 1s
+  - name: evening
+    at: sunset-15m
+    scene: evening
+  - at: "@hourly"
+    lights: all
+    effect: candle
+    duration: 5m
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse([]byte(testFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Jobs) != 3 || f.Jobs[2].Name != "job 3" || *f.Jobs[0].Power != true || f.Jobs[0].Duration != time.Second {
+		t.Fatalf("unexpected jobs %v", f.Jobs)
+	}
+	if _, solar := f.Jobs[1].Trigger.(*Solar); !solar {
+		t.Errorf("expected a sun trigger but got %v", f.Jobs[1].Trigger)
+	}
+
+	upcoming := f.Upcoming(time.Date(2024, 5, 15, 10, 20, 0, 0, time.UTC), 4)
+	if len(upcoming) != 4 || upcoming[0].Job != "job 3" || !upcoming[0].At.Equal(time.Date(2024, 5, 15, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected upcoming runs %v", upcoming)
+	}
+	for i := 1; i < len(upcoming); i++ {
+		if upcoming[i].At.Before(upcoming[i-1].At) {
+			t.Errorf("expected upcoming runs in order but got %v", upcoming)
+		}
+	}
+
+	for _, bad := range []string{
+		"jobs:\n  - at: '* * * *'\n    lights: all\n    power: on\n",
+		"jobs:\n  - at: sunset\n    lights: all\n    power: on\n",
+		"jobs:\n  - at: '@daily'\n    lights: all\n",
+		"jobs:\n  - at: '@daily'\n    power: on\n",
+		"jobs:\n  - at: '@daily'\n    lights: all\n    power: on\n    scene: evening\n",
+		"jobs:\n  - at: '@daily'\n    lights: all\n    scene: evening\n",
+		"jobs:\n  - at: '@daily'\n    lights: all\n    effect: candle\n",
+		"jobs:\n  - at: '@daily'\n    lights: all\n    colour: red\n",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("expected an error for\n%v", bad)
+		}
+	}
+}
+
+func TestScheduler_Execute(t *testing.T) {
+	devices := []*emulator.Device{
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Label: "Bedroom", Color: hsbk.HSBK{Kelvin: 3500}},
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 2, 0}, Label: "Kitchen", Color: hsbk.HSBK{Kelvin: 3500}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, devices...)
+	c := client.New(ctx, out, in)
+
+	sceneFile := filepath.Join(t.TempDir(), "scenes.json")
+	evening := hsbk.HSBK{Hue: 0x1000, Saturation: 0x8000, Brightness: 0x4000, Kelvin: 2700}
+	err := scene.WriteFile(sceneFile, scene.Scenes{"evening": {Name: "evening", Lights: []scene.Entry{
+		{Target: hex.EncodeToString(devices[1].Target), State: light.State{Color: evening, Power: 0xffff}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Parse([]byte(testFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Jobs[0].Duration = 0
+	s := &Scheduler{Client: c, File: f, SceneFile: sceneFile, Wait: 200 * time.Millisecond}
+	for _, j := range f.Jobs[:2] {
+		if err := s.Execute(ctx, j); err != nil {
+			t.Fatalf("%v: %v", j, err)
+		}
+	}
+
+	bedroom, _ := c.Light(devices[0].Target, devices[0].Address).State(ctx)
+	if bedroom.Power != 0xffff || bedroom.Color != (hsbk.HSBK{Brightness: 0xffff, Kelvin: 6500}) {
+		t.Errorf("expected the bedroom on in daylight but got %v", bedroom)
+	}
+	kitchen, _ := c.Light(devices[1].Target, devices[1].Address).State(ctx)
+	if kitchen.Power != 0xffff || kitchen.Color != evening {
+		t.Errorf("expected the kitchen in the evening scene but got %v", kitchen)
+	}
+
+	missing := &Job{Name: "missing", At: "@daily", Lights: "label:Garage", Power: new(bool)}
+	if err := f.compile(missing); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Execute(ctx, missing); err == nil {
+		t.Errorf("expected a job without lights to fail")
+	}
+}
+
+func TestJittered(t *testing.T) {
+	daily, _ := ParseCron("0 20 * * *")
+	j := &Jittered{Trigger: daily, Jitter: 30 * time.Minute, Seed: 7}
+	from := time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+	at := from
+	for day := 0; day < 20; day++ {
+		next := j.Next(at)
+		base := time.Date(2024, 5, 15+day, 20, 0, 0, 0, time.UTC)
+		if diff := next.Sub(base); diff < -30*time.Minute || diff > 30*time.Minute {
+			t.Fatalf("expected day %v within 30 minutes of %v but got %v", day, base, next)
+		}
+		if again := (&Jittered{Trigger: daily, Jitter: 30 * time.Minute, Seed: 7}).Next(at); !again.Equal(next) {
+			t.Fatalf("expected the same seed to give the same time but got %v and %v", next, again)
+		}
+		at = next
+	}
+	if other := (&Jittered{Trigger: daily, Jitter: 30 * time.Minute, Seed: 8}).Next(from); other.Equal(j.Next(from)) {
+		t.Errorf("expected another seed to move the time differently")
+	}
+}
@@ -0,0 +1,209 @@
+package schedule
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/effect"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"github.com/nathanhack/lifx/core/scene"
+	"github.com/nathanhack/lifx/core/selector"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultWait and DefaultRefresh are the selector.Cache defaults the scheduler's discovery uses
+	DefaultWait    = selector.DefaultWait
+	DefaultRefresh = selector.DefaultRefresh
+	DefaultTimeout = 10 * time.Second
+)
+
+//Scheduler runs the jobs of a schedule file through one client. It keeps the lights it discovered so a job
+//only broadcasts when they are getting old or its lights are missing.
+type Scheduler struct {
+	Client *client.Client
+	File   *File
+	//SceneFile is where scene jobs find their scenes, it is read each time one runs
+	SceneFile string
+	//Wait is how long discovery listens for lights, 0 is DefaultWait
+	Wait time.Duration
+	//Refresh is how old the known lights may get before a job discovers again, 0 is DefaultRefresh
+	Refresh time.Duration
+	//Timeout bounds each job, fades and effects get their duration on top, 0 is DefaultTimeout
+	Timeout time.Duration
+	//Log gets the outcome of every job, nil uses logrus' standard logger
+	Log logrus.FieldLogger
+
+	mutex sync.Mutex
+	cache *selector.Cache
+}
+
+//Run runs the jobs as they come due until ctx is done. Jobs run in their own goroutines so a long
+//effect doesn't hold up the others.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.File.Jobs) == 0 {
+		return fmt.Errorf("no jobs to run")
+	}
+	next := make([]time.Time, len(s.File.Jobs))
+	now := time.Now()
+	for i, j := range s.File.Jobs {
+		next[i] = j.Trigger.Next(now)
+		s.log().WithField("job", j.Name).Infof("next run at %v", next[i])
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		first := -1
+		for i := range next {
+			if !next[i].IsZero() && (first < 0 || next[i].Before(next[first])) {
+				first = i
+			}
+		}
+		if first < 0 {
+			return fmt.Errorf("no job will run again")
+		}
+
+		// wake at least every minute and go by the wall clock, timers stop while a computer sleeps
+		wait := time.Until(next[first])
+		if wait > time.Minute {
+			wait = time.Minute
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		for i, j := range s.File.Jobs {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+			wg.Add(1)
+			go func(j *Job) {
+				defer wg.Done()
+				s.run(ctx, j)
+			}(j)
+			next[i] = j.Trigger.Next(now)
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, j *Job) {
+	log := s.log().WithField("job", j.Name)
+	start := time.Now()
+	if err := s.Execute(ctx, j); err != nil {
+		log.WithError(err).Error("failed")
+		return
+	}
+	log.WithField("took", time.Since(start).Round(time.Millisecond)).Info("done")
+}
+
+func (s *Scheduler) log() logrus.FieldLogger {
+	if s.Log == nil {
+		return logrus.StandardLogger()
+	}
+	return s.Log
+}
+
+//Execute runs the job's action once, now.
+func (s *Scheduler) Execute(ctx context.Context, j *Job) error {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout+j.Duration)
+	defer cancel()
+
+	switch {
+	case j.Scene != "":
+		return s.applyScene(ctx, j)
+	case j.Effect != "":
+		return s.runEffect(ctx, j)
+	default:
+		return s.setLights(ctx, j)
+	}
+}
+
+func (s *Scheduler) setLights(ctx context.Context, j *Job) error {
+	lights, err := s.Lights(ctx, j.selector)
+	if err != nil {
+		return err
+	}
+	return client.Each(lights, func(_ int, l *client.Light) error {
+		if j.Color != "" {
+			var current hsbk.HSBK
+			if !j.color.Complete() {
+				state, err := l.State(ctx)
+				if err != nil {
+					return err
+				}
+				current = state.Color
+			}
+			if err := l.SetColor(ctx, j.color.Apply(current), j.Duration); err != nil {
+				return err
+			}
+		}
+		if j.Power != nil {
+			return l.SetPower(ctx, *j.Power, j.Duration)
+		}
+		return nil
+	})
+}
+
+func (s *Scheduler) applyScene(ctx context.Context, j *Job) error {
+	scenes, err := scene.ReadFile(s.SceneFile)
+	if err != nil {
+		return err
+	}
+	sc, has := scenes[j.Scene]
+	if !has {
+		return fmt.Errorf("no scene named %q in %v", j.Scene, s.SceneFile)
+	}
+	targets, err := sc.Targets()
+	if err != nil {
+		return err
+	}
+	sel := make(selector.Selector, len(targets))
+	for i, target := range targets {
+		sel[i] = selector.Term{Field: selector.ID, Pattern: hex.EncodeToString(target)}
+	}
+	lights, err := s.Lights(ctx, sel)
+	if err != nil {
+		return err
+	}
+	return sc.Apply(ctx, lights, j.Duration)
+}
+
+func (s *Scheduler) runEffect(ctx context.Context, j *Job) error {
+	params := effect.Params{Period: j.Period}
+	if j.Color != "" {
+		color := j.color.Apply(hsbk.HSBK{Brightness: 0xffff, Kelvin: hsbk.NeutralKelvin})
+		params.Color = &color
+	}
+	e, err := effect.Named(j.Effect, params)
+	if err != nil {
+		return err
+	}
+	lights, err := s.Lights(ctx, j.selector)
+	if err != nil {
+		return err
+	}
+	return effect.Start(ctx, e, lights, effect.Options{Duration: j.Duration, Restore: true}).Wait()
+}
+
+//Lights returns the lights sel picks from the ones discovered earlier, see selector.Cache.
+func (s *Scheduler) Lights(ctx context.Context, sel selector.Selector) ([]*client.Light, error) {
+	s.mutex.Lock()
+	if s.cache == nil {
+		s.cache = &selector.Cache{Client: s.Client, Wait: s.Wait, Refresh: s.Refresh, Log: s.log()}
+	}
+	s.mutex.Unlock()
+	return s.cache.Lights(ctx, sel)
+}
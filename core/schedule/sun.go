@@ -0,0 +1,151 @@
+package schedule
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+//Event is a moment in the sun's day.
+type Event string
+
+const (
+	Sunrise Event = "sunrise"
+	Sunset  Event = "sunset"
+	//Dawn is civil dawn, when the sun is 6° below the horizon in the morning
+	Dawn Event = "dawn"
+	//Dusk is civil dusk, when the sun is 6° below the horizon in the evening
+	Dusk Event = "dusk"
+	Noon Event = "noon"
+)
+
+var eventAliases = map[string]Event{
+	"sunrise":    Sunrise,
+	"sunset":     Sunset,
+	"dawn":       Dawn,
+	"civil-dawn": Dawn,
+	"dusk":       Dusk,
+	"civil-dusk": Dusk,
+	"noon":       Noon,
+	"solar-noon": Noon,
+}
+
+//sun altitudes in degrees, sunrise and sunset allow for refraction and the size of the sun's disc
+var eventAltitudes = map[Event]float64{
+	Sunrise: -0.833,
+	Sunset:  -0.833,
+	Dawn:    -6,
+	Dusk:    -6,
+}
+
+const j2000 = 2451545.0
+
+//SunTime returns when event happens on the given day at latitude and longitude (degrees, north and east
+//positive), worked out offline with the NOAA sunrise equation to within a minute or two. The day is taken
+//in date's location. ok is false when the event doesn't happen that day, as in a polar summer or winter.
+func SunTime(date time.Time, event Event, latitude, longitude float64) (t time.Time, ok bool) {
+	noon := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, date.Location())
+	// the mean solar noon, counted in days from J2000, closest to the local noon
+	days := julian(noon) - j2000
+	n := math.Round(days + longitude/360)
+	meanNoon := n - longitude/360
+
+	anomaly := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	m := radians(anomaly)
+	center := 1.9148*math.Sin(m) + 0.02*math.Sin(2*m) + 0.0003*math.Sin(3*m)
+	ecliptic := radians(math.Mod(anomaly+center+180+102.9372, 360))
+	transit := j2000 + meanNoon + 0.0053*math.Sin(m) - 0.0069*math.Sin(2*ecliptic)
+	if event == Noon {
+		return fromJulian(transit, date.Location()), true
+	}
+
+	declination := math.Asin(math.Sin(ecliptic) * math.Sin(radians(23.4397)))
+	phi := radians(latitude)
+	cosHour := (math.Sin(radians(eventAltitudes[event])) - math.Sin(phi)*math.Sin(declination)) / (math.Cos(phi) * math.Cos(declination))
+	if cosHour < -1 || cosHour > 1 {
+		return time.Time{}, false
+	}
+	hour := degrees(math.Acos(cosHour)) / 360
+	switch event {
+	case Sunrise, Dawn:
+		return fromJulian(transit-hour, date.Location()), true
+	default:
+		return fromJulian(transit+hour, date.Location()), true
+	}
+}
+
+//Solar is a trigger at a sun event each day, moved by Offset.
+type Solar struct {
+	Event               Event
+	Offset              time.Duration
+	Latitude, Longitude float64
+}
+
+//ParseSolar reads an event name with an optional offset, like sunset, sunrise+30m or civil-dusk-1h15m.
+//dawn and dusk are the civil ones.
+func ParseSolar(s string, latitude, longitude float64) (*Solar, error) {
+	spec := strings.ToLower(strings.TrimSpace(s))
+	name, offset := spec, ""
+	// skip the first character so the dash in civil-dusk isn't taken for an offset
+	if i := strings.LastIndexAny(spec, "+-"); i > 0 {
+		if _, has := eventAliases[spec[:i]]; has {
+			name, offset = spec[:i], spec[i:]
+		}
+	}
+	event, has := eventAliases[name]
+	if !has {
+		return nil, fmt.Errorf("unknown sun event %q (expected sunrise, sunset, dawn, dusk or noon)", s)
+	}
+	solar := &Solar{Event: event, Latitude: latitude, Longitude: longitude}
+	if offset != "" {
+		d, err := time.ParseDuration(offset)
+		if err != nil {
+			return nil, fmt.Errorf("sun event %q: %v", s, err)
+		}
+		solar.Offset = d
+	}
+	return solar, nil
+}
+
+func (s *Solar) String() string {
+	if s.Offset == 0 {
+		return string(s.Event)
+	}
+	if s.Offset > 0 {
+		return fmt.Sprintf("%v+%v", s.Event, s.Offset)
+	}
+	return fmt.Sprintf("%v%v", s.Event, s.Offset)
+}
+
+//Next returns the first time after t the event happens, in t's location. Days without the event are
+//skipped, the zero time means it doesn't happen within a year.
+func (s *Solar) Next(t time.Time) time.Time {
+	// start the day before in case a negative offset pulls tomorrow's event into today
+	day := time.Date(t.Year(), t.Month(), t.Day()-1, 0, 0, 0, 0, t.Location())
+	for i := 0; i < 370; i++ {
+		if at, ok := SunTime(day.AddDate(0, 0, i), s.Event, s.Latitude, s.Longitude); ok {
+			if at = at.Add(s.Offset); at.After(t) {
+				return at
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func julian(t time.Time) float64 {
+	return float64(t.Unix())/86400 + 2440587.5
+}
+
+func fromJulian(j float64, loc *time.Location) time.Time {
+	seconds := (j - 2440587.5) * 86400
+	return time.Unix(0, int64(seconds*1e9)).In(loc).Truncate(time.Second)
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,70 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunTime(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip(err)
+	}
+	day := time.Date(2024, 6, 21, 0, 0, 0, 0, london)
+	tests := []struct {
+		event Event
+		at    time.Time
+	}{
+		// from timeanddate.com for Greenwich
+		{Sunrise, time.Date(2024, 6, 21, 4, 43, 0, 0, london)},
+		{Sunset, time.Date(2024, 6, 21, 21, 21, 0, 0, london)},
+		{Dawn, time.Date(2024, 6, 21, 3, 57, 0, 0, london)},
+		{Dusk, time.Date(2024, 6, 21, 22, 7, 0, 0, london)},
+	}
+	for _, test := range tests {
+		at, ok := SunTime(day, test.event, 51.48, 0)
+		if !ok {
+			t.Errorf("%v: expected it to happen", test.event)
+			continue
+		}
+		if diff := at.Sub(test.at); diff < -3*time.Minute || diff > 3*time.Minute {
+			t.Errorf("%v: expected about %v but got %v", test.event, test.at, at)
+		}
+	}
+
+	// no sunrise in Tromsø at midwinter
+	if at, ok := SunTime(time.Date(2024, 12, 21, 0, 0, 0, 0, time.UTC), Sunrise, 69.65, 18.96); ok {
+		t.Errorf("expected no sunrise but got %v", at)
+	}
+}
+
+func TestSolar(t *testing.T) {
+	s, err := ParseSolar("sunset-30m", 51.48, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Event != Sunset || s.Offset != -30*time.Minute || s.String() != "sunset-30m0s" {
+		t.Errorf("unexpected %#v", s)
+	}
+	if s, err := ParseSolar("civil-dusk", 0, 0); err != nil || s.Event != Dusk || s.Offset != 0 {
+		t.Errorf("expected civil dusk but got %v, %v", s, err)
+	}
+	for _, bad := range []string{"moonrise", "sunset+", "sunset-30"} {
+		if _, err := ParseSolar(bad, 0, 0); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+
+	from := time.Date(2024, 6, 21, 22, 0, 0, 0, time.UTC)
+	next := s.Next(from)
+	sunset, _ := SunTime(time.Date(2024, 6, 22, 0, 0, 0, 0, time.UTC), Sunset, 51.48, 0)
+	if !next.Equal(sunset.Add(-30 * time.Minute)) {
+		t.Errorf("expected the next day's sunset less 30 minutes but got %v", next)
+	}
+
+	// skips the dark winter to the first sunrise
+	north := &Solar{Event: Sunrise, Latitude: 69.65, Longitude: 18.96}
+	if next := north.Next(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)); next.Month() != time.January {
+		t.Errorf("expected the sun back in January but got %v", next)
+	}
+}
@@ -0,0 +1,82 @@
+package selector
+
+import (
+	"context"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWait    = 3 * time.Second
+	DefaultRefresh = 10 * time.Minute
+)
+
+//Cache keeps the lights a client discovered so long running commands only broadcast again when the lights
+//are getting old or a selector finds none of its lights among them.
+type Cache struct {
+	Client *client.Client
+	//Wait is how long discovery listens for lights, 0 is DefaultWait
+	Wait time.Duration
+	//Refresh is how old the known lights may get before they are discovered again, 0 is DefaultRefresh
+	Refresh time.Duration
+	//Log, when set, gets how many lights every discovery found
+	Log logrus.FieldLogger
+
+	mutex      sync.Mutex
+	lights     []*client.Light
+	discovered time.Time
+}
+
+//Lights returns the known lights sel picks, discovering again when the known lights are older than
+//Refresh or none of them, or not all the exact ids asked for, are found.
+func (c *Cache) Lights(ctx context.Context, sel Selector) ([]*client.Light, error) {
+	known, fresh, err := c.known(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	selected := sel.Filter(ctx, known)
+	targets, exact := sel.Targets()
+	if !fresh && (len(selected) == 0 || exact && len(selected) < len(targets)) {
+		if known, _, err = c.known(ctx, true); err != nil {
+			return nil, err
+		}
+		selected = sel.Filter(ctx, known)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("could not find any light matching %v", sel)
+	}
+	return selected, nil
+}
+
+//known returns the discovered lights and whether they were discovered just now.
+func (c *Cache) known(ctx context.Context, force bool) ([]*client.Light, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	refresh := c.Refresh
+	if refresh <= 0 {
+		refresh = DefaultRefresh
+	}
+	if !force && c.lights != nil && time.Since(c.discovered) < refresh {
+		return c.lights, false, nil
+	}
+
+	wait := c.Wait
+	if wait <= 0 {
+		wait = DefaultWait
+	}
+	discoverCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	lights, err := c.Client.Discover(discoverCtx)
+	if err != nil {
+		return nil, false, err
+	}
+	c.lights, c.discovered = lights, time.Now()
+	if c.Log != nil {
+		c.Log.Infof("discovered %v lights", len(lights))
+	}
+	return lights, true, nil
+}
@@ -0,0 +1,215 @@
+package selector
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/messages/fields"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Field is the part of a light a Term is matched against.
+type Field string
+
+const (
+	All      Field = "all"
+	ID       Field = "id"
+	Label    Field = "label"
+	Group    Field = "group"
+	Location Field = "location"
+)
+
+//Term is one comma separated piece of a selector. Pattern is a path.Match glob where / is an ordinary character,
+//matched without regard to case.
+type Term struct {
+	Field   Field
+	Pattern string
+}
+
+func (t Term) String() string {
+	if t.Field == All {
+		return string(All)
+	}
+	return fmt.Sprintf("%v:%v", t.Field, t.Pattern)
+}
+
+//Selector picks lights, a light is selected if any of its terms match.
+type Selector []Term
+
+//Parse reads a selector such as "all", "label:Kitchen", "group:Up*,id:d073d5012345" or a bare
+//TARGET_HEXSTR which is shorthand for id:TARGET_HEXSTR.
+func Parse(s string) (Selector, error) {
+	selector := make(Selector, 0)
+	for _, piece := range strings.Split(s, ",") {
+		piece = strings.TrimSpace(piece)
+		if piece == "" {
+			return nil, fmt.Errorf("empty selector in %q", s)
+		}
+		if strings.ToLower(piece) == string(All) {
+			selector = append(selector, Term{Field: All})
+			continue
+		}
+
+		var term Term
+		if i := strings.Index(piece, ":"); i >= 0 {
+			term = Term{Field: Field(strings.ToLower(piece[:i])), Pattern: piece[i+1:]}
+		} else if _, err := hex.DecodeString(piece); err == nil {
+			term = Term{Field: ID, Pattern: piece}
+		} else {
+			return nil, fmt.Errorf("unknown selector %q: expected all, id:, label:, group:, location: or a TARGET_HEXSTR", piece)
+		}
+
+		switch term.Field {
+		case ID:
+			term.Pattern = normalizeID(term.Pattern)
+		case Label, Group, Location:
+		default:
+			return nil, fmt.Errorf("unknown selector field %q in %q", term.Field, piece)
+		}
+		if _, err := glob(term.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad pattern in %q: %v", piece, err)
+		}
+		selector = append(selector, term)
+	}
+	return selector, nil
+}
+
+//normalizeID lower cases the id and adds the two trailing zeros to a 6 byte MAC address.
+func normalizeID(id string) string {
+	id = strings.ToLower(id)
+	if _, err := hex.DecodeString(id); err == nil && len(id) == 12 {
+		id += "00"
+	}
+	return id
+}
+
+func (s Selector) String() string {
+	terms := make([]string, len(s))
+	for i, t := range s {
+		terms[i] = t.String()
+	}
+	return strings.Join(terms, ",")
+}
+
+//Targets returns the targets when every term is an exact id, which lets Resolve stop discovery as
+//soon as they all answered.
+func (s Selector) Targets() ([][]byte, bool) {
+	targets := make([][]byte, 0, len(s))
+	for _, t := range s {
+		if t.Field != ID {
+			return nil, false
+		}
+		target, err := hex.DecodeString(t.Pattern)
+		if err != nil {
+			return nil, false
+		}
+		targets = append(targets, target)
+	}
+	return targets, len(targets) > 0
+}
+
+//Resolve discovers lights for up to wait and returns the ones selected. Label, group and location
+//terms query every light found, lights that don't answer those queries before ctx is done are left out.
+func (s Selector) Resolve(ctx context.Context, c *client.Client, wait time.Duration) ([]*client.Light, error) {
+	discoverCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	var lights []*client.Light
+	var err error
+	if targets, exact := s.Targets(); exact {
+		lights, err = c.Find(discoverCtx, targets...)
+	} else {
+		lights, err = c.Discover(discoverCtx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.Filter(ctx, lights), nil
+}
+
+//Filter returns the lights that are selected, asking them all at once. Lights that don't answer before
+//ctx is done are left out.
+func (s Selector) Filter(ctx context.Context, lights []*client.Light) []*client.Light {
+	matched := make([]bool, len(lights))
+	var wg sync.WaitGroup
+	for i, l := range lights {
+		wg.Add(1)
+		go func(i int, l *client.Light) {
+			defer wg.Done()
+			matched[i] = s.Match(ctx, l)
+		}(i, l)
+	}
+	wg.Wait()
+
+	selected := make([]*client.Light, 0, len(lights))
+	for i, l := range lights {
+		if matched[i] {
+			selected = append(selected, l)
+		}
+	}
+	return selected
+}
+
+//Match reports if the light is selected, asking the light for its label, group or location only when
+//a term needs it.
+func (s Selector) Match(ctx context.Context, l *client.Light) bool {
+	values := make(map[Field]string)
+	failed := make(map[Field]bool)
+	for _, t := range s {
+		if failed[t.Field] {
+			continue
+		}
+		value, has := values[t.Field]
+		if !has {
+			var err error
+			value, err = property(ctx, l, t.Field)
+			if err != nil {
+				failed[t.Field] = true
+				continue
+			}
+			values[t.Field] = value
+		}
+		if t.Field == All {
+			return true
+		}
+		if ok, _ := glob(strings.ToLower(t.Pattern), strings.ToLower(value)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+//glob is path.Match without / being special, labels like Kitchen/Main are names not paths. The / is swapped
+//for a NUL on both sides, which labels can't hold.
+func glob(pattern, value string) (bool, error) {
+	return path.Match(strings.ReplaceAll(pattern, "/", "\x00"), strings.ReplaceAll(value, "/", "\x00"))
+}
+
+func property(ctx context.Context, l *client.Light, field Field) (string, error) {
+	switch field {
+	case All:
+		return "", nil
+	case ID:
+		return l.TargetHex(), nil
+	case Label:
+		return l.Label(ctx)
+	case Group:
+		g, err := l.Group(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fields.Label(g.Label), nil
+	case Location:
+		loc, err := l.Location(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fields.Label(loc.Label), nil
+	default:
+		return "", fmt.Errorf("unknown selector field %q", field)
+	}
+}
@@ -0,0 +1,87 @@
+package selector
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected Selector
+	}{
+		{"all", Selector{{Field: All}}},
+		{"d073d5012345", Selector{{Field: ID, Pattern: "d073d501234500"}}},
+		{"id:D073D501234500", Selector{{Field: ID, Pattern: "d073d501234500"}}},
+		{"label:Kitchen, group:Up*", Selector{{Field: Label, Pattern: "Kitchen"}, {Field: Group, Pattern: "Up*"}}},
+		{"location:Home", Selector{{Field: Location, Pattern: "Home"}}},
+		{"label:a:b", Selector{{Field: Label, Pattern: "a:b"}}},
+	}
+	for _, test := range tests {
+		actual, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%q: expected %v but got %v", test.in, test.expected, actual)
+		}
+	}
+
+	for _, bad := range []string{"", "kitchen", "color:red", "label:[", "all,,all"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("expected %q to fail", bad)
+		}
+	}
+}
+
+func TestSelector_Resolve(t *testing.T) {
+	devices := []*emulator.Device{
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Label: "Kitchen", GroupLabel: "Downstairs", LocationLabel: "Home"},
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 2, 0}, Label: "Bedroom", GroupLabel: "Upstairs", LocationLabel: "Home"},
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 3, 0}, Label: "Office", GroupLabel: "Upstairs", LocationLabel: "Work"},
+		{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 4, 0}, Label: "Kitchen/Main", GroupLabel: "Downstairs", LocationLabel: "Home"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out, in := emulator.StartUp(ctx, devices...)
+	c := client.New(ctx, out, in)
+
+	tests := []struct {
+		selector string
+		expected []string
+	}{
+		{"all", []string{"d073d500000100", "d073d500000200", "d073d500000300", "d073d500000400"}},
+		{"group:upstairs", []string{"d073d500000200", "d073d500000300"}},
+		{"location:Home,label:Off*", []string{"d073d500000100", "d073d500000200", "d073d500000300", "d073d500000400"}},
+		{"label:K?tchen", []string{"d073d500000100"}},
+		{"label:Kitchen*", []string{"d073d500000100", "d073d500000400"}},
+		{"label:*n?main", []string{"d073d500000400"}},
+		{"d073d5000002", []string{"d073d500000200"}},
+		{"id:d073d5*3*", []string{"d073d500000300"}},
+		{"label:Garage", []string{}},
+	}
+	for _, test := range tests {
+		sel, err := Parse(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lights, err := sel.Resolve(ctx, c, 100*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := make([]string, 0)
+		for _, l := range lights {
+			actual = append(actual, l.TargetHex())
+		}
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%v: expected %v but got %v", test.selector, test.expected, actual)
+		}
+	}
+}
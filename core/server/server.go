@@ -18,7 +18,17 @@ type OutBoundPayload struct {
 	Done    context.CancelFunc
 }
 
+//Recorder receives a copy of every datagram sent or received by the server, e.g. capture.Writer.
+type Recorder interface {
+	Record(timestamp time.Time, src, dst *net.UDPAddr, data []byte) error
+}
+
 func StartUp(ctx context.Context) (outBound chan *OutBoundPayload, inbound chan *InboundPayload, err error) {
+	return StartUpWithRecorder(ctx, nil)
+}
+
+//StartUpWithRecorder is StartUp but every inbound and outbound datagram is also handed to recorder.
+func StartUpWithRecorder(ctx context.Context, recorder Recorder) (outBound chan *OutBoundPayload, inbound chan *InboundPayload, err error) {
 	outBound = make(chan *OutBoundPayload, 10)
 	inbound = make(chan *InboundPayload, 100)
 
@@ -28,6 +38,7 @@ func StartUp(ctx context.Context) (outBound chan *OutBoundPayload, inbound chan
 		logrus.Errorf("%v", err)
 		return nil, nil, err
 	}
+	local := connection.LocalAddr().(*net.UDPAddr)
 
 	go func() {
 		defer connection.Close()
@@ -37,7 +48,12 @@ func StartUp(ctx context.Context) (outBound chan *OutBoundPayload, inbound chan
 			case <-ctx.Done():
 				return
 			case payload := <-outBound:
-				connection.WriteTo(payload.Data, payload.Address)
+				_, err := connection.WriteTo(payload.Data, payload.Address)
+				if err == nil && recorder != nil {
+					if err := recorder.Record(time.Now(), local, payload.Address, payload.Data); err != nil {
+						logrus.Errorf("recording outbound: %v", err)
+					}
+				}
 				if payload.Done != nil {
 					payload.Done()
 				}
@@ -64,6 +80,11 @@ func StartUp(ctx context.Context) (outBound chan *OutBoundPayload, inbound chan
 			if err != nil {
 				continue
 			}
+			if recorder != nil {
+				if err := recorder.Record(time.Now(), conn, local, inputBytes[:length]); err != nil {
+					logrus.Errorf("recording inbound: %v", err)
+				}
+			}
 			inbound <- &InboundPayload{
 				Data: inputBytes[:length],
 				Conn: conn,
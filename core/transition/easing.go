@@ -0,0 +1,95 @@
+package transition
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+//Easing maps the fraction of time gone by, in [0,1], to the fraction of the change to show. Every easing
+//starts at 0 and ends at 1.
+type Easing func(t float64) float64
+
+func Linear(t float64) float64 { return t }
+
+func EaseIn(t float64) float64 { return t * t }
+
+func EaseOut(t float64) float64 { return 1 - (1-t)*(1-t) }
+
+func EaseInOut(t float64) float64 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return 1 - 2*(1-t)*(1-t)
+}
+
+func CubicIn(t float64) float64 { return t * t * t }
+
+func CubicOut(t float64) float64 { return 1 - math.Pow(1-t, 3) }
+
+func CubicInOut(t float64) float64 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	return 1 - 4*math.Pow(1-t, 3)
+}
+
+func ExpoIn(t float64) float64 {
+	if t <= 0 {
+		return 0
+	}
+	return math.Pow(2, 10*(t-1))
+}
+
+func ExpoOut(t float64) float64 {
+	if t >= 1 {
+		return 1
+	}
+	return 1 - math.Pow(2, -10*t)
+}
+
+func ExpoInOut(t float64) float64 {
+	switch {
+	case t <= 0:
+		return 0
+	case t >= 1:
+		return 1
+	case t < 0.5:
+		return math.Pow(2, 20*t-10) / 2
+	default:
+		return 1 - math.Pow(2, -20*t+10)/2
+	}
+}
+
+//Easings are the easings by the names used on the commandline.
+var Easings = map[string]Easing{
+	"linear":       Linear,
+	"ease-in":      EaseIn,
+	"ease-out":     EaseOut,
+	"ease-in-out":  EaseInOut,
+	"cubic-in":     CubicIn,
+	"cubic-out":    CubicOut,
+	"cubic-in-out": CubicInOut,
+	"expo-in":      ExpoIn,
+	"expo-out":     ExpoOut,
+	"expo-in-out":  ExpoInOut,
+}
+
+//EasingNames returns the names in Easings sorted.
+func EasingNames() []string {
+	names := make([]string, 0, len(Easings))
+	for name := range Easings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//ParseEasing looks up an easing by name.
+func ParseEasing(name string) (Easing, error) {
+	easing, has := Easings[name]
+	if !has {
+		return nil, fmt.Errorf("unknown easing %q (expected one of %v)", name, EasingNames())
+	}
+	return easing, nil
+}
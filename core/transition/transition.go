@@ -0,0 +1,110 @@
+package transition
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"time"
+)
+
+const (
+	//DefaultFPS is how many colors a second are sent unless told otherwise
+	DefaultFPS = 20
+	//MaxFPS is the most messages a second LIFX recommends sending a device
+	MaxFPS = 20
+)
+
+//Transition fades lights to a color by sending the in between colors itself, which allows any easing
+//instead of the firmware's linear fade.
+type Transition struct {
+	//To is the color to end at
+	To hsbk.HSBK
+	//Duration is how long the whole fade takes
+	Duration time.Duration
+	//Easing shapes the fade, nil is Linear
+	Easing Easing
+	//Interpolate picks the colors between the start and To, nil is hsbk.Lerp
+	Interpolate hsbk.Interpolator
+	//FPS is how many colors a second are sent, 0 is DefaultFPS and it is capped at MaxFPS
+	FPS int
+	//PowerOn turns lights that are off on at the start. They fade up from their color at zero
+	//brightness, which makes a wake-up light.
+	PowerOn bool
+}
+
+//Frames returns the colors to send, one per frame, fading from the given color. The last is always t.To.
+func (t Transition) Frames(from hsbk.HSBK) []hsbk.HSBK {
+	easing, interpolate := t.Easing, t.Interpolate
+	if easing == nil {
+		easing = Linear
+	}
+	if interpolate == nil {
+		interpolate = hsbk.Lerp
+	}
+
+	count := int(t.Duration / t.frameInterval())
+	if count < 1 {
+		count = 1
+	}
+	frames := make([]hsbk.HSBK, count)
+	for i := range frames {
+		frames[i] = interpolate(from, t.To, easing(float64(i+1)/float64(count)))
+	}
+	frames[count-1] = t.To
+	return frames
+}
+
+func (t Transition) frameInterval() time.Duration {
+	fps := t.FPS
+	if fps <= 0 {
+		fps = DefaultFPS
+	}
+	if fps > MaxFPS {
+		fps = MaxFPS
+	}
+	return time.Second / time.Duration(fps)
+}
+
+//Run fades every light from its current color to t.To at the same time. It returns when the fade is done
+//or ctx is done, a cancelled fade leaves the lights at the color they had reached.
+func (t Transition) Run(ctx context.Context, lights ...*client.Light) error {
+	return client.Each(lights, func(_ int, l *client.Light) error { return t.run(ctx, l) })
+}
+
+func (t Transition) run(ctx context.Context, l *client.Light) error {
+	state, err := l.State(ctx)
+	if err != nil {
+		return err
+	}
+	from := state.Color
+	if t.PowerOn && state.Power == 0 {
+		from.Brightness = 0
+		if err := l.SetColor(ctx, from, 0); err != nil {
+			return err
+		}
+		if err := l.SetPower(ctx, true, 0); err != nil {
+			return err
+		}
+	}
+
+	interval := t.frameInterval()
+	frames := t.Frames(from)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i, frame := range frames {
+		if i == len(frames)-1 {
+			// make sure the end color arrived
+			return l.SetColor(ctx, frame, interval)
+		}
+		// the firmware fades between frames so the steps don't show
+		if err := l.SendColor(ctx, frame, interval); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package transition
+
+import (
+	"context"
+	"github.com/nathanhack/lifx/core/client"
+	"github.com/nathanhack/lifx/core/emulator"
+	"github.com/nathanhack/lifx/core/messages/light/hsbk"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEasings(t *testing.T) {
+	for _, name := range EasingNames() {
+		easing := Easings[name]
+		if easing(0) != 0 || math.Abs(easing(1)-1) > 1e-9 {
+			t.Errorf("%v: expected 0 and 1 at the ends but got %v and %v", name, easing(0), easing(1))
+		}
+		previous := 0.0
+		for i := 1; i <= 100; i++ {
+			v := easing(float64(i) / 100)
+			if v < previous {
+				t.Errorf("%v: expected never to go back but %v < %v at %v", name, v, previous, i)
+			}
+			previous = v
+		}
+	}
+	if EaseIn(0.5) >= 0.5 || EaseOut(0.5) <= 0.5 || EaseInOut(0.5) != 0.5 {
+		t.Errorf("expected ease-in to start slow and ease-out to start fast")
+	}
+	if _, err := ParseEasing("bounce"); err == nil {
+		t.Errorf("expected an unknown easing to fail")
+	}
+}
+
+func TestTransition_Frames(t *testing.T) {
+	from := hsbk.HSBK{Brightness: 0, Kelvin: 3500}
+	to := hsbk.HSBK{Brightness: 0xffff, Kelvin: 3500}
+	frames := Transition{To: to, Duration: time.Second, Easing: EaseIn, FPS: 10}.Frames(from)
+	if len(frames) != 10 || frames[9] != to {
+		t.Fatalf("expected 10 frames ending at %v but got %v", to, frames)
+	}
+	if frames[4].Brightness != hsbk.FromFraction(0.25) {
+		t.Errorf("expected a quarter brightness halfway with ease-in but got %v", frames[4])
+	}
+
+	if frames := (Transition{To: to, FPS: 100}).Frames(from); len(frames) != 1 || frames[0] != to {
+		t.Errorf("expected a single frame without a duration but got %v", frames)
+	}
+	if frames := (Transition{To: to, Duration: time.Second, FPS: 100}).Frames(from); len(frames) != MaxFPS {
+		t.Errorf("expected the frame rate capped at %v but got %v frames", MaxFPS, len(frames))
+	}
+}
+
+func TestTransition_Run(t *testing.T) {
+	bulb := &emulator.Device{Target: []byte{0xd0, 0x73, 0xd5, 0, 0, 1, 0}, Color: hsbk.HSBK{Hue: 0x5555, Saturation: 0xffff, Brightness: 0xffff, Kelvin: 3500}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, in := emulator.StartUp(ctx, bulb)
+	l := client.New(ctx, out, in).Light(bulb.Target, bulb.Address)
+
+	to := hsbk.HSBK{Hue: 0, Saturation: 0xffff, Brightness: 0x8000, Kelvin: 3500}
+	start := time.Now()
+	if err := (Transition{To: to, Duration: 300 * time.Millisecond, Easing: CubicInOut, PowerOn: true}).Run(ctx, l); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("expected the fade to take its time but it took %v", elapsed)
+	}
+	state, err := l.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Color != to || state.Power != 0xffff {
+		t.Errorf("expected %v and on but got %v", to, state)
+	}
+
+	// long enough for the state request and the first frame, which wait their turn at MinInterval
+	cancelled, stop := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer stop()
+	err = Transition{To: hsbk.HSBK{Kelvin: 3500}, Duration: time.Minute}.Run(cancelled, l)
+	if err == nil {
+		t.Errorf("expected a cancelled transition to report it")
+	}
+	state, _ = l.State(ctx)
+	if state.Color.Brightness == 0 || state.Color.Brightness == 0x8000 {
+		t.Errorf("expected the light to stop part way but got %v", state.Color)
+	}
+}
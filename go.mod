@@ -1,12 +1,23 @@
 module github.com/nathanhack/lifx
 
-go 1.13
+go 1.18
 
 require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
+	github.com/gorilla/websocket v1.4.2
 	github.com/hajimehoshi/ebiten v1.10.1
 	github.com/sirupsen/logrus v1.4.2
 	github.com/spf13/cobra v0.0.5
 	golang.org/x/image v0.0.0-20191214001246-9130b4cfad52
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/go-gl/glfw v0.0.0-20190409004039-e6da0acd62b1 // indirect
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
+	github.com/spf13/pflag v1.0.3 // indirect
+	golang.org/x/exp v0.0.0-20190731235908-ec7cb31e5a56 // indirect
 	golang.org/x/mobile v0.0.0-20191210151939-1a1fef82734d // indirect
+	golang.org/x/sys v0.0.0-20191026070338-33540a1f6037 // indirect
 )